@@ -0,0 +1,24 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+/*
+Package opentracing implements the opentracing.Tracer by the go2sky Tracer for the code written against opentracing-go.
+The server and consumer spans of the span.kind tag are entry spans, the client and producer spans are exit spans,
+and the others are local spans. The SkyWalking context is injected and extracted by the sw8 headers, the spans
+other than the exit spans inject the context of an exit span created on demand,
+and the baggage items are the correlation context limited by the CorrelationConfig of the tracer.
+*/
+package opentracing
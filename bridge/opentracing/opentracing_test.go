@@ -0,0 +1,194 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package opentracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+func newTracer(t *testing.T, opts ...go2sky.TracerOption) (*Tracer, *go2sky.Tracer, *testutil.Reporter) {
+	reporter := testutil.NewReporter(10)
+	tracer, err := go2sky.NewTracer("service", append(opts, go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otTracer, err := NewTracer(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return otTracer, tracer, reporter
+}
+
+// segment returns the spans of the reported segment sorted by the span id
+
+func TestTracer_StartSpan(t *testing.T) {
+	otTracer, tracer, reporter := newTracer(t)
+
+	server := otTracer.StartSpan("/orders", ext.SpanKindRPCServer, opentracing.Tag{Key: "http.method", Value: "GET"})
+	native, nativeCtx, err := tracer.CreateLocalSpan(ContextWithSpan(context.Background(), server), go2sky.WithOperationName("native"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := otTracer.StartSpan("compute", opentracing.FollowsFrom(SpanContextFromContext(nativeCtx)))
+	local.LogKV("event", "cache miss", "key", "order")
+	local.Finish()
+	client := otTracer.StartSpan("SELECT", opentracing.ChildOf(server.Context()), ext.SpanKindRPCClient,
+		opentracing.Tag{Key: string(ext.PeerHostname), Value: "db"})
+	ext.PeerPort.Set(client, 3306)
+	ext.DBStatement.Set(client, "SELECT 1")
+	ext.LogError(client, errors.New("timeout"))
+	client.Finish()
+	native.End()
+	ext.HTTPStatusCode.Set(server, 200)
+	server.Finish()
+	server.Finish()
+
+	spans := reporter.Segment(t)
+	if len(spans) != 4 {
+		t.Fatalf("the expected 4 spans in the segment, current is %d", len(spans))
+	}
+	entry, nativeSpan, localSpan, exit := spans[0], spans[1], spans[2], spans[3]
+	if entry.SpanType() != agentv3.SpanType_Entry || entry.IsError() || entry.SpanLayer() != agentv3.SpanLayer_Http ||
+		testutil.TagValue(entry, go2sky.TagStatusCode) != "200" || testutil.TagValue(entry, go2sky.TagHTTPMethod) != "GET" {
+		t.Errorf("unexpected entry span %s: error %t, layer %s, tags %v", entry.OperationName(), entry.IsError(), entry.SpanLayer(), entry.Tags())
+	}
+	if nativeSpan.Context().ParentSpanID != entry.Context().SpanID || localSpan.Context().ParentSpanID != nativeSpan.Context().SpanID ||
+		localSpan.SpanType() != agentv3.SpanType_Local || len(localSpan.Logs()) != 1 {
+		t.Errorf("unexpected local span %s, parent %d, logs %v", localSpan.OperationName(), localSpan.Context().ParentSpanID, localSpan.Logs())
+	}
+	if exit.SpanType() != agentv3.SpanType_Exit || exit.Context().ParentSpanID != entry.Context().SpanID || !exit.IsError() ||
+		exit.Peer() != "db:3306" || exit.SpanLayer() != agentv3.SpanLayer_Database || testutil.TagValue(exit, go2sky.TagDBStatement) != "SELECT 1" {
+		t.Errorf("unexpected exit span %s: peer %s, error %t, layer %s, tags %v", exit.OperationName(), exit.Peer(), exit.IsError(), exit.SpanLayer(), exit.Tags())
+	}
+}
+
+func TestTracer_InjectExtract(t *testing.T) {
+	otTracer, _, reporter := newTracer(t, go2sky.WithCorrelation(1, 8))
+	tests := []struct {
+		name       string
+		format     interface{}
+		newCarrier func() interface{}
+	}{
+		{name: "http headers", format: opentracing.HTTPHeaders, newCarrier: func() interface{} { return opentracing.HTTPHeadersCarrier(http.Header{}) }},
+		{name: "text map", format: opentracing.TextMap, newCarrier: func() interface{} { return opentracing.TextMapCarrier{} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := otTracer.StartSpan("/call", ext.SpanKindProducer)
+			// the baggage items out of the correlation limits are ignored
+			client.SetBaggageItem("user", "alice")
+			client.SetBaggageItem("tenant", "acme")
+			client.SetBaggageItem("user", "too long value")
+			carrier := tt.newCarrier()
+			if err := otTracer.Inject(client.Context(), tt.format, carrier); err != nil {
+				t.Fatal(err)
+			}
+			client.Finish()
+			producer := reporter.Segment(t)[0]
+
+			sc, err := otTracer.Extract(tt.format, carrier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			baggage := make(map[string]string)
+			sc.ForeachBaggageItem(func(k, v string) bool {
+				baggage[k] = v
+				return true
+			})
+			if len(baggage) != 1 || baggage["user"] != "alice" {
+				t.Errorf("unexpected baggage %v", baggage)
+			}
+			server := otTracer.StartSpan("/serve", ext.RPCServerOption(sc))
+			if item := server.BaggageItem("user"); item != "alice" {
+				t.Errorf("the baggage should be carried to the server span, current is %s", item)
+			}
+			server.Finish()
+			consumer := reporter.Segment(t)[0]
+			if consumer.SpanType() != agentv3.SpanType_Entry || len(consumer.Refs()) != 1 ||
+				consumer.Refs()[0].ParentSegmentID != producer.Context().SegmentID || consumer.Context().TraceID != producer.Context().TraceID {
+				t.Errorf("the server should refer to the client, current refs are %v", consumer.Refs())
+			}
+		})
+	}
+}
+
+func TestTracer_InjectExtractErrors(t *testing.T) {
+	otTracer, _, reporter := newTracer(t)
+	local := otTracer.StartSpan("local")
+	if err := otTracer.Inject(local.Context(), opentracing.Binary, opentracing.TextMapCarrier{}); err != opentracing.ErrUnsupportedFormat {
+		t.Errorf("the expected unsupported format error, current is %v", err)
+	}
+	if err := otTracer.Inject(local.Context(), opentracing.TextMap, "carrier"); err != opentracing.ErrInvalidCarrier {
+		t.Errorf("the expected invalid carrier error, current is %v", err)
+	}
+	if _, err := otTracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{}); err != opentracing.ErrSpanContextNotFound {
+		t.Errorf("the expected span context not found error, current is %v", err)
+	}
+	local.LogFields(log.String("message", "done"))
+	local.Finish()
+	reporter.Segment(t)
+	if err := otTracer.Inject(local.Context(), opentracing.TextMap, opentracing.TextMapCarrier{}); err != opentracing.ErrInvalidSpanContext {
+		t.Errorf("the context of the ended local span should not be injected, current is %v", err)
+	}
+}
+
+func TestTracer_InjectWithoutKind(t *testing.T) {
+	otTracer, _, reporter := newTracer(t)
+	span := otTracer.StartSpan("/work")
+	span.SetBaggageItem("user", "alice")
+	carrier := opentracing.TextMapCarrier{}
+	if err := otTracer.Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+		t.Fatal(err)
+	}
+	span.Finish()
+	spans := reporter.Segment(t)
+	if len(spans) != 2 || spans[1].SpanType() != agentv3.SpanType_Exit || spans[1].Peer() != defaultPeer ||
+		spans[1].Context().ParentSpanID != spans[0].Context().SpanID {
+		t.Fatalf("the expected exit span created for the injection, current are %v", spans)
+	}
+
+	sc, err := otTracer.Extract(opentracing.TextMap, carrier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := otTracer.StartSpan("/serve", ext.RPCServerOption(sc))
+	if item := server.BaggageItem("user"); item != "alice" {
+		t.Errorf("the baggage should be carried to the server span, current is %s", item)
+	}
+	server.Finish()
+	refs := reporter.Segment(t)[0].Refs()
+	if len(refs) != 1 || refs[0].ParentSegmentID != spans[0].Context().SegmentID || refs[0].ParentSpanID != spans[1].Context().SpanID ||
+		refs[0].ParentEndpoint != "/work" {
+		t.Errorf("the server should refer to the exit span, current refs are %v", refs)
+	}
+}
+
+func TestNewTracer_InvalidTracer(t *testing.T) {
+	if _, err := NewTracer(nil); err != errInvalidTracer {
+		t.Errorf("the expected invalid tracer error, current is %v", err)
+	}
+}
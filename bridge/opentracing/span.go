@@ -0,0 +1,262 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package opentracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/propagation"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+// tags maps the OpenTracing tags to the SkyWalking tags, the other tags are tagged by their keys
+var tags = map[string]go2sky.Tag{
+	string(ext.HTTPUrl):               go2sky.TagURL,
+	string(ext.HTTPStatusCode):        go2sky.TagStatusCode,
+	string(ext.MessageBusDestination): go2sky.TagMQTopic,
+}
+
+// layers maps the namespaces of the tags to the span layers
+var layers = map[string]agentv3.SpanLayer{
+	"http":        agentv3.SpanLayer_Http,
+	"db":          agentv3.SpanLayer_Database,
+	"message_bus": agentv3.SpanLayer_MQ,
+}
+
+// span implements opentracing.Span by a go2sky span
+type span struct {
+	mu     sync.Mutex
+	tracer *Tracer
+	span   go2sky.Span
+	// the context carrying the span
+	ctx     context.Context
+	exit    bool
+	peer    peer
+	layered bool
+	// the sw8 headers injected by the exit span
+	headers map[string]string
+	ended   bool
+}
+
+func (s *span) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
+}
+
+// FinishWithOptions implements opentracing.Span, the log records are logged
+// and the finish time option is ignored, the span ends at the time of calling it.
+func (s *span) FinishWithOptions(opts opentracing.FinishOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	for _, record := range opts.LogRecords {
+		s.logFields(record.Timestamp, record.Fields)
+	}
+	s.ended = true
+	s.span.End()
+}
+
+func (s *span) Context() opentracing.SpanContext {
+	return &spanContext{ctx: s.ctx, span: s}
+}
+
+func (s *span) SetOperationName(operationName string) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.span.SetOperationName(operationName)
+	}
+	return s
+}
+
+// SetTag implements opentracing.Span, the error tag marks the span as error,
+// and the peer tags update the peer of the exit span.
+func (s *span) SetTag(key string, value interface{}) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.setTag(key, value)
+	}
+	return s
+}
+
+func (s *span) setTag(key string, value interface{}) {
+	switch key {
+	case string(ext.SpanKind):
+		return
+	case string(ext.Error):
+		if isError, ok := value.(bool); ok && isError {
+			s.span.Error(time.Now(), "error", "true")
+		}
+		return
+	}
+	if tag, ok := tags[key]; ok {
+		s.span.Tag(tag, fmt.Sprint(value))
+	} else {
+		s.span.Tag(go2sky.Tag(key), fmt.Sprint(value))
+	}
+	if !s.layered {
+		namespace, _, _ := strings.Cut(key, ".")
+		if layer, ok := layers[namespace]; ok {
+			s.span.SetSpanLayer(layer)
+			s.layered = true
+		}
+	}
+	if s.exit && s.peer.update(map[string]interface{}{key: value}) {
+		s.span.SetPeer(s.peer.String())
+	}
+}
+
+// LogFields implements opentracing.Span, the fields of the error event or the error object mark the span as error.
+func (s *span) LogFields(fields ...log.Field) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.logFields(time.Now(), fields)
+	}
+}
+
+func (s *span) logFields(timestamp time.Time, fields []log.Field) {
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	ll := make([]string, 0, 2*len(fields))
+	isError := false
+	for _, field := range fields {
+		value := fmt.Sprint(field.Value())
+		if (field.Key() == "event" && value == "error") || field.Key() == "error.object" {
+			isError = true
+		}
+		ll = append(ll, field.Key(), value)
+	}
+	if isError {
+		s.span.Error(timestamp, ll...)
+	} else {
+		s.span.Log(timestamp, ll...)
+	}
+}
+
+func (s *span) LogKV(alternatingKeyValues ...interface{}) {
+	fields, err := log.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		s.LogFields(log.Error(err), log.String("function", "LogKV"))
+		return
+	}
+	s.LogFields(fields...)
+}
+
+// SetBaggageItem implements opentracing.Span, the item is put into the correlation context,
+// it is ignored if it is out of the limits of the CorrelationConfig.
+func (s *span) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	go2sky.PutCorrelation(s.ctx, restrictedKey, value)
+	return s
+}
+
+func (s *span) BaggageItem(restrictedKey string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return go2sky.GetCorrelation(s.ctx, restrictedKey)
+}
+
+func (s *span) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+func (s *span) LogEvent(event string) {
+	s.LogFields(log.Event(event))
+}
+
+func (s *span) LogEventWithPayload(event string, payload interface{}) {
+	s.LogFields(log.Event(event), log.Object("payload", payload))
+}
+
+func (s *span) Log(data opentracing.LogData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.logFields(data.Timestamp, data.ToLogRecord().Fields)
+	}
+}
+
+// injectedHeaders returns the sw8 headers of the exit span. The other spans not ended inject their context
+// by an exit span created on demand, whose peer is unknown, so it ends right away.
+func (s *span) injectedHeaders() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exit || s.ended {
+		return s.headers
+	}
+	var headers map[string]string
+	exit, _, err := s.tracer.tracer.CreateExitSpanWithContext(s.ctx, s.span.GetOperationName(), defaultPeer, func(key, value string) error {
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[key] = value
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+	exit.End()
+	return headers
+}
+
+// spanContext implements opentracing.SpanContext, it is the context of a span in the process,
+// or the context extracted from the carrier.
+type spanContext struct {
+	// the context carrying the go2sky span
+	ctx    context.Context
+	span   *span
+	remote *propagation.SpanContext
+}
+
+// baggage returns a copy of the correlation context
+func (c *spanContext) baggage() map[string]string {
+	var correlation map[string]string
+	switch {
+	case c.remote != nil:
+		correlation = c.remote.CorrelationContext
+	case c.ctx != nil:
+		if rs, ok := go2sky.ActiveSpan(c.ctx).(go2sky.ReportedSpan); ok {
+			correlation = rs.Context().CorrelationContext
+		}
+	}
+	baggage := make(map[string]string, len(correlation))
+	for k, v := range correlation {
+		baggage[k] = v
+	}
+	return baggage
+}
+
+func (c *spanContext) ForeachBaggageItem(handler func(k, v string) bool) {
+	for k, v := range c.baggage() {
+		if !handler(k, v) {
+			return
+		}
+	}
+}
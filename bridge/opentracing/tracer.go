@@ -0,0 +1,241 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package opentracing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/textproto"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/tool"
+	"github.com/SkyAPM/go2sky/propagation"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+const (
+	errInvalidTracer = tool.Error("invalid tracer")
+	defaultPeer      = "unknown"
+)
+
+// Tracer implements opentracing.Tracer by the go2sky Tracer.
+type Tracer struct {
+	tracer *go2sky.Tracer
+}
+
+// NewTracer returns the opentracing.Tracer creating the spans by the tracer.
+func NewTracer(tracer *go2sky.Tracer) (*Tracer, error) {
+	if tracer == nil {
+		return nil, errInvalidTracer
+	}
+	return &Tracer{tracer: tracer}, nil
+}
+
+// StartSpan implements opentracing.Tracer. The span is the child of the first referenced span of the process,
+// both ChildOf and FollowsFrom ones, and refers to the extracted contexts. The span without references is the root
+// of a new trace. The start time option is ignored, the spans start at the time of calling StartSpan.
+func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	options := opentracing.StartSpanOptions{}
+	for _, opt := range opts {
+		opt.Apply(&options)
+	}
+	ctx := context.Background()
+	var remotes []*propagation.SpanContext
+	parented := false
+	for _, ref := range options.References {
+		sc, ok := ref.ReferencedContext.(*spanContext)
+		if !ok {
+			continue
+		}
+		switch {
+		case sc.remote != nil:
+			remotes = append(remotes, sc.remote)
+		case !parented && sc.ctx != nil:
+			ctx, parented = sc.ctx, true
+		}
+	}
+
+	s := &span{tracer: t}
+	var err error
+	switch options.Tags[string(ext.SpanKind)] {
+	case ext.SpanKindRPCClientEnum, ext.SpanKindProducerEnum, string(ext.SpanKindRPCClientEnum), string(ext.SpanKindProducerEnum):
+		s.exit = true
+		s.peer.update(options.Tags)
+		s.span, s.ctx, err = t.tracer.CreateExitSpanWithContext(ctx, operationName, s.peer.String(), func(key, value string) error {
+			if s.headers == nil {
+				s.headers = make(map[string]string)
+			}
+			s.headers[key] = value
+			return nil
+		})
+	default:
+		spanType := go2sky.SpanTypeLocal
+		if len(remotes) > 0 || isEntry(options.Tags[string(ext.SpanKind)]) {
+			spanType = go2sky.SpanTypeEntry
+		}
+		spanOpts := []go2sky.SpanOption{go2sky.WithOperationName(operationName), go2sky.WithSpanType(spanType)}
+		for _, remote := range remotes {
+			spanOpts = append(spanOpts, go2sky.WithContext(remote))
+		}
+		s.span, s.ctx, err = t.tracer.CreateLocalSpan(ctx, spanOpts...)
+	}
+	if err != nil {
+		return opentracing.NoopTracer{}.StartSpan(operationName)
+	}
+	for key, value := range options.Tags {
+		s.setTag(key, value)
+	}
+	return s
+}
+
+func isEntry(kind interface{}) bool {
+	switch kind {
+	case ext.SpanKindRPCServerEnum, ext.SpanKindConsumerEnum, string(ext.SpanKindRPCServerEnum), string(ext.SpanKindConsumerEnum):
+		return true
+	}
+	return false
+}
+
+// Inject implements opentracing.Tracer, the context of the span is injected into the HTTPHeaders and TextMap
+// carriers by the sw8 headers, with the current baggage items. The spans other than the exit spans inject the
+// context of an exit span created on demand as their child. It returns opentracing.ErrInvalidSpanContext for
+// the extracted contexts, the sampled out spans and the ended spans other than the exit spans.
+func (t *Tracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	if format != opentracing.HTTPHeaders && format != opentracing.TextMap {
+		return opentracing.ErrUnsupportedFormat
+	}
+	writer, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+	c, ok := sc.(*spanContext)
+	if !ok || c.span == nil {
+		return opentracing.ErrInvalidSpanContext
+	}
+	headers := c.span.injectedHeaders()
+	if headers == nil {
+		return opentracing.ErrInvalidSpanContext
+	}
+	// encode the current correlation context, the baggage items may be set after the exit span is created
+	refSc := &propagation.SpanContext{}
+	if err := refSc.DecodeSW8(headers[propagation.Header]); err != nil {
+		return err
+	}
+	refSc.CorrelationContext = c.baggage()
+	return refSc.Encode(func(key, value string) error {
+		if value != "" {
+			writer.Set(key, value)
+		}
+		return nil
+	})
+}
+
+// Extract implements opentracing.Tracer, the context is extracted from the HTTPHeaders and TextMap carriers
+// by the sw8 headers. It returns opentracing.ErrSpanContextNotFound if the carrier has no valid context.
+func (t *Tracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	if format != opentracing.HTTPHeaders && format != opentracing.TextMap {
+		return nil, opentracing.ErrUnsupportedFormat
+	}
+	reader, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+	headers := make(map[string]string)
+	err := reader.ForeachKey(func(key, value string) error {
+		headers[textproto.CanonicalMIMEHeaderKey(key)] = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	remote := &propagation.SpanContext{}
+	err = remote.Decode(func(key string) (string, error) {
+		return headers[textproto.CanonicalMIMEHeaderKey(key)], nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !remote.Valid {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	return &spanContext{remote: remote}, nil
+}
+
+// ContextWithSpan returns the context carrying the go2sky span of the OpenTracing span,
+// the native go2sky spans created in it are the children of the span.
+func ContextWithSpan(ctx context.Context, s opentracing.Span) context.Context {
+	if c, ok := s.Context().(*spanContext); ok && c.span != nil {
+		return go2sky.WithSpan(ctx, c.span.span)
+	}
+	return ctx
+}
+
+// SpanContextFromContext returns the opentracing.SpanContext of the go2sky span in the context,
+// the OpenTracing spans referring to it are the children of the native go2sky span.
+func SpanContextFromContext(ctx context.Context) opentracing.SpanContext {
+	return &spanContext{ctx: ctx}
+}
+
+// peer is the address of the exit span resolved by the peer tags
+type peer struct {
+	service  string
+	hostname string
+	address  string
+	port     string
+}
+
+// update updates the peer by the tags, and returns whether the peer is changed
+func (p *peer) update(tags map[string]interface{}) bool {
+	before := *p
+	for key, value := range tags {
+		switch key {
+		case string(ext.PeerService):
+			p.service = fmt.Sprint(value)
+		case string(ext.PeerHostname):
+			p.hostname = fmt.Sprint(value)
+		case string(ext.PeerHostIPv4):
+			if ip, ok := value.(uint32); ok {
+				p.address = net.IPv4(byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip)).String()
+			} else {
+				p.address = fmt.Sprint(value)
+			}
+		case string(ext.PeerHostIPv6):
+			p.address = fmt.Sprint(value)
+		case string(ext.PeerPort):
+			p.port = fmt.Sprint(value)
+		}
+	}
+	return *p != before
+}
+
+func (p peer) String() string {
+	host := p.hostname
+	if host == "" {
+		host = p.address
+	}
+	switch {
+	case p.service != "":
+		return p.service
+	case host != "" && p.port != "":
+		return net.JoinHostPort(host, p.port)
+	case host != "":
+		return host
+	}
+	return defaultPeer
+}
@@ -0,0 +1,24 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+/*
+Package otel bridges the OpenTelemetry tracing API to go2sky, so the libraries instrumented by OpenTelemetry
+report to SkyWalking. The spans are created by the go2sky Tracer and carried in the context by go2sky,
+the OpenTelemetry spans and the native go2sky spans of a request end up in one segment.
+The server and consumer spans are entry spans, the client and producer spans are exit spans,
+and the others are local spans. Propagator carries the SkyWalking context across the processes by the sw8 headers.
+*/
+package otel
@@ -0,0 +1,151 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package otel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	otelpropagation "go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+func newProvider(t *testing.T) (*TracerProvider, *go2sky.Tracer, *testutil.Reporter) {
+	reporter := testutil.NewReporter(10)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewTracerProvider(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider, tracer, reporter
+}
+
+// segment returns the spans of the reported segment sorted by the span id
+
+func TestTracer_Segment(t *testing.T) {
+	provider, tracer, reporter := newProvider(t)
+	otelTracer := provider.Tracer("library")
+
+	ctx, server := otelTracer.Start(context.Background(), "GET /orders", trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("http.method", "GET")))
+	native, nativeCtx, err := tracer.CreateLocalSpan(ctx, go2sky.WithOperationName("native"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, client := otelTracer.Start(nativeCtx, "SELECT", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "mysql"), attribute.String("server.address", "db")))
+	client.SetAttributes(attribute.Int("server.port", 3306), attribute.String("db.statement", "SELECT 1"))
+	client.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", 2)))
+	client.RecordError(errors.New("timeout"))
+	client.SetStatus(codes.Error, "timeout")
+	client.End()
+	native.End()
+	server.SetAttributes(attribute.Int("http.status_code", 200))
+	server.SetStatus(codes.Ok, "")
+	server.SetStatus(codes.Error, "ignored after ok")
+	server.End()
+	server.End()
+
+	spans := reporter.Segment(t)
+	if len(spans) != 3 {
+		t.Fatalf("the expected 3 spans in the segment, current is %d", len(spans))
+	}
+	entry, local, exit := spans[0], spans[1], spans[2]
+	if entry.SpanType() != agentv3.SpanType_Entry || entry.OperationName() != "GET /orders" || entry.IsError() ||
+		entry.SpanLayer() != agentv3.SpanLayer_Http || testutil.TagValue(entry, go2sky.TagStatusCode) != "200" ||
+		testutil.TagValue(entry, go2sky.TagHTTPMethod) != "GET" || testutil.TagValue(entry, tagScopeName) != "library" {
+		t.Errorf("unexpected entry span %s: error %t, layer %s, tags %v", entry.OperationName(), entry.IsError(), entry.SpanLayer(), entry.Tags())
+	}
+	if local.SpanType() != agentv3.SpanType_Local || local.OperationName() != "native" || local.Context().ParentSpanID != entry.Context().SpanID {
+		t.Errorf("unexpected native span %s, parent %d", local.OperationName(), local.Context().ParentSpanID)
+	}
+	if exit.SpanType() != agentv3.SpanType_Exit || exit.Context().ParentSpanID != local.Context().SpanID || !exit.IsError() ||
+		exit.Peer() != "db:3306" || exit.SpanLayer() != agentv3.SpanLayer_Database ||
+		testutil.TagValue(exit, go2sky.TagDBType) != "mysql" || testutil.TagValue(exit, go2sky.TagDBStatement) != "SELECT 1" {
+		t.Errorf("unexpected exit span %s: peer %s, error %t, layer %s, tags %v", exit.OperationName(), exit.Peer(), exit.IsError(), exit.SpanLayer(), exit.Tags())
+	}
+	if logs := exit.Logs(); len(logs) != 3 || logs[0].Data[0].Value != "retry" || logs[0].Data[1].Value != "2" ||
+		logs[1].Data[0].Value != "exception" || logs[1].Data[2].Value != "timeout" {
+		t.Errorf("unexpected logs of the events %v", logs)
+	}
+}
+
+func TestTracer_SpanContext(t *testing.T) {
+	provider, _, reporter := newProvider(t)
+	ctx, parent := provider.Tracer("").Start(context.Background(), "parent")
+	_, child := provider.Tracer("").Start(ctx, "child")
+	_, root := provider.Tracer("").Start(ctx, "root", trace.WithNewRoot())
+
+	if !parent.SpanContext().IsValid() || !parent.IsRecording() {
+		t.Fatal("the span context should be valid")
+	}
+	if child.SpanContext().TraceID() != parent.SpanContext().TraceID() || child.SpanContext().SpanID() == parent.SpanContext().SpanID() {
+		t.Error("the child should be in the trace of the parent with its own span id")
+	}
+	if root.SpanContext().TraceID() == parent.SpanContext().TraceID() {
+		t.Error("the new root should start a new trace")
+	}
+	child.End()
+	parent.End()
+	root.End()
+	if parent.IsRecording() {
+		t.Error("the ended span should not be recording")
+	}
+	for i := 0; i < 2; i++ {
+		if spans := reporter.Segment(t); len(spans) == 0 {
+			t.Error("empty segment")
+		}
+	}
+}
+
+func TestPropagator(t *testing.T) {
+	provider, _, reporter := newProvider(t)
+	propagator := Propagator{}
+	ctx, client := provider.Tracer("").Start(context.Background(), "/call", trace.WithSpanKind(trace.SpanKindProducer))
+	header := http.Header{}
+	propagator.Inject(ctx, otelpropagation.HeaderCarrier(header))
+	client.End()
+	producer := reporter.Segment(t)[0]
+	if header.Get("sw8") == "" {
+		t.Fatal("the sw8 header should be injected")
+	}
+
+	ctx = propagator.Extract(context.Background(), otelpropagation.HeaderCarrier(header))
+	_, server := provider.Tracer("").Start(ctx, "/serve", trace.WithSpanKind(trace.SpanKindConsumer))
+	server.End()
+	consumer := reporter.Segment(t)[0]
+	if consumer.SpanType() != agentv3.SpanType_Entry || len(consumer.Refs()) != 1 ||
+		consumer.Refs()[0].ParentSegmentID != producer.Context().SegmentID || consumer.Context().TraceID != producer.Context().TraceID {
+		t.Errorf("the consumer should refer to the producer, current refs are %v", consumer.Refs())
+	}
+}
+
+func TestNewTracerProvider_InvalidTracer(t *testing.T) {
+	if _, err := NewTracerProvider(nil); err != errInvalidTracer {
+		t.Errorf("the expected invalid tracer error, current is %v", err)
+	}
+}
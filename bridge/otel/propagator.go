@@ -0,0 +1,65 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package otel
+
+import (
+	"context"
+
+	"github.com/SkyAPM/go2sky/propagation"
+	otelpropagation "go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// carrierKey is the context key of the sw8 headers extracted by Propagator
+type carrierKey struct{}
+
+// Propagator implements propagation.TextMapPropagator of OpenTelemetry by the sw8 headers,
+// it should be setup as the global propagator so the instrumented libraries carry the SkyWalking context.
+// Inject writes the headers of the exit span in the context, and the entry span created in the context
+// returned by Extract refers to the extracted context.
+type Propagator struct{}
+
+var _ otelpropagation.TextMapPropagator = Propagator{}
+
+func (Propagator) Inject(ctx context.Context, carrier otelpropagation.TextMapCarrier) {
+	s, ok := trace.SpanFromContext(ctx).(*span)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, value := range s.headers {
+		carrier.Set(key, value)
+	}
+}
+
+func (p Propagator) Extract(ctx context.Context, carrier otelpropagation.TextMapCarrier) context.Context {
+	headers := make(map[string]string)
+	for _, key := range p.Fields() {
+		if value := carrier.Get(key); value != "" {
+			headers[key] = value
+		}
+	}
+	if len(headers) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, carrierKey{}, headers)
+}
+
+func (Propagator) Fields() []string {
+	return []string{propagation.Header, propagation.HeaderCorrelation}
+}
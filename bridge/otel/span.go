@@ -0,0 +1,248 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package otel
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+const (
+	tagScopeName go2sky.Tag = "otel.scope.name"
+	defaultPeer             = "unknown"
+)
+
+// tags maps the attributes of the semantic conventions to the SkyWalking tags,
+// the other attributes are tagged by their keys
+var tags = map[attribute.Key]go2sky.Tag{
+	"http.method":                go2sky.TagHTTPMethod,
+	"http.request.method":        go2sky.TagHTTPMethod,
+	"http.url":                   go2sky.TagURL,
+	"url.full":                   go2sky.TagURL,
+	"http.status_code":           go2sky.TagStatusCode,
+	"http.response.status_code":  go2sky.TagStatusCode,
+	"db.system":                  go2sky.TagDBType,
+	"db.name":                    go2sky.TagDBInstance,
+	"db.statement":               go2sky.TagDBStatement,
+	"messaging.destination.name": go2sky.TagMQTopic,
+	"rpc.method":                 go2sky.TagRPCMethod,
+	"rpc.grpc.status_code":       go2sky.TagRPCStatusCode,
+}
+
+// layers maps the namespaces of the attributes to the span layers
+var layers = map[string]agentv3.SpanLayer{
+	"http":      agentv3.SpanLayer_Http,
+	"db":        agentv3.SpanLayer_Database,
+	"messaging": agentv3.SpanLayer_MQ,
+	"rpc":       agentv3.SpanLayer_RPCFramework,
+	"faas":      agentv3.SpanLayer_FAAS,
+}
+
+// span implements trace.Span by a go2sky span
+type span struct {
+	mu          sync.Mutex
+	provider    *TracerProvider
+	span        go2sky.Span
+	spanContext trace.SpanContext
+	exit        bool
+	peer        peer
+	layered     bool
+	// the sw8 headers injected by the exit span
+	headers map[string]string
+	ok      bool
+	ended   bool
+}
+
+// End implements trace.Span, the end time is the time of calling End
+func (s *span) End(...trace.SpanEndOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.ended = true
+	s.span.End()
+}
+
+// AddEvent implements trace.Span, the event is logged with its attributes
+func (s *span) AddEvent(name string, options ...trace.EventOption) {
+	config := trace.NewEventConfig(options...)
+	s.log(config.Timestamp(), append([]string{"event", name}, keyValues(config.Attributes())...))
+}
+
+func (s *span) IsRecording() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, noop := s.span.(*go2sky.NoopSpan)
+	return !noop && !s.ended
+}
+
+// RecordError implements trace.Span, the error is logged as the exception event
+func (s *span) RecordError(err error, options ...trace.EventOption) {
+	if err == nil {
+		return
+	}
+	config := trace.NewEventConfig(options...)
+	s.log(config.Timestamp(), append([]string{"event", "exception",
+		"exception.type", fmt.Sprintf("%T", err), "exception.message", err.Error()}, keyValues(config.Attributes())...))
+}
+
+func (s *span) SpanContext() trace.SpanContext {
+	return s.spanContext
+}
+
+// SetStatus implements trace.Span, the span is marked as error by the error status unless the status is ok
+func (s *span) SetStatus(code codes.Code, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended || s.ok {
+		return
+	}
+	switch code {
+	case codes.Ok:
+		s.ok = true
+	case codes.Error:
+		s.span.Error(time.Now(), "status", description)
+	}
+}
+
+func (s *span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.span.SetOperationName(name)
+	}
+}
+
+func (s *span) SetAttributes(kv ...attribute.KeyValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.setAttributes(kv)
+	}
+}
+
+func (s *span) TracerProvider() trace.TracerProvider {
+	return s.provider
+}
+
+// setAttributes tags the attributes, and updates the layer by the first namespaced attribute
+// and the peer of the exit span by the network attributes
+func (s *span) setAttributes(kv []attribute.KeyValue) {
+	for _, attr := range kv {
+		if tag, ok := tags[attr.Key]; ok {
+			s.span.Tag(tag, attr.Value.Emit())
+		} else {
+			s.span.Tag(go2sky.Tag(attr.Key), attr.Value.Emit())
+		}
+		if !s.layered {
+			namespace, _, _ := strings.Cut(string(attr.Key), ".")
+			if layer, ok := layers[namespace]; ok {
+				s.span.SetSpanLayer(layer)
+				s.layered = true
+			}
+		}
+	}
+	if s.exit && s.peer.update(kv) {
+		s.span.SetPeer(s.peer.String())
+	}
+}
+
+func (s *span) log(timestamp time.Time, ll []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	s.span.Log(timestamp, ll...)
+}
+
+func keyValues(kv []attribute.KeyValue) []string {
+	ll := make([]string, 0, 2*len(kv))
+	for _, attr := range kv {
+		ll = append(ll, string(attr.Key), attr.Value.Emit())
+	}
+	return ll
+}
+
+// peer is the address of the exit span resolved by the network attributes
+type peer struct {
+	service string
+	address string
+	port    string
+}
+
+// update updates the peer by the attributes, and returns whether the peer is changed
+func (p *peer) update(kv []attribute.KeyValue) bool {
+	before := *p
+	for _, attr := range kv {
+		switch attr.Key {
+		case "peer.service":
+			p.service = attr.Value.Emit()
+		case "server.address", "net.peer.name":
+			p.address = attr.Value.Emit()
+		case "server.port", "net.peer.port":
+			p.port = attr.Value.Emit()
+		}
+	}
+	return *p != before
+}
+
+func (p peer) String() string {
+	switch {
+	case p.service != "":
+		return p.service
+	case p.address != "" && p.port != "":
+		return net.JoinHostPort(p.address, p.port)
+	case p.address != "":
+		return p.address
+	}
+	return defaultPeer
+}
+
+// newSpanContext returns the OpenTelemetry span context identifying the go2sky span in the context,
+// the ids are hashed from the SkyWalking trace id, segment id and span id
+func newSpanContext(ctx context.Context) trace.SpanContext {
+	traceID := go2sky.TraceID(ctx)
+	if traceID == go2sky.EmptyTraceID {
+		return trace.SpanContext{}
+	}
+	var config trace.SpanContextConfig
+	h := fnv.New128a()
+	_, _ = h.Write([]byte(traceID))
+	copy(config.TraceID[:], h.Sum(nil))
+	h64 := fnv.New64a()
+	_, _ = h64.Write([]byte(go2sky.TraceSegmentID(ctx) + "-" + strconv.Itoa(int(go2sky.SpanID(ctx)))))
+	copy(config.SpanID[:], h64.Sum(nil))
+	config.TraceFlags = trace.FlagsSampled
+	return trace.NewSpanContext(config)
+}
@@ -0,0 +1,100 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package otel
+
+import (
+	"context"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/tool"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	errInvalidTracer = tool.Error("invalid tracer")
+)
+
+// TracerProvider implements trace.TracerProvider by the go2sky Tracer.
+type TracerProvider struct {
+	tracer *go2sky.Tracer
+}
+
+// NewTracerProvider returns the TracerProvider creating the spans by the tracer.
+func NewTracerProvider(tracer *go2sky.Tracer) (*TracerProvider, error) {
+	if tracer == nil {
+		return nil, errInvalidTracer
+	}
+	return &TracerProvider{tracer: tracer}, nil
+}
+
+// Tracer implements trace.TracerProvider, the spans are tagged with the instrumentation name.
+func (p *TracerProvider) Tracer(name string, _ ...trace.TracerOption) trace.Tracer {
+	return &tracer{provider: p, name: name}
+}
+
+type tracer struct {
+	provider *TracerProvider
+	name     string
+}
+
+// Start implements trace.Tracer. The parent is the go2sky span in the context, which may be an OpenTelemetry span
+// or a native one, and the entry spans refer to the context extracted by Propagator.
+func (t *tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(opts...)
+	if config.NewRoot() {
+		ctx = go2sky.WithSpan(ctx, nil)
+	}
+	s := &span{provider: t.provider}
+	var (
+		gs   go2sky.Span
+		nCtx context.Context
+		err  error
+	)
+	switch config.SpanKind() {
+	case trace.SpanKindServer, trace.SpanKindConsumer:
+		headers, _ := ctx.Value(carrierKey{}).(map[string]string)
+		gs, nCtx, err = t.provider.tracer.CreateEntrySpan(ctx, name, func(key string) (string, error) {
+			return headers[key], nil
+		})
+		if err == nil {
+			// the extracted context is referred once
+			nCtx = context.WithValue(nCtx, carrierKey{}, nil)
+		}
+	case trace.SpanKindClient, trace.SpanKindProducer:
+		s.exit = true
+		s.peer.update(config.Attributes())
+		gs, nCtx, err = t.provider.tracer.CreateExitSpanWithContext(ctx, name, s.peer.String(), func(key, value string) error {
+			if s.headers == nil {
+				s.headers = make(map[string]string)
+			}
+			s.headers[key] = value
+			return nil
+		})
+	default:
+		gs, nCtx, err = t.provider.tracer.CreateLocalSpan(ctx, go2sky.WithOperationName(name))
+	}
+	if err != nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	s.span = gs
+	s.spanContext = newSpanContext(nCtx)
+	if t.name != "" {
+		gs.Tag(tagScopeName, t.name)
+	}
+	s.setAttributes(config.Attributes())
+	return trace.ContextWithSpan(nCtx, s), s
+}
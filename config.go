@@ -0,0 +1,304 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ReporterTypeGRPC reports to the backend OAP server through gRPC
+	ReporterTypeGRPC = "grpc"
+	// ReporterTypeLog prints the segments and logs to the standard output
+	ReporterTypeLog = "log"
+)
+
+var (
+	reporterFactoriesMu sync.RWMutex
+	reporterFactories   = make(map[string]ReporterFactory)
+)
+
+// AgentConfig is the whole agent configuration, it could be loaded from a YAML file by LoadConfig.
+// Every field could be overridden by the environment variable in its env tag.
+type AgentConfig struct {
+	// The name of the service
+	Service string `yaml:"service" env:"SW_AGENT_NAME"`
+	// The name of the service instance, randomly generated when it is empty
+	Instance string `yaml:"instance" env:"SW_AGENT_INSTANCE_NAME"`
+	// The sample rate, it's [0, 1]
+	Sample      float64                `yaml:"sample" env:"SW_AGENT_SAMPLE"`
+	Correlation CorrelationAgentConfig `yaml:"correlation"`
+	// Compute the endpoint RED metrics from all the spans regardless of the sampling
+	EndpointMetrics bool           `yaml:"endpoint_metrics" env:"SW_AGENT_ENDPOINT_METRICS_ENABLE"`
+	Reporter        ReporterConfig `yaml:"reporter"`
+}
+
+// CorrelationAgentConfig is the correlation context limits
+type CorrelationAgentConfig struct {
+	MaxKeyCount  int `yaml:"element_max_number" env:"SW_AGENT_CORRELATION_ELEMENT_MAX_NUMBER"`
+	MaxValueSize int `yaml:"value_max_length" env:"SW_AGENT_CORRELATION_VALUE_MAX_LENGTH"`
+}
+
+// ReporterConfig is the configuration of the reporter, the intervals are in seconds
+type ReporterConfig struct {
+	// The reporter type, grpc or log
+	Type string `yaml:"type" env:"SW_AGENT_REPORTER_TYPE"`
+	// The backend OAP server address
+	BackendServices string `yaml:"backend_services" env:"SW_AGENT_COLLECTOR_BACKEND_SERVICES"`
+	Authentication  string `yaml:"authentication" env:"SW_AGENT_AUTHENTICATION"`
+	Layer           string `yaml:"layer" env:"SW_AGENT_LAYER"`
+	// The heartbeat period, must be positive
+	HeartbeatPeriod int `yaml:"heartbeat_period" env:"SW_AGENT_COLLECTOR_HEARTBEAT_PERIOD"`
+	// The dynamic configuration fetching interval, <= 0 turns the CDS off explicitly
+	DynamicConfigInterval int `yaml:"dynamic_config_interval" env:"SW_AGENT_COLLECTOR_GET_AGENT_DYNAMIC_CONFIG_INTERVAL"`
+	// The local file of the dynamic configurations, the backend CDS is used when it is empty
+	DynamicConfigFile string `yaml:"dynamic_config_file" env:"SW_AGENT_DYNAMIC_CONFIG_FILE"`
+	MaxSendQueueSize  int    `yaml:"max_send_queue_size" env:"SW_AGENT_COLLECTOR_MAX_SEND_QUEUE_SIZE"`
+	// The meter collection interval, <= 0 turns the meter collection off
+	MeterCollectPeriod int `yaml:"meter_collect_period" env:"SW_AGENT_METER_COLLECT_PERIOD"`
+	// Whether the host CPU and memory metrics are collected
+	HostMetrics        bool              `yaml:"host_metrics" env:"SW_AGENT_HOST_METRICS_ENABLE"`
+	InstanceProperties map[string]string `yaml:"instance_properties" env:"SW_AGENT_INSTANCE_PROPERTIES_JSON"`
+	// The minimum level of the reported logs, all the levels are reported when it is empty
+	LogMinLevel string `yaml:"log_min_level" env:"SW_AGENT_LOG_MIN_LEVEL"`
+	// The max logs sent in a batch
+	LogBatchSize int `yaml:"log_batch_size" env:"SW_AGENT_LOG_BATCH_SIZE"`
+	// The max logs reported per second of the levels, eg: {"debug": 100}
+	LogRateLimit      map[string]int `yaml:"log_rate_limit" env:"SW_AGENT_LOG_RATE_LIMIT_JSON"`
+	ProcessStatusHook bool           `yaml:"process_status_hook" env:"SW_AGENT_PROCESS_STATUS_HOOK_ENABLE"`
+	ProcessLabels     []string       `yaml:"process_labels" env:"SW_AGENT_PROCESS_LABELS"`
+	TLS               TLSConfig      `yaml:"tls"`
+}
+
+// TLSConfig is the transport layer security of the reporter, TLS is used when any of them is set
+type TLSConfig struct {
+	// Use TLS with the system root CAs when no trusted CA is set
+	Force bool `yaml:"force" env:"SW_AGENT_FORCE_TLS"`
+	// The trusted CA of the backend
+	CAPath string `yaml:"ca_path" env:"SW_AGENT_SSL_TRUSTED_CA_PATH"`
+	// The client certificate chain and key for mutual TLS
+	CertChainPath string `yaml:"cert_chain_path" env:"SW_AGENT_SSL_CERT_CHAIN_PATH"`
+	KeyPath       string `yaml:"key_path" env:"SW_AGENT_SSL_KEY_PATH"`
+}
+
+// Enabled returns whether the TLS is used
+func (c *TLSConfig) Enabled() bool {
+	return c.Force || c.CAPath != "" || c.CertChainPath != ""
+}
+
+// ReporterFactory creates the reporter from the configuration, the reporters are registered
+// by their packages, e.g. import github.com/SkyAPM/go2sky/reporter for grpc and log.
+type ReporterFactory func(config *ReporterConfig) (Reporter, error)
+
+// RegisterReporterFactory makes a reporter type available to NewTracerFromConfig
+func RegisterReporterFactory(reporterType string, factory ReporterFactory) {
+	reporterFactoriesMu.Lock()
+	defer reporterFactoriesMu.Unlock()
+	reporterFactories[reporterType] = factory
+}
+
+// DefaultAgentConfig returns the configuration with the default values
+func DefaultAgentConfig() *AgentConfig {
+	return &AgentConfig{
+		Sample: 1,
+		Correlation: CorrelationAgentConfig{
+			MaxKeyCount:  3,
+			MaxValueSize: 128,
+		},
+		Reporter: ReporterConfig{
+			Type:                  ReporterTypeGRPC,
+			HeartbeatPeriod:       20,
+			DynamicConfigInterval: 20,
+			MaxSendQueueSize:      30000,
+			MeterCollectPeriod:    15,
+			HostMetrics:           true,
+			LogBatchSize:          100,
+		},
+	}
+}
+
+// LoadConfig reads the agent configuration from the YAML file, then overrides it by
+// the environment variables. Only the defaults and environment variables are used
+// when the path is empty.
+func LoadConfig(path string) (*AgentConfig, error) {
+	config := DefaultAgentConfig()
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(config); err != nil && err != io.EOF {
+			return nil, errors.Wrap(err, path)
+		}
+	}
+	if err := overrideFromEnv(reflect.ValueOf(config).Elem()); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the configuration, the error names the offending key
+func (c *AgentConfig) Validate() error {
+	switch {
+	case c.Service == "":
+		return errors.New("service: must not be empty")
+	case c.Sample < 0 || c.Sample > 1:
+		return errors.Errorf("sample: must be in [0, 1], got %v", c.Sample)
+	case c.Correlation.MaxKeyCount < 0:
+		return errors.Errorf("correlation.element_max_number: must not be negative, got %d", c.Correlation.MaxKeyCount)
+	case c.Correlation.MaxValueSize < 0:
+		return errors.Errorf("correlation.value_max_length: must not be negative, got %d", c.Correlation.MaxValueSize)
+	case c.Reporter.Type == "":
+		return errors.New("reporter.type: must not be empty")
+	case c.Reporter.Type == ReporterTypeGRPC && c.Reporter.BackendServices == "":
+		return errors.New("reporter.backend_services: must not be empty for the grpc reporter")
+	case c.Reporter.HeartbeatPeriod <= 0:
+		return errors.Errorf("reporter.heartbeat_period: must be positive, got %d", c.Reporter.HeartbeatPeriod)
+	case c.Reporter.MaxSendQueueSize <= 0:
+		return errors.Errorf("reporter.max_send_queue_size: must be positive, got %d", c.Reporter.MaxSendQueueSize)
+	case c.Reporter.LogBatchSize <= 0:
+		return errors.Errorf("reporter.log_batch_size: must be positive, got %d", c.Reporter.LogBatchSize)
+	case c.Reporter.LogMinLevel != "" && !isLogLevel(c.Reporter.LogMinLevel):
+		return errors.Errorf("reporter.log_min_level: must be debug, info, warn or error, got %s", c.Reporter.LogMinLevel)
+	case c.Reporter.TLS.KeyPath != "" && c.Reporter.TLS.CertChainPath == "":
+		return errors.New("reporter.tls.cert_chain_path: must be set together with reporter.tls.key_path")
+	case c.Reporter.TLS.CertChainPath != "" && c.Reporter.TLS.KeyPath == "":
+		return errors.New("reporter.tls.key_path: must be set together with reporter.tls.cert_chain_path")
+	}
+	return nil
+}
+
+// NewTracerFromConfig validates the configuration, creates the reporter and the tracer together.
+// The options are applied after the ones from the configuration.
+func NewTracerFromConfig(config *AgentConfig, opts ...TracerOption) (*Tracer, error) {
+	if config == nil {
+		return nil, errParameter
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	reporterFactoriesMu.RLock()
+	factory, ok := reporterFactories[config.Reporter.Type]
+	reporterFactoriesMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("reporter.type: unknown reporter %s, is its package imported?", config.Reporter.Type)
+	}
+	reporter, err := factory(&config.Reporter)
+	if err != nil {
+		return nil, err
+	}
+
+	configOpts := []TracerOption{
+		WithReporter(reporter),
+		WithSampler(config.Sample),
+		WithCorrelation(config.Correlation.MaxKeyCount, config.Correlation.MaxValueSize),
+	}
+	if config.Instance != "" {
+		configOpts = append(configOpts, WithInstance(config.Instance))
+	}
+	if config.EndpointMetrics {
+		configOpts = append(configOpts, WithEndpointMetrics())
+	}
+	tracer, err := NewTracer(config.Service, append(configOpts, opts...)...)
+	if err != nil {
+		reporter.Close()
+		return nil, err
+	}
+	return tracer, nil
+}
+
+// overrideFromEnv sets the fields whose environment variable in the env tag is not empty
+func overrideFromEnv(v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := overrideFromEnv(field); err != nil {
+				return err
+			}
+			continue
+		}
+		env := v.Type().Field(i).Tag.Get("env")
+		if env == "" {
+			continue
+		}
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		if err := setFieldFromString(field, value); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%s=%s", env, value))
+		}
+	}
+	return nil
+}
+
+func setFieldFromString(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		i, err := strconv.ParseInt(value, 0, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(strings.Split(value, ",")))
+	case reflect.Map:
+		m := reflect.New(field.Type())
+		if err := json.Unmarshal([]byte(value), m.Interface()); err != nil {
+			return err
+		}
+		field.Set(m.Elem())
+	default:
+		return errors.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+func isLogLevel(level string) bool {
+	_, err := ParseLogLevel(level)
+	return err == nil
+}
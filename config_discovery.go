@@ -16,7 +16,15 @@
 
 package go2sky
 
-import common "skywalking.apache.org/repo/goapi/collect/common/v3"
+import (
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/SkyAPM/go2sky/logger"
+	common "skywalking.apache.org/repo/goapi/collect/common/v3"
+)
 
 type AgentConfigEventType int32
 
@@ -25,54 +33,140 @@ const (
 	DELETED
 )
 
-func NewConfigDiscoveryService() *ConfigDiscoveryService {
-	return &ConfigDiscoveryService{}
+const cdsLogPrefix = "go2sky-cds"
+
+// ConfigDiscoveryOption allows for functional options to adjust behaviour
+// of a ConfigDiscoveryService to be created by NewConfigDiscoveryService
+type ConfigDiscoveryOption func(s *ConfigDiscoveryService)
+
+// WithConfigDiscoveryLogger setup logger to report the rejected configurations
+func WithConfigDiscoveryLogger(log logger.Log) ConfigDiscoveryOption {
+	return func(s *ConfigDiscoveryService) {
+		s.logger = log
+	}
+}
+
+func NewConfigDiscoveryService(opts ...ConfigDiscoveryOption) *ConfigDiscoveryService {
+	s := &ConfigDiscoveryService{
+		logger: logger.NewDefaultLogger(log.New(os.Stderr, cdsLogPrefix, log.LstdFlags)),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.state.Store(&cdsSnapshot{watchers: map[string]AgentConfigChangeWatcher{}})
+	return s
 }
 
+// ConfigDiscoveryService dispatches the configurations fetched from the backend
+// to the bound and the globally registered watchers. Readers always observe an immutable snapshot, updates
+// are serialized so that every watcher sees the commands in order.
 type ConfigDiscoveryService struct {
-	UUID     string
+	// UUID is the uuid of the last handled configuration command.
+	//
+	// Deprecated: it is only kept for compatibility and is not safe to read while the commands
+	// are handled, use Version instead.
+	UUID string
+
+	mu     sync.Mutex
+	state  atomic.Value
+	logger logger.Log
+}
+
+// cdsSnapshot is never modified after it has been stored
+type cdsSnapshot struct {
+	uuid     string
 	watchers map[string]AgentConfigChangeWatcher
 }
 
+func (s *ConfigDiscoveryService) snapshot() *cdsSnapshot {
+	if state, ok := s.state.Load().(*cdsSnapshot); ok {
+		return state
+	}
+	return &cdsSnapshot{}
+}
+
+// Version returns the uuid of the last handled configuration command
+func (s *ConfigDiscoveryService) Version() string {
+	return s.snapshot().uuid
+}
+
 func (s *ConfigDiscoveryService) BindWatchers(watchers []AgentConfigChangeWatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// bind watchers
-	s.watchers = make(map[string]AgentConfigChangeWatcher)
+	bound := make(map[string]AgentConfigChangeWatcher, len(watchers))
 	for _, watcher := range watchers {
-		s.watchers[watcher.Key()] = watcher
+		bound[watcher.Key()] = watcher
 	}
+	s.state.Store(&cdsSnapshot{uuid: s.snapshot().uuid, watchers: bound})
 }
 
+// HandleCommand applies the configurations of the command fetched from the backend
 func (s *ConfigDiscoveryService) HandleCommand(command *common.Command) {
-	var uuid string
-	var newConfigs = make(map[string]*common.KeyStringValuePair)
-	for _, pair := range command.GetArgs() {
-		if pair.Key == "SerialNumber" {
-		} else if pair.Key == "UUID" {
-			uuid = pair.Value
-		} else {
-			newConfigs[pair.Key] = pair
-		}
-	}
+	s.Apply(CommandConfigurations(command))
+}
+
+// Apply notifies the watchers with the configurations identified by the version,
+// the configurations are ignored when the version has been applied.
+// The watchers whose key is absent or empty are reset to their defaults.
+func (s *ConfigDiscoveryService) Apply(version string, configs map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current := s.snapshot()
 
 	// check same uuid
-	if s.UUID == uuid {
+	if current.uuid == version {
 		return
 	}
 
 	// notify to all watchers
-	for key, watcher := range s.watchers {
-		pair := newConfigs[key]
-		if pair == nil || pair.Value == "" {
-			watcher.Notify(DELETED, "")
-		} else if pair.Value != watcher.Value() {
-			watcher.Notify(MODIFY, pair.Value)
-		}
+	for _, watcher := range current.watchers {
+		s.notify(watcher, configs[watcher.Key()])
+	}
+	for _, watcher := range dynamicConfigs.all() {
+		s.notify(watcher, configs[watcher.Key()])
 	}
 
 	// update uuid
-	s.UUID = uuid
+	s.state.Store(&cdsSnapshot{uuid: version, watchers: current.watchers})
+	s.UUID = version
+}
+
+func (s *ConfigDiscoveryService) notify(watcher AgentConfigChangeWatcher, value string) {
+	if value == "" {
+		watcher.Notify(DELETED, "")
+		return
+	}
+	if value == watcher.Value() {
+		return
+	}
+	if validator, ok := watcher.(AgentConfigChangeValidator); ok {
+		if err := validator.Validate(value); err != nil {
+			s.logger.Errorf("reject dynamic configuration %s=%s: %v", watcher.Key(), value, err)
+			return
+		}
+	}
+	watcher.Notify(MODIFY, value)
+}
+
+// CommandConfigurations extracts the UUID and the configurations from the CDS command
+func CommandConfigurations(command *common.Command) (uuid string, configs map[string]string) {
+	configs = make(map[string]string)
+	for _, pair := range command.GetArgs() {
+		if pair.Key == "SerialNumber" {
+		} else if pair.Key == "UUID" {
+			uuid = pair.Value
+		} else {
+			configs[pair.Key] = pair.Value
+		}
+	}
+	return
 }
 
+// AgentConfigChangeWatcher receives the dynamic configuration changes of a key.
+// Notify may be called while other goroutines are reading the watched value,
+// implementations must be safe for concurrent use.
 type AgentConfigChangeWatcher interface {
 	Key() string
 	Notify(eventType AgentConfigEventType, newValue string)
@@ -17,6 +17,8 @@
 package go2sky
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	commonv3 "skywalking.apache.org/repo/goapi/collect/common/v3"
@@ -107,8 +109,8 @@ func TestHandleCommand(t *testing.T) {
 			if tt.value != testWatcher.currentValue {
 				t.Errorf("error validate current value, current is: %s, excepted is: %s", testWatcher.currentValue, tt.value)
 			}
-			if tt.uuid != configDiscoveryService.UUID {
-				t.Errorf("error validate current uuid, current is: %s, excepted is: %s", configDiscoveryService.UUID, tt.uuid)
+			if tt.uuid != configDiscoveryService.Version() || tt.uuid != configDiscoveryService.UUID {
+				t.Errorf("error validate current uuid, current is: %s, excepted is: %s", configDiscoveryService.Version(), tt.uuid)
 			}
 			if tt.lastEvent != testWatcher.lastEvent {
 				t.Errorf("error validate current last event type, current is: %d, excepted is: %d", testWatcher.lastEvent, tt.lastEvent)
@@ -116,3 +118,94 @@ func TestHandleCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleCommand_Concurrent(t *testing.T) {
+	tracer := &Tracer{}
+	sampler := NewDynamicSampler(1, tracer)
+	configDiscoveryService := NewConfigDiscoveryService()
+	configDiscoveryService.BindWatchers(tracer.cdsWatchers)
+
+	const rounds = 500
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// readers keep sampling and reading the current snapshot
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					sampler.IsSampled("op")
+					_ = sampler.Value()
+					_ = configDiscoveryService.Version()
+				}
+			}
+		}()
+	}
+
+	// several fetch loops may deliver commands at the same time
+	var writers sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			for i := 0; i < rounds; i++ {
+				args := []*commonv3.KeyStringValuePair{
+					{Key: "UUID", Value: fmt.Sprintf("uuid-%d-%d", w, i)},
+				}
+				// flip between sampling everything, nothing and some and delete the key
+				switch i % 4 {
+				case 0:
+					args = append(args, &commonv3.KeyStringValuePair{Key: sampler.Key(), Value: "1"})
+				case 1:
+					args = append(args, &commonv3.KeyStringValuePair{Key: sampler.Key(), Value: "0"})
+				case 2:
+					args = append(args, &commonv3.KeyStringValuePair{Key: sampler.Key(), Value: "0.5"})
+				}
+				configDiscoveryService.HandleCommand(&commonv3.Command{Args: args})
+			}
+		}(w)
+	}
+	writers.Wait()
+	close(done)
+	wg.Wait()
+
+	configDiscoveryService.HandleCommand(&commonv3.Command{Args: []*commonv3.KeyStringValuePair{
+		{Key: "UUID", Value: "final"},
+		{Key: sampler.Key(), Value: "0"},
+	}})
+	if configDiscoveryService.Version() != "final" {
+		t.Errorf("error validate current uuid, current is: %s, excepted is: final", configDiscoveryService.Version())
+	}
+	if sampler.IsSampled("op") {
+		t.Errorf("sampler should not sample after the rate changed to 0")
+	}
+}
+
+func TestBindWatchers_Concurrent(t *testing.T) {
+	configDiscoveryService := NewConfigDiscoveryService()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				configDiscoveryService.BindWatchers([]AgentConfigChangeWatcher{newTestDynamicSampler(0.5)})
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				configDiscoveryService.HandleCommand(&commonv3.Command{Args: []*commonv3.KeyStringValuePair{
+					{Key: "UUID", Value: fmt.Sprintf("uuid-%d-%d", i, j)},
+					{Key: "agent.sample_rate", Value: "0.1"},
+				}})
+			}
+		}(i)
+	}
+	wg.Wait()
+}
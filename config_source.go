@@ -0,0 +1,130 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigSource provides the dynamic configurations to the ConfigDiscoveryService
+type ConfigSource interface {
+	// Fetch returns the latest configurations and the version identifying them.
+	// When nothing has changed since currentVersion, currentVersion is returned
+	// and the configurations are ignored.
+	Fetch(ctx context.Context, currentVersion string) (version string, configs map[string]string, err error)
+}
+
+// FileConfigSource reads the dynamic configurations from a local file,
+// the file is read again only when its modify time or size has changed.
+// Files ending with .yaml or .yml are parsed as YAML, the nested keys are joined
+// by dots, the other files are parsed as properties (key=value per line).
+type FileConfigSource struct {
+	path string
+}
+
+// NewFileConfigSource creates a ConfigSource polling the file
+func NewFileConfigSource(path string) *FileConfigSource {
+	return &FileConfigSource{path: path}
+}
+
+// Fetch implements ConfigSource
+func (f *FileConfigSource) Fetch(ctx context.Context, currentVersion string) (string, map[string]string, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return currentVersion, nil, err
+	}
+	version := fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+	if version == currentVersion {
+		return currentVersion, nil, nil
+	}
+
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return currentVersion, nil, err
+	}
+	var configs map[string]string
+	switch strings.ToLower(filepath.Ext(f.path)) {
+	case ".yaml", ".yml":
+		configs, err = parseYAMLConfigs(content)
+	default:
+		configs, err = parsePropertiesConfigs(content)
+	}
+	if err != nil {
+		return currentVersion, nil, errors.Wrap(err, f.path)
+	}
+	return version, configs, nil
+}
+
+func parsePropertiesConfigs(content []byte) (map[string]string, error) {
+	configs := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "!") {
+			continue
+		}
+		i := strings.IndexAny(text, "=:")
+		if i <= 0 {
+			return nil, errors.Errorf("line %d: missing separator", line)
+		}
+		configs[strings.TrimSpace(text[:i])] = strings.TrimSpace(text[i+1:])
+	}
+	return configs, scanner.Err()
+}
+
+func parseYAMLConfigs(content []byte) (map[string]string, error) {
+	var root map[string]interface{}
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+	configs := make(map[string]string)
+	flattenConfigs("", root, configs)
+	return configs, nil
+}
+
+// flattenConfigs joins the nested keys by dots and the list values by commas
+func flattenConfigs(prefix string, value interface{}, configs map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flattenConfigs(key, item, configs)
+		}
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		configs[prefix] = strings.Join(items, ",")
+	case nil:
+		configs[prefix] = ""
+	default:
+		configs[prefix] = fmt.Sprint(v)
+	}
+}
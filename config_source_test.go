@@ -0,0 +1,149 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileConfigSource_Fetch(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		configs map[string]string
+		wantErr bool
+	}{
+		{
+			name: "properties",
+			file: "agent.properties",
+			content: `# dynamic configurations
+agent.sample_rate=0.5
+correlation.element_max_number : 5
+! ignored
+
+empty.key=
+`,
+			configs: map[string]string{"agent.sample_rate": "0.5", "correlation.element_max_number": "5", "empty.key": ""},
+		},
+		{
+			name: "yaml",
+			file: "agent.yaml",
+			content: `agent:
+  sample_rate: 0.5
+correlation:
+  element_max_number: 5
+biz.paths:
+  - /health
+  - /ready
+`,
+			configs: map[string]string{"agent.sample_rate": "0.5", "correlation.element_max_number": "5", "biz.paths": "/health,/ready"},
+		},
+		{
+			name:    "invalid properties",
+			file:    "invalid.properties",
+			content: "agent.sample_rate",
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			file:    "invalid.yml",
+			content: "agent: [",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			source := NewFileConfigSource(path)
+			version, configs, err := source.Fetch(context.Background(), "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(configs, tt.configs) {
+				t.Errorf("Fetch() configs = %v, want %v", configs, tt.configs)
+			}
+
+			// the same file should not be read again
+			sameVersion, configs, err := source.Fetch(context.Background(), version)
+			if err != nil || sameVersion != version || configs != nil {
+				t.Errorf("Fetch() should return the current version when the file is not changed")
+			}
+		})
+	}
+
+	if _, _, err := NewFileConfigSource(filepath.Join(dir, "missing.yaml")).Fetch(context.Background(), ""); err == nil {
+		t.Error("fetch the missing file should be failed")
+	}
+}
+
+func TestFileConfigSource_DriveWatchers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.properties")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tracer, err := NewTracer("service", WithSampler(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDiscoveryService := NewConfigDiscoveryService()
+	configDiscoveryService.BindWatchers(tracer.cdsWatchers)
+	source := NewFileConfigSource(path)
+	apply := func() {
+		version, configs, err := source.Fetch(context.Background(), configDiscoveryService.Version())
+		if err != nil {
+			t.Fatal(err)
+		}
+		configDiscoveryService.Apply(version, configs)
+	}
+
+	now := time.Now()
+	write("agent.sample_rate=0\n", now)
+	apply()
+	if tracer.Config()["agent.sample_rate"] != fmt.Sprintf("%f", 0.0) {
+		t.Errorf("error validate sample rate, current is: %s", tracer.Config()["agent.sample_rate"])
+	}
+
+	// remove the key, the sampler is reset to the default rate
+	write("correlation.element_max_number=1\n", now.Add(time.Second))
+	apply()
+	if tracer.Config()["agent.sample_rate"] != fmt.Sprintf("%f", 1.0) {
+		t.Errorf("error validate sample rate, current is: %s", tracer.Config()["agent.sample_rate"])
+	}
+	if tracer.Config()[correlationElementMaxNumberKey] != "1" {
+		t.Errorf("error validate correlation key count, current is: %s", tracer.Config()[correlationElementMaxNumberKey])
+	}
+}
@@ -0,0 +1,187 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testAgentConfig = `service: config-service
+instance: config-instance
+sample: 0.5
+correlation:
+  element_max_number: 5
+reporter:
+  type: grpc
+  backend_services: oap:11800
+  heartbeat_period: 30
+  dynamic_config_interval: -1
+  instance_properties:
+    org: SkyAPM
+  process_labels: [a, b]
+  tls:
+    ca_path: /etc/ca.crt
+`
+
+func writeTestConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "agent.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig(writeTestConfig(t, testAgentConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultAgentConfig()
+	want.Service = "config-service"
+	want.Instance = "config-instance"
+	want.Sample = 0.5
+	want.Correlation.MaxKeyCount = 5
+	want.Reporter.BackendServices = "oap:11800"
+	want.Reporter.HeartbeatPeriod = 30
+	want.Reporter.DynamicConfigInterval = -1
+	want.Reporter.InstanceProperties = map[string]string{"org": "SkyAPM"}
+	want.Reporter.ProcessLabels = []string{"a", "b"}
+	want.Reporter.TLS.CAPath = "/etc/ca.crt"
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("LoadConfig() = %+v, want %+v", config, want)
+	}
+}
+
+func TestLoadConfig_EnvOverride(t *testing.T) {
+	envs := map[string]string{
+		swAgentName:                              "env-service",
+		swAgentSample:                            "0.2",
+		swAgentCorrelationValueMaxLength:         "64",
+		"SW_AGENT_COLLECTOR_MAX_SEND_QUEUE_SIZE": "100",
+		"SW_AGENT_INSTANCE_PROPERTIES_JSON":      `{"env":"test"}`,
+		"SW_AGENT_PROCESS_LABELS":                "c,d",
+		"SW_AGENT_FORCE_TLS":                     "true",
+		"SW_AGENT_LOG_RATE_LIMIT_JSON":           `{"debug":10}`,
+	}
+	for k, v := range envs {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	config, err := LoadConfig(writeTestConfig(t, testAgentConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Service != "env-service" || config.Sample != 0.2 || config.Correlation.MaxValueSize != 64 || config.Correlation.MaxKeyCount != 5 {
+		t.Errorf("error validate the tracer configurations overridden by env, current is: %+v", config)
+	}
+	if config.Reporter.MaxSendQueueSize != 100 || config.Reporter.HeartbeatPeriod != 30 || !config.Reporter.TLS.Force {
+		t.Errorf("error validate the reporter configurations overridden by env, current is: %+v", config.Reporter)
+	}
+	if !reflect.DeepEqual(config.Reporter.InstanceProperties, map[string]string{"env": "test"}) {
+		t.Errorf("error validate instance properties, current is: %v", config.Reporter.InstanceProperties)
+	}
+	if !reflect.DeepEqual(config.Reporter.LogRateLimit, map[string]int{"debug": 10}) {
+		t.Errorf("error validate log rate limit, current is: %v", config.Reporter.LogRateLimit)
+	}
+	if !reflect.DeepEqual(config.Reporter.ProcessLabels, []string{"c", "d"}) {
+		t.Errorf("error validate process labels, current is: %v", config.Reporter.ProcessLabels)
+	}
+
+	// only the defaults and env without file
+	config, err = LoadConfig("")
+	if err == nil || !strings.Contains(err.Error(), "reporter.backend_services") {
+		t.Errorf("load config without backend should be failed by reporter.backend_services, current is: %v", err)
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+		key     string
+	}{
+		{name: "unknown key", content: "service: s\nreporter:\n  backend: oap:11800\n", key: "backend"},
+		{name: "empty service", content: "reporter:\n  backend_services: oap:11800\n", key: "service"},
+		{name: "invalid sample", content: "service: s\nsample: 2\nreporter:\n  backend_services: oap:11800\n", key: "sample"},
+		{name: "invalid heartbeat period", content: "service: s\nreporter:\n  backend_services: oap:11800\n  heartbeat_period: 0\n", key: "reporter.heartbeat_period"},
+		{name: "invalid queue size", content: "service: s\nreporter:\n  backend_services: oap:11800\n  max_send_queue_size: 0\n", key: "reporter.max_send_queue_size"},
+		{name: "invalid log level", content: "service: s\nreporter:\n  backend_services: oap:11800\n  log_min_level: fatal\n", key: "reporter.log_min_level"},
+		{name: "key without cert", content: "service: s\nreporter:\n  backend_services: oap:11800\n  tls:\n    key_path: a.key\n", key: "reporter.tls.cert_chain_path"},
+		{name: "invalid env", content: "service: s\nreporter:\n  backend_services: oap:11800\n", env: map[string]string{swAgentSample: "half"}, key: swAgentSample},
+		{name: "invalid env json", content: "service: s\nreporter:\n  backend_services: oap:11800\n",
+			env: map[string]string{"SW_AGENT_INSTANCE_PROPERTIES_JSON": "org"}, key: "SW_AGENT_INSTANCE_PROPERTIES_JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+			_, err := LoadConfig(writeTestConfig(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.key) {
+				t.Errorf("LoadConfig() error = %v, should name %s", err, tt.key)
+			}
+		})
+	}
+}
+
+func TestNewTracerFromConfig(t *testing.T) {
+	config := DefaultAgentConfig()
+	config.Service = "config-service"
+	config.Instance = "config-instance"
+	config.Sample = 0.5
+	config.Reporter.Type = "test"
+	if _, err := NewTracerFromConfig(config); err == nil || !strings.Contains(err.Error(), "reporter.type") {
+		t.Errorf("NewTracerFromConfig() with unknown reporter should be failed by reporter.type, current is: %v", err)
+	}
+
+	var reporterConfig *ReporterConfig
+	reporter := &mockRegisterReporter{}
+	RegisterReporterFactory("test", func(c *ReporterConfig) (Reporter, error) {
+		reporterConfig = c
+		return reporter, nil
+	})
+	defer func() {
+		reporterFactoriesMu.Lock()
+		delete(reporterFactories, "test")
+		reporterFactoriesMu.Unlock()
+	}()
+
+	tracer, err := NewTracerFromConfig(config, WithCorrelation(1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reporterConfig != &config.Reporter {
+		t.Error("the reporter should be created from the reporter configuration")
+	}
+	if tracer.reporter != reporter || tracer.service != "config-service" || tracer.instance != "config-instance" {
+		t.Errorf("error validate tracer, current is: %+v", tracer)
+	}
+	if tracer.Config()["agent.sample_rate"] != "0.500000" {
+		t.Errorf("error validate sample rate, current is: %s", tracer.Config()["agent.sample_rate"])
+	}
+	// the options override the configuration
+	if tracer.correlation.MaxKeyCount != 1 {
+		t.Errorf("error validate correlation, current is: %+v", tracer.correlation)
+	}
+}
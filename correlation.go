@@ -16,7 +16,17 @@
 
 package go2sky
 
-import "context"
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	correlationElementMaxNumberKey = "correlation.element_max_number"
+	correlationValueMaxLengthKey   = "correlation.value_max_length"
+)
 
 type CorrelationConfig struct {
 	MaxKeyCount  int
@@ -53,7 +63,7 @@ func PutCorrelation(ctx context.Context, key, value string) bool {
 		return true
 	}
 	// out of max value size
-	if len(value) > span.tracer().correlation.MaxValueSize {
+	if len(value) > span.tracer().correlationValueSize.Get() {
 		return false
 	}
 	// already exists key
@@ -62,7 +72,7 @@ func PutCorrelation(ctx context.Context, key, value string) bool {
 		return true
 	}
 	// out of max key count
-	if len(correlationContext) >= span.tracer().correlation.MaxKeyCount {
+	if len(correlationContext) >= span.tracer().correlationKeyCount.Get() {
 		return false
 	}
 	span.context().CorrelationContext[key] = value
@@ -81,3 +91,21 @@ func GetCorrelation(ctx context.Context, key string) string {
 	}
 	return span.context().CorrelationContext[key]
 }
+
+// newCorrelationConfigs makes the correlation limits of the tracer changeable by the CDS
+func newCorrelationConfigs(t *Tracer) {
+	t.correlationKeyCount = NewDynamicConfig(correlationElementMaxNumberKey, t.correlation.MaxKeyCount, parseNonNegativeInt)
+	t.correlationValueSize = NewDynamicConfig(correlationValueMaxLengthKey, t.correlation.MaxValueSize, parseNonNegativeInt)
+	t.cdsWatchers = append(t.cdsWatchers, t.correlationKeyCount, t.correlationValueSize)
+}
+
+func parseNonNegativeInt(value string) (int, error) {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, errors.Errorf("%d must not be negative", i)
+	}
+	return i, nil
+}
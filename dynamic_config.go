@@ -0,0 +1,163 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	dynamicConfigs = &dynamicConfigRegistry{}
+)
+
+// AgentConfigChangeValidator is implemented by the watchers which can reject a new value,
+// the ConfigDiscoveryService logs the rejected value instead of notifying the watcher.
+type AgentConfigChangeValidator interface {
+	Validate(newValue string) error
+}
+
+// DynamicConfig is a typed configuration value which could be changed by the
+// Configuration Discovery Service at runtime. It is safe for concurrent use.
+type DynamicConfig[T any] struct {
+	key          string
+	defaultValue T
+	parse        func(string) (T, error)
+
+	// mu serializes the updates and guards subscribers
+	mu          sync.Mutex
+	current     atomic.Value
+	subscribers []func(T)
+}
+
+type dynamicConfigState[T any] struct {
+	raw   string
+	value T
+}
+
+// NewDynamicConfig creates a dynamic configuration which is not registered globally,
+// it is used by the components which bind their own watchers, such as reporters.
+func NewDynamicConfig[T any](key string, defaultValue T, parse func(string) (T, error)) *DynamicConfig[T] {
+	c := &DynamicConfig[T]{
+		key:          key,
+		defaultValue: defaultValue,
+		parse:        parse,
+	}
+	c.current.Store(&dynamicConfigState[T]{raw: fmt.Sprint(defaultValue), value: defaultValue})
+	return c
+}
+
+// RegisterDynamicConfig creates a dynamic configuration and registers it globally,
+// so it is notified by the Configuration Discovery Service of every tracer.
+func RegisterDynamicConfig[T any](key string, defaultValue T, parse func(string) (T, error)) (*DynamicConfig[T], error) {
+	if key == "" || parse == nil {
+		return nil, errParameter
+	}
+	c := NewDynamicConfig(key, defaultValue, parse)
+	if err := dynamicConfigs.register(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get returns the current effective value
+func (c *DynamicConfig[T]) Get() T {
+	return c.load().value
+}
+
+// Subscribe registers a function called with the new value after every change
+func (c *DynamicConfig[T]) Subscribe(fn func(T)) {
+	if fn == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscribers = append(c.subscribers, fn)
+}
+
+// Key implements AgentConfigChangeWatcher
+func (c *DynamicConfig[T]) Key() string {
+	return c.key
+}
+
+// Value implements AgentConfigChangeWatcher
+func (c *DynamicConfig[T]) Value() string {
+	return c.load().raw
+}
+
+// Validate implements AgentConfigChangeValidator
+func (c *DynamicConfig[T]) Validate(newValue string) error {
+	_, err := c.parse(newValue)
+	return err
+}
+
+// Notify implements AgentConfigChangeWatcher, the invalid value is ignored
+func (c *DynamicConfig[T]) Notify(eventType AgentConfigEventType, newValue string) {
+	state := &dynamicConfigState[T]{raw: fmt.Sprint(c.defaultValue), value: c.defaultValue}
+	if eventType != DELETED {
+		value, err := c.parse(newValue)
+		if err != nil {
+			return
+		}
+		state = &dynamicConfigState[T]{raw: newValue, value: value}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current.Store(state)
+	for _, fn := range c.subscribers {
+		fn(state.value)
+	}
+}
+
+func (c *DynamicConfig[T]) load() *dynamicConfigState[T] {
+	return c.current.Load().(*dynamicConfigState[T])
+}
+
+// dynamicConfigRegistry holds the globally registered configurations,
+// readers always see an immutable map.
+type dynamicConfigRegistry struct {
+	mu       sync.Mutex
+	watchers atomic.Value
+}
+
+func (r *dynamicConfigRegistry) register(watcher AgentConfigChangeWatcher) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.all()
+	if _, ok := current[watcher.Key()]; ok {
+		return errors.Errorf("dynamic configuration %s is already registered", watcher.Key())
+	}
+	watchers := make(map[string]AgentConfigChangeWatcher, len(current)+1)
+	for k, v := range current {
+		watchers[k] = v
+	}
+	watchers[watcher.Key()] = watcher
+	r.watchers.Store(watchers)
+	return nil
+}
+
+func (r *dynamicConfigRegistry) all() map[string]AgentConfigChangeWatcher {
+	if watchers, ok := r.watchers.Load().(map[string]AgentConfigChangeWatcher); ok {
+		return watchers
+	}
+	return nil
+}
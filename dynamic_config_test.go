@@ -0,0 +1,199 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	commonv3 "skywalking.apache.org/repo/goapi/collect/common/v3"
+)
+
+func TestDynamicConfig_Notify(t *testing.T) {
+	config := NewDynamicConfig("test.int", 10, strconv.Atoi)
+	var notified []int
+	config.Subscribe(func(v int) {
+		notified = append(notified, v)
+	})
+
+	tests := []struct {
+		name      string
+		eventType AgentConfigEventType
+		value     string
+		want      int
+		wantRaw   string
+	}{
+		{name: "modify", eventType: MODIFY, value: "20", want: 20, wantRaw: "20"},
+		{name: "invalid value", eventType: MODIFY, value: "abc", want: 20, wantRaw: "20"},
+		{name: "delete", eventType: DELETED, value: "", want: 10, wantRaw: "10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Notify(tt.eventType, tt.value)
+			if config.Get() != tt.want {
+				t.Errorf("error validate current value, current is: %d, excepted is: %d", config.Get(), tt.want)
+			}
+			if config.Value() != tt.wantRaw {
+				t.Errorf("error validate raw value, current is: %s, excepted is: %s", config.Value(), tt.wantRaw)
+			}
+		})
+	}
+	if fmt.Sprint(notified) != "[20 10]" {
+		t.Errorf("error validate notified values, current is: %v", notified)
+	}
+}
+
+func TestRegisterDynamicConfig(t *testing.T) {
+	defer resetDynamicConfigs()
+
+	paths, err := RegisterDynamicConfig("test.paths", []string{"/health"}, func(value string) ([]string, error) {
+		return strings.Split(value, ","), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = RegisterDynamicConfig("test.paths", "", func(value string) (string, error) {
+		return value, nil
+	}); err == nil {
+		t.Error("register the same key twice should be failed")
+	}
+	if _, err = RegisterDynamicConfig[string]("", "", nil); err == nil {
+		t.Error("register without key should be failed")
+	}
+
+	// the registered configurations are notified without binding
+	NewConfigDiscoveryService().HandleCommand(&commonv3.Command{Args: []*commonv3.KeyStringValuePair{
+		{Key: "UUID", Value: "uuid1"},
+		{Key: "test.paths", Value: "/health,/ready"},
+	}})
+	if fmt.Sprint(paths.Get()) != "[/health /ready]" {
+		t.Errorf("error validate registered value, current is: %v", paths.Get())
+	}
+}
+
+func TestConfigDiscoveryService_RejectInvalidValue(t *testing.T) {
+	log := &recordLog{}
+	configDiscoveryService := NewConfigDiscoveryService(WithConfigDiscoveryLogger(log))
+	tracer, err := NewTracer("service", WithSampler(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDiscoveryService.BindWatchers(tracer.cdsWatchers)
+	configDiscoveryService.HandleCommand(&commonv3.Command{Args: []*commonv3.KeyStringValuePair{
+		{Key: "UUID", Value: "uuid1"},
+		{Key: "agent.sample_rate", Value: "half"},
+		{Key: correlationElementMaxNumberKey, Value: "-1"},
+	}})
+
+	if len(log.errors) != 2 {
+		t.Fatalf("error validate rejected configurations, current is: %v", log.errors)
+	}
+	for _, key := range []string{"agent.sample_rate", correlationElementMaxNumberKey} {
+		found := false
+		for _, e := range log.errors {
+			found = found || strings.Contains(e, key)
+		}
+		if !found {
+			t.Errorf("rejected configuration %s is not logged", key)
+		}
+	}
+	if tracer.Config()["agent.sample_rate"] != fmt.Sprintf("%f", 0.5) {
+		t.Errorf("invalid sample rate should be ignored, current is: %s", tracer.Config()["agent.sample_rate"])
+	}
+}
+
+func TestTracer_Config(t *testing.T) {
+	defer resetDynamicConfigs()
+
+	if _, err := RegisterDynamicConfig("test.user", "a", func(value string) (string, error) {
+		return value, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reporter := &mockRegisterReporter{}
+	tracer, err := NewTracer("service", WithReporter(reporter), WithCorrelation(2, 10), WithSampler(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	configDiscoveryService := NewConfigDiscoveryService()
+	configDiscoveryService.BindWatchers(tracer.cdsWatchers)
+
+	want := map[string]string{
+		"agent.sample_rate":            fmt.Sprintf("%f", 1.0),
+		correlationElementMaxNumberKey: "2",
+		correlationValueMaxLengthKey:   "10",
+		"test.user":                    "a",
+	}
+	if fmt.Sprint(tracer.Config()) != fmt.Sprint(want) {
+		t.Errorf("error validate config, current is: %v, excepted is: %v", tracer.Config(), want)
+	}
+
+	configDiscoveryService.HandleCommand(&commonv3.Command{Args: []*commonv3.KeyStringValuePair{
+		{Key: "UUID", Value: "uuid1"},
+		{Key: "agent.sample_rate", Value: "1"},
+		{Key: correlationElementMaxNumberKey, Value: "1"},
+		{Key: correlationValueMaxLengthKey, Value: "10"},
+		{Key: "test.user", Value: "b"},
+	}})
+	want[correlationElementMaxNumberKey] = "1"
+	want["test.user"] = "b"
+	if fmt.Sprint(tracer.Config()) != fmt.Sprint(want) {
+		t.Errorf("error validate config, current is: %v, excepted is: %v", tracer.Config(), want)
+	}
+
+	// the correlation limit is applied on the next put
+	span, ctx, err := tracer.CreateLocalSpan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !PutCorrelation(ctx, "key1", "value") {
+		t.Error("put the first correlation key should be success")
+	}
+	if PutCorrelation(ctx, "key2", "value") {
+		t.Error("put the second correlation key should be failed after the limit changed")
+	}
+	span.End()
+	reporter.wait()
+}
+
+func resetDynamicConfigs() {
+	dynamicConfigs = &dynamicConfigRegistry{}
+}
+
+// recordLog records the error logs, test only
+type recordLog struct {
+	errors []string
+}
+
+func (r *recordLog) Info(args ...interface{}) {}
+
+func (r *recordLog) Infof(format string, args ...interface{}) {}
+
+func (r *recordLog) Warn(args ...interface{}) {}
+
+func (r *recordLog) Warnf(format string, args ...interface{}) {}
+
+func (r *recordLog) Error(args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func (r *recordLog) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
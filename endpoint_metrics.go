@@ -0,0 +1,223 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	InstanceEndpointCalls   = "instance_endpoint_calls_delta"
+	InstanceEndpointErrors  = "instance_endpoint_errors_delta"
+	InstanceEndpointLatency = "instance_endpoint_latency"
+	InstancePeerCalls       = "instance_peer_calls_delta"
+	InstancePeerErrors      = "instance_peer_errors_delta"
+	InstancePeerLatency     = "instance_peer_latency"
+
+	serviceLabel  = "service"
+	endpointLabel = "endpoint"
+	peerLabel     = "peer"
+	// the operation names or peers beyond the limit are aggregated into endpointMetricsOverflow
+	maxEndpointMetricsKeys  = 1000
+	endpointMetricsOverflow = "_other"
+)
+
+var (
+	// DefaultEndpointLatencyBuckets are the lower boundaries(MS) of the latency histogram
+	DefaultEndpointLatencyBuckets = []float64{0, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+)
+
+// WithEndpointMetrics enables the endpoint RED metrics, the request rate, error rate and latency
+// of entry spans by operation name and exit spans by peer. They are computed from all the spans
+// regardless of the sampling, and reported by the meter collection of the reporter on every interval.
+// DefaultEndpointLatencyBuckets are used when no bucket is given.
+func WithEndpointMetrics(buckets ...float64) TracerOption {
+	return func(t *Tracer) {
+		if len(buckets) == 0 {
+			buckets = DefaultEndpointLatencyBuckets
+		}
+		sorted := append([]float64(nil), buckets...)
+		sort.Float64s(sorted)
+		t.endpointMetrics = newEndpointMetrics(sorted)
+	}
+}
+
+// endpointMetrics aggregates the spans of a tracer since it starts, each metric collector reports
+// the deltas since its last collection
+type endpointMetrics struct {
+	mu      sync.Mutex
+	service string
+	buckets []float64
+	entries map[string]*redStats
+	exits   map[string]*redStats
+}
+
+// redStats is the rate, errors and duration of an endpoint or peer
+type redStats struct {
+	calls  int64
+	errors int64
+	// the summed latency(MS)
+	sum    float64
+	counts []int64
+}
+
+func newEndpointMetrics(buckets []float64) *endpointMetrics {
+	return &endpointMetrics{
+		buckets: buckets,
+		entries: make(map[string]*redStats),
+		exits:   make(map[string]*redStats),
+	}
+}
+
+func (e *endpointMetrics) record(spanType SpanType, operationName, peer string, duration time.Duration, isError bool) {
+	var stats map[string]*redStats
+	var key string
+	switch spanType {
+	case SpanTypeEntry:
+		stats, key = e.entries, operationName
+	case SpanTypeExit:
+		stats, key = e.exits, peer
+	default:
+		return
+	}
+	latency := float64(duration) / float64(time.Millisecond)
+	i := sort.Search(len(e.buckets), func(i int) bool {
+		return e.buckets[i] > latency
+	}) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s, ok := stats[key]
+	if !ok {
+		if len(stats) >= maxEndpointMetricsKeys {
+			key = endpointMetricsOverflow
+			s = stats[key]
+		}
+		if s == nil {
+			s = &redStats{counts: make([]int64, len(e.buckets))}
+			stats[key] = s
+		}
+	}
+	s.calls++
+	s.sum += latency
+	if isError {
+		s.errors++
+	}
+	s.counts[i]++
+}
+
+// endpointSnapshot is the cumulative stats at a collection
+type endpointSnapshot struct {
+	entries map[string]redStats
+	exits   map[string]redStats
+}
+
+// collectMeters returns the meters of the endpoints and peers called since the last snapshot
+func (e *endpointMetrics) collectMeters(last sourceSnapshot) ([]MeterValue, sourceSnapshot) {
+	e.mu.Lock()
+	current := &endpointSnapshot{entries: copyRedStats(e.entries), exits: copyRedStats(e.exits)}
+	e.mu.Unlock()
+
+	previous, _ := last.(*endpointSnapshot)
+	if previous == nil {
+		previous = &endpointSnapshot{}
+	}
+	values := make([]MeterValue, 0, 3*(len(current.entries)+len(current.exits)))
+	values = e.appendMeters(values, current.entries, previous.entries, endpointLabel, InstanceEndpointCalls, InstanceEndpointErrors, InstanceEndpointLatency)
+	values = e.appendMeters(values, current.exits, previous.exits, peerLabel, InstancePeerCalls, InstancePeerErrors, InstancePeerLatency)
+	return values, current
+}
+
+func copyRedStats(stats map[string]*redStats) map[string]redStats {
+	result := make(map[string]redStats, len(stats))
+	for key, s := range stats {
+		result[key] = redStats{calls: s.calls, errors: s.errors, sum: s.sum, counts: append([]int64(nil), s.counts...)}
+	}
+	return result
+}
+
+func (e *endpointMetrics) appendMeters(values []MeterValue, stats, previous map[string]redStats, label, calls, errors, latency string) []MeterValue {
+	keys := make([]string, 0, len(stats))
+	for key, s := range stats {
+		// skip the endpoints not called in the interval
+		if s.calls != previous[key].calls {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s, p := stats[key], previous[key]
+		labels := []MeterLabel{{Name: serviceLabel, Value: e.service}, {Name: label, Value: key}}
+		buckets := make([]MeterBucket, len(e.buckets))
+		for i, b := range e.buckets {
+			buckets[i] = MeterBucket{Bucket: b, Count: s.counts[i]}
+			if i < len(p.counts) {
+				buckets[i].Count -= p.counts[i]
+			}
+		}
+		values = append(values,
+			MeterValue{Type: MeterTypeGauge, Name: calls, Labels: labels, Value: float64(s.calls - p.calls), Delta: true},
+			MeterValue{Type: MeterTypeGauge, Name: errors, Labels: labels, Value: float64(s.errors - p.errors), Delta: true},
+			MeterValue{Type: MeterTypeHistogram, Name: latency, Labels: labels, Value: s.sum - p.sum, Buckets: buckets, Delta: true})
+	}
+	return values
+}
+
+// measuredSpan keeps what the endpoint metrics need of a span dropped by the sampling
+type measuredSpan struct {
+	metrics       *endpointMetrics
+	spanType      SpanType
+	operationName string
+	peer          string
+	start         time.Time
+	isError       bool
+	ended         int32
+}
+
+func (m *measuredSpan) end() {
+	if atomic.CompareAndSwapInt32(&m.ended, 0, 1) {
+		m.metrics.record(m.spanType, m.operationName, m.peer, time.Since(m.start), m.isError)
+	}
+}
+
+// newMeasuredNoopSpan returns the noop span measured by the endpoint metrics for entry and exit spans,
+// and the plain noop span otherwise.
+func (t *Tracer) newMeasuredNoopSpan(spanType SpanType, operationName string) *NoopSpan {
+	if t.endpointMetrics == nil || spanType == SpanTypeLocal {
+		return &NoopSpan{}
+	}
+	return &NoopSpan{measured: &measuredSpan{
+		metrics:       t.endpointMetrics,
+		spanType:      spanType,
+		operationName: operationName,
+		start:         time.Now(),
+	}}
+}
+
+// recordEndpointMetrics records the ended span if the endpoint metrics are enabled
+func (ds *defaultSpan) recordEndpointMetrics() {
+	if ds.tracer == nil || ds.tracer.endpointMetrics == nil {
+		return
+	}
+	ds.tracer.endpointMetrics.record(ds.SpanType, ds.OperationName, ds.Peer, ds.EndTime.Sub(ds.StartTime), ds.IsError)
+}
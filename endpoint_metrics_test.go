@@ -0,0 +1,201 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type discardReporter struct{}
+
+func (discardReporter) Boot(string, string, []AgentConfigChangeWatcher) {}
+func (discardReporter) Send([]ReportedSpan)                             {}
+func (discardReporter) SendLog(ReportedLogData)                         {}
+func (discardReporter) Close()                                          {}
+
+func endpointMeters(values []MeterValue) map[string]MeterValue {
+	result := make(map[string]MeterValue)
+	for _, v := range values {
+		key := v.Name
+		for _, l := range v.Labels {
+			key += "," + l.Name + "=" + l.Value
+		}
+		result[key] = v
+	}
+	return result
+}
+
+func TestEndpointMetrics(t *testing.T) {
+	for _, sample := range []float64{0, 1} {
+		resetMeters()
+		tracer, err := NewTracer("service", WithReporter(discardReporter{}), WithSampler(sample), WithEndpointMetrics(0, 100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := meters.snapshot()
+		for i := 0; i < 3; i++ {
+			entry, ctx, err := tracer.CreateEntrySpan(context.Background(), "/api", func(string) (string, error) { return "", nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+			local, localCtx, err := tracer.CreateLocalSpan(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exit, err := tracer.CreateExitSpan(localCtx, "GET /users", "users:8080", func(string, string) error { return nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i == 0 {
+				exit.Error(time.Now(), "error")
+				entry.Error(time.Now(), "error")
+			}
+			exit.End()
+			local.End()
+			entry.End()
+		}
+
+		collected, snapshots := meters.collect(start)
+		values := endpointMeters(collected)
+		if len(values) != 6 {
+			t.Fatalf("sample %v: the expected 6 meters, current are %+v", sample, values)
+		}
+		for _, name := range []string{InstanceEndpointCalls + ",service=service,endpoint=/api", InstancePeerCalls + ",service=service,peer=users:8080"} {
+			if values[name].Value != 3 {
+				t.Errorf("sample %v: the expected %s is 3, current is %v", sample, name, values[name].Value)
+			}
+		}
+		for _, name := range []string{InstanceEndpointErrors + ",service=service,endpoint=/api", InstancePeerErrors + ",service=service,peer=users:8080"} {
+			if values[name].Value != 1 {
+				t.Errorf("sample %v: the expected %s is 1, current is %v", sample, name, values[name].Value)
+			}
+		}
+		latency := values[InstanceEndpointLatency+",service=service,endpoint=/api"]
+		if len(latency.Buckets) != 2 || latency.Buckets[0].Count != 3 || latency.Buckets[1].Bucket != 100 {
+			t.Errorf("sample %v: unexpected latency %+v", sample, latency)
+		}
+
+		if values, _ := meters.collect(snapshots); len(values) != 0 {
+			t.Errorf("sample %v: expected a new interval after the collection, current are %+v", sample, values)
+		}
+		// the collection of another collector is not affected
+		if other, _ := meters.collect(start); !reflect.DeepEqual(other, collected) {
+			t.Errorf("sample %v: the expected meters of another collector are %+v, current are %+v", sample, collected, other)
+		}
+	}
+	resetMeters()
+}
+
+func TestEndpointMetrics_Services(t *testing.T) {
+	defer resetMeters()
+	resetMeters()
+	for _, service := range []string{"first", "second"} {
+		tracer, err := NewTracer(service, WithReporter(discardReporter{}), WithEndpointMetrics())
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, _, err := tracer.CreateEntrySpan(context.Background(), "/api", func(string) (string, error) { return "", nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.End()
+	}
+	collected, _ := meters.collect(nil)
+	values := endpointMeters(collected)
+	for _, name := range []string{InstanceEndpointCalls + ",service=first,endpoint=/api", InstanceEndpointCalls + ",service=second,endpoint=/api"} {
+		if values[name].Value != 1 {
+			t.Errorf("the expected %s is 1, current is %v", name, values[name].Value)
+		}
+	}
+}
+
+func TestEndpointMetrics_Prometheus(t *testing.T) {
+	e := newEndpointMetrics([]float64{0, 100})
+	e.service = "service"
+	h := NewPrometheusHandler()
+	var last sourceSnapshot
+	for _, latencies := range [][]time.Duration{{20 * time.Millisecond, 200 * time.Millisecond}, {30 * time.Millisecond}} {
+		for _, latency := range latencies {
+			e.record(SpanTypeEntry, "/api", "", latency, false)
+		}
+		var values []MeterValue
+		values, last = e.collectMeters(last)
+		h.SendMetrics(RunTimeMetric{Meters: values})
+	}
+	values, _ := e.collectMeters(nil)
+	if latency := endpointMeters(values)[InstanceEndpointLatency+",service=service,endpoint=/api"]; latency.Value != 250 {
+		t.Errorf("the expected summed latency is 250, current is %v", latency.Value)
+	}
+
+	// the deltas of the intervals are exposed as cumulative in Prometheus
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	labels := `{service="service",endpoint="/api"}`
+	for _, want := range []string{
+		"# TYPE instance_endpoint_calls counter\ninstance_endpoint_calls" + labels + " 3\n",
+		"instance_endpoint_latency_sum" + labels + " 250\ninstance_endpoint_latency_count" + labels + " 3\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the exposition:\n%s", want, body)
+		}
+	}
+}
+
+func TestEndpointMetrics_Overflow(t *testing.T) {
+	e := newEndpointMetrics(DefaultEndpointLatencyBuckets)
+	for i := 0; i < maxEndpointMetricsKeys+10; i++ {
+		e.record(SpanTypeEntry, time.Duration(i).String(), "", time.Millisecond, false)
+	}
+	e.record(SpanTypeLocal, "local", "", time.Millisecond, false)
+	if len(e.entries) != maxEndpointMetricsKeys+1 || e.entries[endpointMetricsOverflow].calls != 10 {
+		t.Errorf("the expected %d entries with 10 overflow calls, current are %d", maxEndpointMetricsKeys+1, len(e.entries))
+	}
+}
+
+func TestTracer_EndpointMetricsEnv(t *testing.T) {
+	defer resetMeters()
+	os.Setenv(swAgentEndpointMetricsEnable, "true")
+	defer os.Unsetenv(swAgentEndpointMetricsEnable)
+
+	tracer, err := NewTracer("service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tracer.endpointMetrics == nil || len(tracer.endpointMetrics.buckets) != len(DefaultEndpointLatencyBuckets) {
+		t.Error("the expected endpoint metrics are enabled with the default buckets")
+	}
+
+	os.Setenv(swAgentEndpointMetricsEnable, "false")
+	if tracer, err = NewTracer("service", WithEndpointMetrics()); err != nil {
+		t.Fatal(err)
+	}
+	if tracer.endpointMetrics != nil {
+		t.Error("the expected endpoint metrics are disabled by the environment variable")
+	}
+
+	os.Setenv(swAgentEndpointMetricsEnable, "on")
+	if _, err = NewTracer("service"); err == nil {
+		t.Error("invalid endpoint metrics switch should be failed")
+	}
+}
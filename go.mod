@@ -1,6 +1,6 @@
 module github.com/SkyAPM/go2sky
 
-go 1.16
+go 1.18
 
 require (
 	github.com/agiledragon/gomonkey/v2 v2.2.0
@@ -9,5 +9,21 @@ require (
 	github.com/pkg/errors v0.8.1
 	github.com/shirou/gopsutil/v3 v3.22.6
 	google.golang.org/grpc v1.49.0
+	gopkg.in/yaml.v3 v3.0.1
 	skywalking.apache.org/repo/goapi v0.0.0-20221019074310-53ebda305187
 )
+
+require (
+	github.com/go-ole/go-ole v1.2.6 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/lufia/plan9stats v0.0.0-20211012122336-39d0f177ccd0 // indirect
+	github.com/power-devops/perfstat v0.0.0-20210106213030-5aafc221ea8c // indirect
+	github.com/tklauser/go-sysconf v0.3.10 // indirect
+	github.com/tklauser/numcpus v0.4.0 // indirect
+	github.com/yusufpapurcu/wmi v1.2.2 // indirect
+	golang.org/x/net v0.0.0-20220722155237-a158d28d115b // indirect
+	golang.org/x/sys v0.0.0-20220811171246-fbc7d0a398ab // indirect
+	golang.org/x/text v0.3.7 // indirect
+	google.golang.org/genproto v0.0.0-20210624195500-8bfb893ecb84 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+)
@@ -0,0 +1,66 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package testutil provides the helpers shared by the tests of the plugins and bridges.
+package testutil
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+)
+
+// Reporter records the reported segments
+type Reporter struct {
+	Segments chan []go2sky.ReportedSpan
+}
+
+// NewReporter creates the reporter buffering size segments
+func NewReporter(size int) *Reporter {
+	return &Reporter{Segments: make(chan []go2sky.ReportedSpan, size)}
+}
+
+func (r *Reporter) Boot(string, string, []go2sky.AgentConfigChangeWatcher) {}
+func (r *Reporter) Send(spans []go2sky.ReportedSpan)                       { r.Segments <- spans }
+func (r *Reporter) SendLog(go2sky.ReportedLogData)                         {}
+func (r *Reporter) Close()                                                 {}
+
+// Segment waits for the next segment and returns its spans sorted by the span ID
+func (r *Reporter) Segment(t testing.TB) []go2sky.ReportedSpan {
+	t.Helper()
+	select {
+	case segment := <-r.Segments:
+		sort.Slice(segment, func(i, j int) bool {
+			return segment[i].Context().SpanID < segment[j].Context().SpanID
+		})
+		return segment
+	case <-time.After(5 * time.Second):
+		t.Fatal("the segment is not reported")
+	}
+	return nil
+}
+
+// TagValue returns the value of the tag of the span, or empty if the span has no such tag
+func TagValue(span go2sky.ReportedSpan, tag go2sky.Tag) string {
+	for _, t := range span.Tags() {
+		if t.Key == string(tag) {
+			return t.Value
+		}
+	}
+	return ""
+}
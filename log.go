@@ -18,7 +18,15 @@ package go2sky
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SkyAPM/go2sky/internal/tool"
+	"gopkg.in/yaml.v3"
 )
 
 type LogLevel string
@@ -30,16 +38,60 @@ const (
 	LogLevelError LogLevel = "error"
 )
 
+// ParseLogLevel parses the level case-insensitively
+func ParseLogLevel(level string) (LogLevel, error) {
+	switch l := LogLevel(strings.ToLower(level)); l {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+		return l, nil
+	}
+	return "", fmt.Errorf("unknown log level %s", level)
+}
+
 type ReportedLogData interface {
 	Context() context.Context
 	ErrorLevel() LogLevel
 	Data() string
 }
 
+// LogTag is a key value reported in the tags of the log
+type LogTag struct {
+	Key   string
+	Value string
+}
+
+// TaggedLogData is the ReportedLogData carrying tags, they are reported besides the level
+type TaggedLogData interface {
+	ReportedLogData
+	Tags() []LogTag
+}
+
+// LogBodyType is the type of the reported log body
+type LogBodyType string
+
+const (
+	LogBodyTypeText LogBodyType = "text"
+	LogBodyTypeJSON LogBodyType = "json"
+	LogBodyTypeYAML LogBodyType = "yaml"
+)
+
+// StructuredLogData is the TaggedLogData carrying the body type, the endpoint and the timestamp.
+// The endpoint of the trace context is reported if the endpoint is empty,
+// and the sending time is reported if the timestamp is zero.
+type StructuredLogData interface {
+	TaggedLogData
+	BodyType() LogBodyType
+	Endpoint() string
+	Timestamp() time.Time
+}
+
 type DefaultLogData struct {
 	LogCtx      context.Context
 	LogErrLevel LogLevel
 	LogContent  string
+	LogTags     []LogTag
+	LogBodyType LogBodyType
+	LogEndpoint string
+	LogTime     time.Time
 }
 
 func (l *DefaultLogData) Context() context.Context {
@@ -54,11 +106,42 @@ func (l *DefaultLogData) Data() string {
 	return l.LogContent
 }
 
+func (l *DefaultLogData) Tags() []LogTag {
+	return l.LogTags
+}
+
+func (l *DefaultLogData) BodyType() LogBodyType {
+	if l.LogBodyType == "" {
+		return LogBodyTypeText
+	}
+	return l.LogBodyType
+}
+
+func (l *DefaultLogData) Endpoint() string {
+	return l.LogEndpoint
+}
+
+func (l *DefaultLogData) Timestamp() time.Time {
+	return l.LogTime
+}
+
 type Logger struct {
-	mReporter Reporter
+	mReporter   Reporter
+	errorToSpan bool
 }
 
-func NewLogger(reporter Reporter) (*Logger, error) {
+// LoggerOption allows for functional options to adjust behaviour of a Logger to be created by NewLogger
+type LoggerOption func(l *Logger)
+
+// WithErrorLogToSpan marks the active span of the context as error when an error log is written, the level is case-insensitive,
+// the message and the timestamp of the log are logged on the span to find the log.
+func WithErrorLogToSpan() LoggerOption {
+	return func(l *Logger) {
+		l.errorToSpan = true
+	}
+}
+
+func NewLogger(reporter Reporter, opts ...LoggerOption) (*Logger, error) {
 
 	if reporter == nil {
 		return nil, errors.New("invalid reporter.")
@@ -66,16 +149,55 @@ func NewLogger(reporter Reporter) (*Logger, error) {
 
 	l := new(Logger)
 	l.mReporter = reporter
+	for _, o := range opts {
+		o(l)
+	}
 
 	return l, nil
 }
 
 func (l *Logger) WriteLogWithContext(ctx context.Context, level LogLevel, data string) {
+	l.WriteLogWithFields(ctx, level, data)
+}
+
+// WriteLogWithFields writes the text log, the fields are reported as the tags
+func (l *Logger) WriteLogWithFields(ctx context.Context, level LogLevel, data string, fields ...LogTag) {
+	l.write(ctx, level, LogBodyTypeText, data, fields)
+}
 
-	xLogData := DefaultLogData{}
-	xLogData.LogCtx = ctx
-	xLogData.LogErrLevel = level
-	xLogData.LogContent = data
+// WriteJSONLogWithContext writes v marshaled as the JSON log body, the fields are reported as the tags
+func (l *Logger) WriteJSONLogWithContext(ctx context.Context, level LogLevel, v interface{}, fields ...LogTag) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	l.write(ctx, level, LogBodyTypeJSON, string(data), fields)
+	return nil
+}
 
-	l.mReporter.SendLog(&xLogData)
+// WriteYAMLLogWithContext writes v marshaled as the YAML log body, the fields are reported as the tags
+func (l *Logger) WriteYAMLLogWithContext(ctx context.Context, level LogLevel, v interface{}, fields ...LogTag) error {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	l.write(ctx, level, LogBodyTypeYAML, string(data), fields)
+	return nil
+}
+
+func (l *Logger) write(ctx context.Context, level LogLevel, bodyType LogBodyType, data string, fields []LogTag) {
+	now := time.Now()
+	if l.errorToSpan && strings.EqualFold(string(level), string(LogLevelError)) && ctx != nil {
+		if span := ActiveSpan(ctx); span != nil {
+			span.Error(now, "event", "error", "message", data, "log.timestamp", strconv.FormatInt(tool.Millisecond(now), 10))
+		}
+	}
+	l.mReporter.SendLog(&DefaultLogData{
+		LogCtx:      ctx,
+		LogErrLevel: level,
+		LogContent:  data,
+		LogTags:     fields,
+		LogBodyType: bodyType,
+		LogTime:     now,
+	})
 }
@@ -18,11 +18,40 @@ package log
 
 import (
 	"context"
-	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
 
 	"github.com/SkyAPM/go2sky"
 )
 
+// The keys of the SkyWalking context in the structured logs
+const (
+	FieldServiceName         = "service"
+	FieldServiceInstanceName = "service_instance"
+	FieldTraceID             = "trace_id"
+	FieldTraceSegmentID      = "segment_id"
+	FieldSpanID              = "span_id"
+)
+
+// DefaultFormat is the format of SkyWalkingContext.String, the placeholders are the field keys in braces
+const DefaultFormat = "[{service},{service_instance},{trace_id},{segment_id},{span_id}]"
+
+var format atomic.Value
+
+func init() {
+	format.Store(DefaultFormat)
+}
+
+// SetFormat changes the format of SkyWalkingContext.String, such as "TID:{trace_id}",
+// the empty format restores DefaultFormat
+func SetFormat(layout string) {
+	if layout == "" {
+		layout = DefaultFormat
+	}
+	format.Store(layout)
+}
+
 type SkyWalkingContext struct {
 	ServiceName         string
 	ServiceInstanceName string
@@ -31,8 +60,20 @@ type SkyWalkingContext struct {
 	SpanID              int32
 }
 
-// FromContext from context for logging
+// skyWalkingContextKey is the key of the SkyWalkingContext set by NewContext
+type skyWalkingContextKey struct{}
+
+// NewContext returns the context carrying the SkyWalking context, such as the one decoded from a log record
+// without the context of the span, it is returned by FromContext instead of the context of the active span.
+func NewContext(ctx context.Context, sw *SkyWalkingContext) context.Context {
+	return context.WithValue(ctx, skyWalkingContextKey{}, sw)
+}
+
+// FromContext from context for logging, the SkyWalking context set by NewContext is returned if any
 func FromContext(ctx context.Context) *SkyWalkingContext {
+	if sw, ok := ctx.Value(skyWalkingContextKey{}).(*SkyWalkingContext); ok {
+		return sw
+	}
 	return &SkyWalkingContext{
 		ServiceName:         go2sky.ServiceName(ctx),
 		ServiceInstanceName: go2sky.ServiceInstanceName(ctx),
@@ -42,7 +83,40 @@ func FromContext(ctx context.Context) *SkyWalkingContext {
 	}
 }
 
+// String formats the context by the format set by SetFormat
 func (context *SkyWalkingContext) String() string {
-	return fmt.Sprintf("[%s,%s,%s,%s,%d]", context.ServiceName, context.ServiceInstanceName,
-		context.TraceID, context.TraceSegmentID, context.SpanID)
+	return context.Format(format.Load().(string))
+}
+
+// Format replaces the placeholders of the field keys in braces by the context, such as "{trace_id}"
+func (context *SkyWalkingContext) Format(layout string) string {
+	return strings.NewReplacer(
+		"{"+FieldServiceName+"}", context.ServiceName,
+		"{"+FieldServiceInstanceName+"}", context.ServiceInstanceName,
+		"{"+FieldTraceID+"}", context.TraceID,
+		"{"+FieldTraceSegmentID+"}", context.TraceSegmentID,
+		"{"+FieldSpanID+"}", strconv.FormatInt(int64(context.SpanID), 10),
+	).Replace(layout)
+}
+
+// IsValid returns whether the context is in a sampled trace
+func (context *SkyWalkingContext) IsValid() bool {
+	return context.TraceID != go2sky.EmptyTraceID
+}
+
+// Forward sends the record of a structured logger to the reporter, the fields are reported as the tags.
+// It does nothing if the reporter is nil.
+func Forward(reporter go2sky.Reporter, ctx context.Context, level go2sky.LogLevel, msg string, tags []go2sky.LogTag) {
+	if reporter == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	reporter.SendLog(&go2sky.DefaultLogData{
+		LogCtx:      ctx,
+		LogErrLevel: level,
+		LogContent:  msg,
+		LogTags:     tags,
+	})
 }
@@ -28,6 +28,13 @@ func TestFromContext(t *testing.T) {
 	verifyContext(t, skyWalkingContext)
 }
 
+func TestNewContext(t *testing.T) {
+	sw := &SkyWalkingContext{ServiceName: "service", TraceID: "t1", SpanID: 2}
+	if ctx := FromContext(NewContext(context.Background(), sw)); ctx != sw {
+		t.Errorf("the expected context %v, current is %v", sw, ctx)
+	}
+}
+
 func verifyContext(t *testing.T, ctx *SkyWalkingContext) {
 	if ctx == nil {
 		t.Error("nil context")
@@ -45,3 +52,22 @@ func verifyContext(t *testing.T, ctx *SkyWalkingContext) {
 		t.Errorf("wrong context string, excepted:%s, actual:%s", exceptString, contextString)
 	}
 }
+
+func TestSetFormat(t *testing.T) {
+	defer SetFormat("")
+	ctx := &SkyWalkingContext{ServiceName: "service", ServiceInstanceName: "instance", TraceID: "t1", TraceSegmentID: "s1", SpanID: 2}
+	tests := []struct {
+		format string
+		expect string
+	}{
+		{format: "TID:{trace_id}", expect: "TID:t1"},
+		{format: "{service}/{service_instance} {segment_id}-{span_id} {unknown}", expect: "service/instance s1-2 {unknown}"},
+		{format: "", expect: "[service,instance,t1,s1,2]"},
+	}
+	for _, tt := range tests {
+		SetFormat(tt.format)
+		if s := ctx.String(); s != tt.expect {
+			t.Errorf("format %q: expected %s, actual %s", tt.format, tt.expect, s)
+		}
+	}
+}
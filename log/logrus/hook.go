@@ -0,0 +1,82 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package logrus
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/log"
+	"github.com/sirupsen/logrus"
+)
+
+// Hook adds the SkyWalking context of the entry context, set by WithContext, as fields,
+// and forwards the entries to the reporter with the fields as tags if it is not nil.
+type Hook struct {
+	reporter go2sky.Reporter
+}
+
+// NewHook creates the hook, the reporter could be nil to add the fields only
+func NewHook(reporter go2sky.Reporter) *Hook {
+	return &Hook{reporter: reporter}
+}
+
+// Levels implements logrus.Hook
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	if h.reporter != nil {
+		keys := make([]string, 0, len(entry.Data))
+		for key := range entry.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		tags := make([]go2sky.LogTag, 0, len(keys))
+		for _, key := range keys {
+			tags = append(tags, go2sky.LogTag{Key: key, Value: fmt.Sprint(entry.Data[key])})
+		}
+		log.Forward(h.reporter, entry.Context, logLevel(entry.Level), entry.Message, tags)
+	}
+	if entry.Context == nil {
+		return nil
+	}
+	if sw := log.FromContext(entry.Context); sw.IsValid() {
+		entry.Data[log.FieldServiceName] = sw.ServiceName
+		entry.Data[log.FieldServiceInstanceName] = sw.ServiceInstanceName
+		entry.Data[log.FieldTraceID] = sw.TraceID
+		entry.Data[log.FieldTraceSegmentID] = sw.TraceSegmentID
+		entry.Data[log.FieldSpanID] = sw.SpanID
+	}
+	return nil
+}
+
+func logLevel(level logrus.Level) go2sky.LogLevel {
+	switch level {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		return go2sky.LogLevelDebug
+	case logrus.InfoLevel:
+		return go2sky.LogLevelInfo
+	case logrus.WarnLevel:
+		return go2sky.LogLevelWarn
+	default:
+		return go2sky.LogLevelError
+	}
+}
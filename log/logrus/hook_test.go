@@ -0,0 +1,85 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package logrus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/log"
+	"github.com/sirupsen/logrus"
+)
+
+type recordReporter struct {
+	logs []go2sky.ReportedLogData
+}
+
+func (r *recordReporter) Boot(string, string, []go2sky.AgentConfigChangeWatcher) {}
+func (r *recordReporter) Send([]go2sky.ReportedSpan)                             {}
+func (r *recordReporter) SendLog(data go2sky.ReportedLogData)                    { r.logs = append(r.logs, data) }
+func (r *recordReporter) Close()                                                 {}
+
+func TestHook(t *testing.T) {
+	reporter := &recordReporter{}
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ctx, err := tracer.CreateLocalSpan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.AddHook(NewHook(reporter))
+	logger.WithContext(ctx).WithField("user", "alice").Error("failed")
+
+	record := make(map[string]interface{})
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record[log.FieldTraceID] != go2sky.TraceID(ctx) || record[log.FieldServiceInstanceName] != "instance" || record["user"] != "alice" {
+		t.Errorf("unexpected record %v", record)
+	}
+	if len(reporter.logs) != 1 {
+		t.Fatalf("the expected 1 forwarded log, current is %d", len(reporter.logs))
+	}
+	l := reporter.logs[0].(go2sky.TaggedLogData)
+	if l.Data() != "failed" || l.ErrorLevel() != go2sky.LogLevelError || l.Context() != ctx {
+		t.Errorf("unexpected forwarded log %v", l)
+	}
+	if tags := l.Tags(); len(tags) != 1 || tags[0] != (go2sky.LogTag{Key: "user", Value: "alice"}) {
+		t.Errorf("unexpected forwarded tags %v", tags)
+	}
+}
+
+func TestHook_NoTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.AddHook(NewHook(nil))
+	logger.Info("hello")
+	if bytes.Contains(buf.Bytes(), []byte(log.FieldTraceID)) {
+		t.Errorf("unexpected trace context out of trace %s", buf.String())
+	}
+}
@@ -0,0 +1,121 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/SkyAPM/go2sky"
+)
+
+// NewSlogHandler wraps the handler to add the SkyWalking context of the record context as attributes,
+// and forwards the records to the reporter with the attributes as tags if the reporter is not nil.
+func NewSlogHandler(next slog.Handler, reporter go2sky.Reporter) slog.Handler {
+	return &slogHandler{next: next, reporter: reporter}
+}
+
+type slogHandler struct {
+	next     slog.Handler
+	reporter go2sky.Reporter
+	// the attributes and groups added by WithAttrs and WithGroup, as tags
+	tags   []go2sky.LogTag
+	groups []string
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if sw := FromContext(ctx); sw.IsValid() {
+			r = r.Clone()
+			r.AddAttrs(
+				slog.String(FieldServiceName, sw.ServiceName),
+				slog.String(FieldServiceInstanceName, sw.ServiceInstanceName),
+				slog.String(FieldTraceID, sw.TraceID),
+				slog.String(FieldTraceSegmentID, sw.TraceSegmentID),
+				slog.Int(FieldSpanID, int(sw.SpanID)),
+			)
+		}
+	}
+	if h.reporter != nil {
+		tags := append([]go2sky.LogTag(nil), h.tags...)
+		r.Attrs(func(a slog.Attr) bool {
+			tags = appendSlogTags(tags, h.groups, a)
+			return true
+		})
+		Forward(h.reporter, ctx, slogLevel(r.Level), r.Message, tags)
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	tags := append([]go2sky.LogTag(nil), h.tags...)
+	for _, a := range attrs {
+		tags = appendSlogTags(tags, h.groups, a)
+	}
+	return &slogHandler{next: h.next.WithAttrs(attrs), reporter: h.reporter, tags: tags, groups: h.groups}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := append(append([]string(nil), h.groups...), name)
+	return &slogHandler{next: h.next.WithGroup(name), reporter: h.reporter, tags: h.tags, groups: groups}
+}
+
+// appendSlogTags flattens the attribute, the keys in groups are joined by dots
+func appendSlogTags(tags []go2sky.LogTag, groups []string, a slog.Attr) []go2sky.LogTag {
+	value := a.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			groups = append(append([]string(nil), groups...), a.Key)
+		}
+		for _, child := range value.Group() {
+			tags = appendSlogTags(tags, groups, child)
+		}
+		return tags
+	}
+	if a.Key == "" {
+		return tags
+	}
+	key := a.Key
+	if len(groups) > 0 {
+		key = strings.Join(groups, ".") + "." + key
+	}
+	return append(tags, go2sky.LogTag{Key: key, Value: value.String()})
+}
+
+// slogLevel compares the levels numerically, so the custom levels are mapped to the nearest lower level
+func slogLevel(level slog.Level) go2sky.LogLevel {
+	switch {
+	case level >= slog.LevelError:
+		return go2sky.LogLevelError
+	case level >= slog.LevelWarn:
+		return go2sky.LogLevelWarn
+	case level >= slog.LevelInfo:
+		return go2sky.LogLevelInfo
+	default:
+		return go2sky.LogLevelDebug
+	}
+}
@@ -0,0 +1,110 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build go1.21
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/SkyAPM/go2sky"
+)
+
+type recordReporter struct {
+	logs []go2sky.ReportedLogData
+}
+
+func (r *recordReporter) Boot(string, string, []go2sky.AgentConfigChangeWatcher) {}
+func (r *recordReporter) Send([]go2sky.ReportedSpan)                             {}
+func (r *recordReporter) SendLog(data go2sky.ReportedLogData)                    { r.logs = append(r.logs, data) }
+func (r *recordReporter) Close()                                                 {}
+
+func newTraceContext(t *testing.T, reporter go2sky.Reporter) context.Context {
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ctx, err := tracer.CreateLocalSpan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
+
+func TestSlogHandler(t *testing.T) {
+	reporter := &recordReporter{}
+	ctx := newTraceContext(t, reporter)
+	var buf bytes.Buffer
+	logger := slog.New(NewSlogHandler(slog.NewJSONHandler(&buf, nil), reporter))
+
+	logger.With("user", "alice").WithGroup("req").ErrorContext(ctx, "failed", "status", 500)
+
+	record := make(map[string]interface{})
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := record["req"].(map[string]interface{})
+	if req[FieldTraceID] != go2sky.TraceID(ctx) || req[FieldServiceName] != "service" || req[FieldServiceInstanceName] != "instance" {
+		t.Errorf("unexpected record %v", record)
+	}
+
+	if len(reporter.logs) != 1 {
+		t.Fatalf("the expected 1 forwarded log, current is %d", len(reporter.logs))
+	}
+	log := reporter.logs[0].(go2sky.TaggedLogData)
+	if log.Data() != "failed" || log.ErrorLevel() != go2sky.LogLevelError || log.Context() != ctx {
+		t.Errorf("unexpected forwarded log %v", log)
+	}
+	tags := make(map[string]string)
+	for _, tag := range log.Tags() {
+		tags[tag.Key] = tag.Value
+	}
+	if tags["user"] != "alice" || tags["req.status"] != "500" {
+		t.Errorf("unexpected forwarded tags %v", tags)
+	}
+}
+
+func TestSlogHandler_NoTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewSlogHandler(slog.NewJSONHandler(&buf, nil), nil))
+	logger.InfoContext(context.Background(), "hello")
+	if bytes.Contains(buf.Bytes(), []byte(FieldTraceID)) {
+		t.Errorf("unexpected trace context out of trace %s", buf.String())
+	}
+}
+
+func TestSlogLevel(t *testing.T) {
+	tests := []struct {
+		level  slog.Level
+		expect go2sky.LogLevel
+	}{
+		{level: slog.LevelError + 4, expect: go2sky.LogLevelError},
+		{level: slog.LevelError, expect: go2sky.LogLevelError},
+		{level: slog.LevelWarn + 2, expect: go2sky.LogLevelWarn},
+		{level: slog.LevelInfo, expect: go2sky.LogLevelInfo},
+		{level: slog.LevelDebug - 4, expect: go2sky.LogLevelDebug},
+	}
+	for _, tt := range tests {
+		if level := slogLevel(tt.level); level != tt.expect {
+			t.Errorf("the expected level of %v is %s, current is %s", tt.level, tt.expect, level)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package zap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// TraceContext returns the field adding the SkyWalking context of ctx,
+// nothing is added if ctx is not in a trace.
+func TraceContext(ctx context.Context) zap.Field {
+	return zap.Inline(traceContext{ctx: ctx})
+}
+
+type traceContext struct {
+	ctx context.Context
+}
+
+func (t traceContext) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	if sw := log.FromContext(t.ctx); sw.IsValid() {
+		enc.AddString(log.FieldServiceName, sw.ServiceName)
+		enc.AddString(log.FieldServiceInstanceName, sw.ServiceInstanceName)
+		enc.AddString(log.FieldTraceID, sw.TraceID)
+		enc.AddString(log.FieldTraceSegmentID, sw.TraceSegmentID)
+		enc.AddInt32(log.FieldSpanID, sw.SpanID)
+	}
+	return nil
+}
+
+// NewCore wraps the core to forward the entries to the reporter with the fields as tags,
+// the context of the TraceContext field is used to correlate the logs with the trace.
+func NewCore(core zapcore.Core, reporter go2sky.Reporter) zapcore.Core {
+	return &forwardCore{Core: core, reporter: reporter}
+}
+
+type forwardCore struct {
+	zapcore.Core
+	reporter go2sky.Reporter
+	fields   []zapcore.Field
+}
+
+func (c *forwardCore) With(fields []zapcore.Field) zapcore.Core {
+	return &forwardCore{
+		Core:     c.Core.With(fields),
+		reporter: c.reporter,
+		fields:   append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *forwardCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+func (c *forwardCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if c.reporter != nil {
+		var ctx context.Context
+		enc := zapcore.NewMapObjectEncoder()
+		for _, fs := range [][]zapcore.Field{c.fields, fields} {
+			for _, f := range fs {
+				if t, ok := f.Interface.(traceContext); ok && f.Type == zapcore.InlineMarshalerType {
+					ctx = t.ctx
+					continue
+				}
+				f.AddTo(enc)
+			}
+		}
+		log.Forward(c.reporter, ctx, logLevel(entry.Level), entry.Message, mapTags(enc.Fields))
+	}
+	return c.Core.Write(entry, fields)
+}
+
+func mapTags(fields map[string]interface{}) []go2sky.LogTag {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tags := make([]go2sky.LogTag, 0, len(keys))
+	for _, key := range keys {
+		tags = append(tags, go2sky.LogTag{Key: key, Value: fmt.Sprint(fields[key])})
+	}
+	return tags
+}
+
+func logLevel(level zapcore.Level) go2sky.LogLevel {
+	switch level {
+	case zapcore.DebugLevel:
+		return go2sky.LogLevelDebug
+	case zapcore.InfoLevel:
+		return go2sky.LogLevelInfo
+	case zapcore.WarnLevel:
+		return go2sky.LogLevelWarn
+	default:
+		return go2sky.LogLevelError
+	}
+}
@@ -0,0 +1,22 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+/*
+Package zerolog adds the SkyWalking context to the zerolog events, and forwards them to the SkyWalking reporter.
+The Hook forwards only the message and the level, since a zerolog hook could not read the fields,
+the Writer decodes the written events to forward their fields as the log tags.
+*/
+package zerolog
@@ -0,0 +1,64 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package zerolog
+
+import (
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/log"
+	"github.com/rs/zerolog"
+)
+
+// Hook adds the SkyWalking context of the event context, set by Event.Ctx or Context.Ctx, as fields,
+// and forwards the events to the reporter if it is not nil. The fields of the event could not be
+// read by a hook, so only the message and the level are forwarded, without any log tag.
+// Use the Writer to forward the fields, with the hook created without the reporter.
+type Hook struct {
+	reporter go2sky.Reporter
+}
+
+// NewHook creates the hook, the reporter could be nil to add the fields only
+func NewHook(reporter go2sky.Reporter) Hook {
+	return Hook{reporter: reporter}
+}
+
+// Run implements zerolog.Hook
+func (h Hook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	ctx := e.GetCtx()
+	if sw := log.FromContext(ctx); sw.IsValid() {
+		e.Str(log.FieldServiceName, sw.ServiceName).
+			Str(log.FieldServiceInstanceName, sw.ServiceInstanceName).
+			Str(log.FieldTraceID, sw.TraceID).
+			Str(log.FieldTraceSegmentID, sw.TraceSegmentID).
+			Int32(log.FieldSpanID, sw.SpanID)
+	}
+	if h.reporter != nil && level != zerolog.Disabled {
+		log.Forward(h.reporter, ctx, logLevel(level), msg, nil)
+	}
+}
+
+func logLevel(level zerolog.Level) go2sky.LogLevel {
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return go2sky.LogLevelDebug
+	case zerolog.WarnLevel:
+		return go2sky.LogLevelWarn
+	case zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel:
+		return go2sky.LogLevelError
+	default:
+		return go2sky.LogLevelInfo
+	}
+}
@@ -0,0 +1,119 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package zerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/log"
+	"github.com/rs/zerolog"
+)
+
+type recordReporter struct {
+	logs []go2sky.ReportedLogData
+}
+
+func (r *recordReporter) Boot(string, string, []go2sky.AgentConfigChangeWatcher) {}
+func (r *recordReporter) Send([]go2sky.ReportedSpan)                             {}
+func (r *recordReporter) SendLog(data go2sky.ReportedLogData)                    { r.logs = append(r.logs, data) }
+func (r *recordReporter) Close()                                                 {}
+
+func TestHook(t *testing.T) {
+	reporter := &recordReporter{}
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ctx, err := tracer.CreateLocalSpan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Hook(NewHook(reporter))
+	logger.Warn().Ctx(ctx).Str("user", "alice").Msg("slow")
+
+	record := make(map[string]interface{})
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record[log.FieldTraceID] != go2sky.TraceID(ctx) || record[log.FieldServiceName] != "service" ||
+		record[log.FieldSpanID] != float64(go2sky.SpanID(ctx)) || record["user"] != "alice" {
+		t.Errorf("unexpected record %v", record)
+	}
+	if len(reporter.logs) != 1 {
+		t.Fatalf("the expected 1 forwarded log, current is %d", len(reporter.logs))
+	}
+	if l := reporter.logs[0]; l.Data() != "slow" || l.ErrorLevel() != go2sky.LogLevelWarn || l.Context() != ctx {
+		t.Errorf("unexpected forwarded log %v", l)
+	}
+	if tagged, ok := reporter.logs[0].(go2sky.TaggedLogData); ok && len(tagged.Tags()) != 0 {
+		t.Errorf("unexpected forwarded tags %v", tagged.Tags())
+	}
+}
+
+func TestWriter(t *testing.T) {
+	reporter := &recordReporter{}
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(&recordReporter{}), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ctx, err := tracer.CreateLocalSpan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	logger := zerolog.New(NewWriter(&buf, reporter)).Hook(NewHook(nil)).With().Timestamp().Logger()
+	logger.Warn().Ctx(ctx).Str("user", "alice").Int("cost", 3).Msg("slow")
+	logger.Log().Str("level", "error").Msg("failed")
+
+	if !bytes.Contains(buf.Bytes(), []byte(`"user":"alice"`)) {
+		t.Errorf("the events should be written to the next writer, current is %s", buf.String())
+	}
+	if len(reporter.logs) != 2 {
+		t.Fatalf("the expected 2 forwarded logs, current is %d", len(reporter.logs))
+	}
+	l := reporter.logs[0].(go2sky.TaggedLogData)
+	if l.Data() != "slow" || l.ErrorLevel() != go2sky.LogLevelWarn {
+		t.Errorf("unexpected forwarded log %v", l)
+	}
+	if sw := log.FromContext(l.Context()); sw.TraceID != go2sky.TraceID(ctx) || sw.SpanID != go2sky.SpanID(ctx) || sw.ServiceName != "service" {
+		t.Errorf("unexpected SkyWalking context %v", sw)
+	}
+	expected := []go2sky.LogTag{{Key: "cost", Value: "3"}, {Key: "user", Value: "alice"}}
+	if tags := l.Tags(); len(tags) != len(expected) || tags[0] != expected[0] || tags[1] != expected[1] {
+		t.Errorf("the expected forwarded tags %v, current are %v", expected, tags)
+	}
+	// the level of the events without level is read from the level field
+	if l := reporter.logs[1]; l.Data() != "failed" || l.ErrorLevel() != go2sky.LogLevelError || log.FromContext(l.Context()).IsValid() {
+		t.Errorf("unexpected forwarded log %v", l)
+	}
+}
+
+func TestHook_NoTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Hook(NewHook(nil))
+	logger.Info().Msg("hello")
+	if bytes.Contains(buf.Bytes(), []byte(log.FieldTraceID)) {
+		t.Errorf("unexpected trace context out of trace %s", buf.String())
+	}
+}
@@ -0,0 +1,104 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package zerolog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/log"
+	"github.com/rs/zerolog"
+)
+
+// Writer is a zerolog.LevelWriter writing the events to the next writer, and forwarding them to the reporter
+// with their fields as the log tags. The SkyWalking context added by the Hook is decoded from the fields,
+// so the Hook should be created without the reporter to not forward the events twice.
+type Writer struct {
+	next     io.Writer
+	reporter go2sky.Reporter
+}
+
+// NewWriter creates the writer of the JSON events, the events are forwarded only if the reporter is not nil
+func NewWriter(next io.Writer, reporter go2sky.Reporter) *Writer {
+	return &Writer{next: next, reporter: reporter}
+}
+
+// Write implements io.Writer, the level is read from the level field of the event
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel implements zerolog.LevelWriter, the events not in JSON are written without being forwarded
+func (w *Writer) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	n, err := w.next.Write(p)
+	if w.reporter != nil && level != zerolog.Disabled {
+		w.forward(level, p)
+	}
+	return n, err
+}
+
+func (w *Writer) forward(level zerolog.Level, p []byte) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(p, &fields); err != nil {
+		return
+	}
+	if level == zerolog.NoLevel {
+		if parsed, err := zerolog.ParseLevel(fieldString(fields[zerolog.LevelFieldName])); err == nil {
+			level = parsed
+		}
+	}
+	msg := fieldString(fields[zerolog.MessageFieldName])
+	sw := &log.SkyWalkingContext{
+		ServiceName:         fieldString(fields[log.FieldServiceName]),
+		ServiceInstanceName: fieldString(fields[log.FieldServiceInstanceName]),
+		TraceID:             fieldString(fields[log.FieldTraceID]),
+		TraceSegmentID:      fieldString(fields[log.FieldTraceSegmentID]),
+	}
+	if spanID, err := json.Number(fields[log.FieldSpanID]).Int64(); err == nil {
+		sw.SpanID = int32(spanID)
+	}
+	ctx := context.Background()
+	if sw.TraceID != "" {
+		ctx = log.NewContext(ctx, sw)
+	}
+	for _, key := range []string{zerolog.LevelFieldName, zerolog.MessageFieldName, zerolog.TimestampFieldName,
+		log.FieldServiceName, log.FieldServiceInstanceName, log.FieldTraceID, log.FieldTraceSegmentID, log.FieldSpanID} {
+		delete(fields, key)
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tags := make([]go2sky.LogTag, 0, len(keys))
+	for _, key := range keys {
+		tags = append(tags, go2sky.LogTag{Key: key, Value: fieldString(fields[key])})
+	}
+	log.Forward(w.reporter, ctx, logLevel(level), msg, tags)
+}
+
+// fieldString returns the string field unquoted, and the other fields in JSON
+func fieldString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
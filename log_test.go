@@ -0,0 +1,111 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"context"
+	"testing"
+)
+
+type recordLogReporter struct {
+	discardReporter
+	logs []ReportedLogData
+}
+
+func (r *recordLogReporter) SendLog(data ReportedLogData) {
+	r.logs = append(r.logs, data)
+}
+
+func TestLogger_Structured(t *testing.T) {
+	reporter := &recordLogReporter{}
+	logger, err := NewLogger(reporter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	logger.WriteLogWithContext(ctx, LogLevelInfo, "plain")
+	logger.WriteLogWithFields(ctx, LogLevelWarn, "slow", LogTag{Key: "cost", Value: "3"})
+	if err = logger.WriteJSONLogWithContext(ctx, LogLevelError, map[string]int{"order": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = logger.WriteYAMLLogWithContext(ctx, LogLevelError, map[string]int{"order": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = logger.WriteJSONLogWithContext(ctx, LogLevelError, func() {}); err == nil {
+		t.Error("the expected error of the unsupported JSON value")
+	}
+
+	tests := []struct {
+		bodyType LogBodyType
+		data     string
+		tags     int
+	}{
+		{LogBodyTypeText, "plain", 0},
+		{LogBodyTypeText, "slow", 1},
+		{LogBodyTypeJSON, `{"order":1}`, 0},
+		{LogBodyTypeYAML, "order: 1\n", 0},
+	}
+	if len(reporter.logs) != len(tests) {
+		t.Fatalf("the expected %d logs, current is %d", len(tests), len(reporter.logs))
+	}
+	for i, tt := range tests {
+		l := reporter.logs[i].(StructuredLogData)
+		if l.BodyType() != tt.bodyType || l.Data() != tt.data || len(l.Tags()) != tt.tags || l.Timestamp().IsZero() {
+			t.Errorf("unexpected log %d: %v", i, l)
+		}
+	}
+}
+
+func TestLogger_ErrorLogToSpan(t *testing.T) {
+	reporter := &recordLogReporter{}
+	tracer, err := NewTracer("service", WithInstance("instance"), WithReporter(&mockRegisterReporter{success: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		opts   []LoggerOption
+		level  LogLevel
+		marked bool
+	}{
+		{name: "error", opts: []LoggerOption{WithErrorLogToSpan()}, level: LogLevelError, marked: true},
+		{name: "upper case", opts: []LoggerOption{WithErrorLogToSpan()}, level: "ERROR", marked: true},
+		{name: "warn", opts: []LoggerOption{WithErrorLogToSpan()}, level: LogLevelWarn, marked: false},
+		{name: "disabled", level: LogLevelError, marked: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span, ctx, err := tracer.CreateLocalSpan(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			logger, err := NewLogger(reporter, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			logger.WriteLogWithContext(ctx, tt.level, "failed")
+			s := span.(ReportedSpan)
+			if s.IsError() != tt.marked || (len(s.Logs()) == 1) != tt.marked {
+				t.Fatalf("the expected span marked %v, current is %v with logs %v", tt.marked, s.IsError(), s.Logs())
+			}
+			if tt.marked && s.Logs()[0].GetData()[1].GetValue() != "failed" {
+				t.Errorf("unexpected span log %v", s.Logs()[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// MeterType is used to identify counter, gauge and histogram
+type MeterType int32
+
+const (
+	// MeterTypeCounter is a monotonically increasing value
+	MeterTypeCounter MeterType = iota
+	// MeterTypeGauge is a value read when the meters are collected
+	MeterTypeGauge
+	// MeterTypeHistogram counts the values in buckets
+	MeterTypeHistogram
+)
+
+var (
+	meters = &meterRegistry{index: make(map[string]struct{})}
+)
+
+// MeterLabel is a label of the application meter
+type MeterLabel struct {
+	Name  string
+	Value string
+}
+
+// MeterBucket is a histogram bucket, Bucket is the lower boundary of the bucket,
+// it is negative infinity for the values less than the first bucket.
+type MeterBucket struct {
+	Bucket float64
+	Count  int64
+}
+
+// MeterValue is the collected value of an application meter
+type MeterValue struct {
+	Type   MeterType
+	Name   string
+	Labels []MeterLabel
+	// the value of counter and gauge, the sum of the observed values of histogram
+	Value float64
+	// the buckets of histogram
+	Buckets []MeterBucket
+	// whether the value and the bucket counts are the deltas since the last collection
+	Delta bool
+}
+
+// MeterOption allows for functional options to adjust behaviour
+// of a meter to be created by NewCounter, NewGauge and NewHistogram
+type MeterOption func(m *meterIdentity)
+
+// WithMeterLabel adds a label to the meter
+func WithMeterLabel(name, value string) MeterOption {
+	return func(m *meterIdentity) {
+		m.labels = append(m.labels, MeterLabel{Name: name, Value: value})
+	}
+}
+
+type meterIdentity struct {
+	name   string
+	labels []MeterLabel
+}
+
+func newMeterIdentity(name string, opts []MeterOption) (*meterIdentity, error) {
+	if name == "" {
+		return nil, errors.New("meter name must not be empty")
+	}
+	m := &meterIdentity{name: name}
+	for _, opt := range opts {
+		opt(m)
+	}
+	sort.Slice(m.labels, func(i, j int) bool {
+		return m.labels[i].Name < m.labels[j].Name
+	})
+	return m, nil
+}
+
+func (m *meterIdentity) id() string {
+	var b strings.Builder
+	b.WriteString(m.name)
+	for _, l := range m.labels {
+		b.WriteString("," + l.Name + "=" + l.Value)
+	}
+	return b.String()
+}
+
+func (m *meterIdentity) value(meterType MeterType) MeterValue {
+	return MeterValue{Type: meterType, Name: m.name, Labels: m.labels}
+}
+
+// Counter is a monotonically increasing meter, it is safe for concurrent use
+type Counter struct {
+	*meterIdentity
+	bits uint64
+}
+
+// NewCounter creates and registers a counter
+func NewCounter(name string, opts ...MeterOption) (*Counter, error) {
+	identity, err := newMeterIdentity(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	c := &Counter{meterIdentity: identity}
+	if err := meters.register(identity.id(), c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Inc increases the counter by 1
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increases the counter, the negative delta is ignored
+func (c *Counter) Add(delta float64) {
+	if delta <= 0 {
+		return
+	}
+	addFloat64(&c.bits, delta)
+}
+
+// Get returns the current value
+func (c *Counter) Get() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+func (c *Counter) collect() (MeterValue, bool) {
+	v := c.value(MeterTypeCounter)
+	v.Value = c.Get()
+	return v, true
+}
+
+// Gauge reads its value by the getter when the meters are collected
+type Gauge struct {
+	*meterIdentity
+	getter func() float64
+}
+
+// NewGauge creates and registers a gauge
+func NewGauge(name string, getter func() float64, opts ...MeterOption) (*Gauge, error) {
+	if getter == nil {
+		return nil, errParameter
+	}
+	identity, err := newMeterIdentity(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	g := &Gauge{meterIdentity: identity, getter: getter}
+	if err := meters.register(identity.id(), g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Get returns the current value
+func (g *Gauge) Get() float64 {
+	return g.getter()
+}
+
+func (g *Gauge) collect() (v MeterValue, ok bool) {
+	// a panic getter should not stop the collection of the other meters
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	v = g.value(MeterTypeGauge)
+	v.Value = g.Get()
+	return v, true
+}
+
+// Histogram counts the observed values in buckets, it is safe for concurrent use
+type Histogram struct {
+	*meterIdentity
+	// the lower boundaries in ascending order
+	buckets []float64
+	// counts[0] is the values less than the first bucket, counts[i] is buckets[i-1]
+	counts []int64
+	sum    uint64
+}
+
+// NewHistogram creates and registers a histogram with the lower boundaries of the buckets
+func NewHistogram(name string, buckets []float64, opts ...MeterOption) (*Histogram, error) {
+	identity, err := newMeterIdentity(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(buckets) == 0 {
+		return nil, errors.New("histogram buckets must not be empty")
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	for i, b := range sorted {
+		if math.IsNaN(b) || math.IsInf(b, 0) || (i > 0 && b == sorted[i-1]) {
+			return nil, errors.Errorf("invalid histogram buckets %v", buckets)
+		}
+	}
+	h := &Histogram{meterIdentity: identity, buckets: sorted, counts: make([]int64, len(sorted)+1)}
+	if err := meters.register(identity.id(), h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Observe counts the value in its bucket
+func (h *Histogram) Observe(value float64) {
+	i := sort.Search(len(h.buckets), func(i int) bool {
+		return h.buckets[i] > value
+	})
+	atomic.AddInt64(&h.counts[i], 1)
+	addFloat64(&h.sum, value)
+}
+
+func (h *Histogram) collect() (MeterValue, bool) {
+	v := h.value(MeterTypeHistogram)
+	v.Value = math.Float64frombits(atomic.LoadUint64(&h.sum))
+	v.Buckets = make([]MeterBucket, 0, len(h.counts))
+	v.Buckets = append(v.Buckets, MeterBucket{Bucket: math.Inf(-1), Count: atomic.LoadInt64(&h.counts[0])})
+	for i, b := range h.buckets {
+		v.Buckets = append(v.Buckets, MeterBucket{Bucket: b, Count: atomic.LoadInt64(&h.counts[i+1])})
+	}
+	return v, true
+}
+
+// addFloat64 adds delta to the float64 stored as bits
+func addFloat64(bits *uint64, delta float64) {
+	for {
+		old := atomic.LoadUint64(bits)
+		if atomic.CompareAndSwapUint64(bits, old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+type meter interface {
+	collect() (MeterValue, bool)
+}
+
+// meterSource provides a group of cumulative meters, such as the endpoint metrics of a tracer.
+// The source is never drained, collectMeters reports the deltas since the snapshot of the last
+// collection and returns the new one, so each metric collector reports its own intervals.
+type meterSource interface {
+	collectMeters(last sourceSnapshot) ([]MeterValue, sourceSnapshot)
+}
+
+// sourceSnapshot is the state of a meter source at a collection, defined by the source
+type sourceSnapshot interface{}
+
+// meterSnapshots are the sources snapshots at the last collection of a metric collector
+type meterSnapshots map[meterSource]sourceSnapshot
+
+// meterRegistry holds the application meters in the registration order
+type meterRegistry struct {
+	mu      sync.RWMutex
+	meters  []meter
+	sources []meterSource
+	index   map[string]struct{}
+}
+
+func (r *meterRegistry) register(id string, m meter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.index[id]; ok {
+		return errors.Errorf("meter %s is already registered", id)
+	}
+	r.index[id] = struct{}{}
+	r.meters = append(r.meters, m)
+	return nil
+}
+
+func (r *meterRegistry) registerSource(source meterSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = append(r.sources, source)
+}
+
+func (r *meterRegistry) unregisterSource(source meterSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// copy on write, the collecting may be reading the sources
+	sources := make([]meterSource, 0, len(r.sources))
+	for _, s := range r.sources {
+		if s != source {
+			sources = append(sources, s)
+		}
+	}
+	r.sources = sources
+}
+
+// collect returns the application meters and the deltas of the sources since the last snapshots,
+// the snapshots of the unregistered sources are dropped from the returned ones.
+func (r *meterRegistry) collect(last meterSnapshots) ([]MeterValue, meterSnapshots) {
+	r.mu.RLock()
+	registered, sources := r.meters, r.sources
+	r.mu.RUnlock()
+
+	values := make([]MeterValue, 0, len(registered))
+	for _, m := range registered {
+		if v, ok := m.collect(); ok {
+			values = append(values, v)
+		}
+	}
+	snapshots := make(meterSnapshots, len(sources))
+	for _, source := range sources {
+		var sourceValues []MeterValue
+		sourceValues, snapshots[source] = source.collectMeters(last[source])
+		values = append(values, sourceValues...)
+	}
+	return values, snapshots
+}
+
+// snapshot returns the current snapshots of the sources, a metric collector starts its first interval from them
+func (r *meterRegistry) snapshot() meterSnapshots {
+	r.mu.RLock()
+	sources := r.sources
+	r.mu.RUnlock()
+
+	snapshots := make(meterSnapshots, len(sources))
+	for _, source := range sources {
+		_, snapshots[source] = source.collectMeters(nil)
+	}
+	return snapshots
+}
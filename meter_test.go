@@ -0,0 +1,134 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func resetMeters() {
+	meters = &meterRegistry{index: make(map[string]struct{})}
+}
+
+func TestCounter(t *testing.T) {
+	resetMeters()
+	defer resetMeters()
+
+	c, err := NewCounter("requests", WithMeterLabel("method", "GET"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Inc()
+			c.Add(0.5)
+			c.Add(-1)
+		}()
+	}
+	wg.Wait()
+	if c.Get() != 150 {
+		t.Errorf("the expected value of counter is 150, current is %v", c.Get())
+	}
+}
+
+func TestGauge(t *testing.T) {
+	resetMeters()
+	defer resetMeters()
+
+	value := 1.0
+	if _, err := NewGauge("queue_size", func() float64 { return value }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewGauge("broken", func() float64 { panic("broken getter") }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewGauge("nil_getter", nil); err == nil {
+		t.Error("expected an error for nil getter")
+	}
+	value = 3
+	got, _ := meters.collect(nil)
+	want := []MeterValue{{Type: MeterTypeGauge, Name: "queue_size", Value: 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("the expected meters are %+v, current are %+v", want, got)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	resetMeters()
+	defer resetMeters()
+
+	h, err := NewHistogram("latency", []float64{100, 0, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []float64{-1, 0, 5, 10, 99, 100, 1000} {
+		h.Observe(v)
+	}
+	got, _ := meters.collect(nil)
+	want := []MeterValue{{
+		Type:  MeterTypeHistogram,
+		Name:  "latency",
+		Value: 1213,
+		Buckets: []MeterBucket{
+			{Bucket: math.Inf(-1), Count: 1},
+			{Bucket: 0, Count: 2},
+			{Bucket: 10, Count: 2},
+			{Bucket: 100, Count: 2},
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("the expected meters are %+v, current are %+v", want, got)
+	}
+}
+
+func TestNewMeter_Invalid(t *testing.T) {
+	resetMeters()
+	defer resetMeters()
+
+	if _, err := NewCounter("requests", WithMeterLabel("b", "2"), WithMeterLabel("a", "1")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		new  func() error
+	}{
+		{"empty name", func() error { _, err := NewCounter(""); return err }},
+		{"duplicate labels in another order", func() error {
+			_, err := NewGauge("requests", func() float64 { return 0 }, WithMeterLabel("a", "1"), WithMeterLabel("b", "2"))
+			return err
+		}},
+		{"empty buckets", func() error { _, err := NewHistogram("latency", nil); return err }},
+		{"duplicate buckets", func() error { _, err := NewHistogram("latency", []float64{1, 1}); return err }},
+		{"infinite bucket", func() error { _, err := NewHistogram("latency", []float64{1, math.Inf(1)}); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.new(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+	if _, err := NewCounter("requests", WithMeterLabel("a", "2")); err != nil {
+		t.Errorf("unexpected error for different labels: %v", err)
+	}
+}
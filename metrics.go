@@ -25,7 +25,7 @@ import (
 	"log"
 	"os"
 	"runtime"
-	"runtime/debug"
+	"sync"
 	"time"
 )
 
@@ -40,6 +40,33 @@ const (
 	InstanceGolangGoroutineNum = "instance_golang_live_goroutines_num"
 	InstanceCPUUsedRate        = "instance_host_cpu_used_rate"
 	InstanceMemUsedRate        = "instance_host_mem_used_rate"
+
+	InstanceGolangHeapObjects   = "instance_golang_heap_objects"
+	InstanceGolangNextGC        = "instance_golang_next_gc"
+	InstanceGolangMemoryLimit   = "instance_golang_memory_limit"
+	InstanceGolangGCCountDelta  = "instance_golang_gc_count_delta"
+	InstanceGolangGCTimeDelta   = "instance_golang_gc_pause_time_delta"
+	InstanceGolangGCPauseP50    = "instance_golang_gc_pause_time_p50"
+	InstanceGolangGCPauseP90    = "instance_golang_gc_pause_time_p90"
+	InstanceGolangGCPauseP99    = "instance_golang_gc_pause_time_p99"
+	InstanceGolangSchedLatency  = "instance_golang_sched_latency"
+	InstanceGolangMutexWaitTime = "instance_golang_mutex_wait_time_delta"
+	InstanceGolangCGOCallsDelta = "instance_golang_cgo_calls_delta"
+
+	InstanceContainerCPULimit     = "instance_container_cpu_limit"
+	InstanceContainerCPUThrottled = "instance_container_cpu_throttled_periods_delta"
+	InstanceContainerMemoryUsage  = "instance_container_memory_usage"
+	InstanceContainerMemoryLimit  = "instance_container_memory_limit"
+	InstanceContainerOOMKills     = "instance_container_oom_kills_delta"
+
+	InstanceProcessRSS                  = "instance_process_rss"
+	InstanceProcessOpenFDs              = "instance_process_open_fds"
+	InstanceProcessMaxFDs               = "instance_process_max_fds"
+	InstanceProcessSockets              = "instance_process_sockets"
+	InstanceProcessCPUUserTime          = "instance_process_cpu_user_time_delta"
+	InstanceProcessCPUSystemTime        = "instance_process_cpu_system_time_delta"
+	InstanceProcessVoluntaryCtxSwitch   = "instance_process_voluntary_ctx_switches_delta"
+	InstanceProcessInvoluntaryCtxSwitch = "instance_process_involuntary_ctx_switches_delta"
 )
 
 type RunTimeMetric struct {
@@ -51,16 +78,134 @@ type RunTimeMetric struct {
 	StackInUse int64
 	// the number of completed GC cycles since instance started
 	GCCount int64
-	// the total gc pause time(NS) since instance started
+	// the total gc pause time(NS) since instance started, estimated by the gc pause histogram
 	GCPauseTime int64
 	// the number of goroutines that currently exist
 	GoroutineNum int64
 	// the number of records in the thread creation profile
 	ThreadNum int64
-	// the cpu Used float64
+	// the cpu Used float64, against the CPU quota when running in a container
 	CpuUsedRate float64
-	// the Percentage of RAM used by programs
+	// the Percentage of RAM used by programs, against the memory limit when running in a container
 	MemUsedRate float64
+	// whether the CPU and memory metrics are read from the host
+	Host bool
+	// whether the CPU and memory metrics are read from the cgroup of the container
+	Container bool
+	// whether the memory used rate is read from the host as the container has no memory limit
+	HostMemory bool
+	// the CPU quota(cores) of the container, 0 if unlimited
+	ContainerCPULimit float64
+	// the number of periods the container was throttled in the collection interval
+	ContainerCPUThrottledDelta int64
+	// the working set(bytes) of the container
+	ContainerMemoryUsage int64
+	// the memory limit(bytes) of the container, 0 if unlimited
+	ContainerMemoryLimit int64
+	// the number of processes killed by the OOM killer in the collection interval
+	ContainerOOMKillsDelta int64
+	// the number of live and unswept heap objects
+	HeapObjects int64
+	// the heap size target(bytes) of the next GC cycle
+	NextGC int64
+	// the memory limit(bytes) of the Go runtime, 0 if not supported by the Go version
+	MemoryLimit int64
+	// the number of completed GC cycles in the collection interval
+	GCCountDelta int64
+	// the gc pause time(NS) in the collection interval
+	GCPauseTimeDelta int64
+	// the percentiles of the gc pause time(NS) in the collection interval
+	GCPauseP50 int64
+	GCPauseP90 int64
+	GCPauseP99 int64
+	// the histogram of the time(NS) goroutines spent runnable before running in the collection interval
+	SchedLatency []MeterBucket
+	// the time(NS) goroutines spent blocked on sync.Mutex and sync.RWMutex in the collection interval
+	MutexWaitTimeDelta int64
+	// the number of calls from Go to C in the collection interval
+	CGOCallsDelta int64
+	// whether the process metrics are read from /proc/self
+	Process bool
+	// the resident set size(bytes) of the process
+	ProcessRSS int64
+	// the number of open file descriptors, and the soft limit of them, 0 if unlimited
+	ProcessOpenFDs int64
+	ProcessMaxFDs  int64
+	// the number of open sockets
+	ProcessSockets int64
+	// the CPU time(NS) of the process in user and kernel mode in the collection interval
+	ProcessCPUUserTimeDelta   int64
+	ProcessCPUSystemTimeDelta int64
+	// the context switches of all the threads in the collection interval
+	ProcessVoluntaryCtxSwitchesDelta   int64
+	ProcessInvoluntaryCtxSwitchesDelta int64
+	// the application meters created by NewCounter, NewGauge and NewHistogram
+	Meters []MeterValue
+}
+
+// MeterValues returns the collected values as meters, the runtime values are gauges,
+// followed by the delta scheduler latency histogram and the application meters.
+func (m *RunTimeMetric) MeterValues() []MeterValue {
+	values := make([]MeterValue, 0, 48+len(m.Meters))
+	gauge := func(name string, value float64) {
+		values = append(values, MeterValue{Type: MeterTypeGauge, Name: name, Value: value})
+	}
+	gauge(InstanceGolangHeap, float64(m.HeapAlloc))
+	gauge(InstanceGolangStack, float64(m.StackInUse))
+	gauge(InstanceGolangGCTime, float64(m.GCPauseTime))
+	gauge(InstanceGolangGCCount, float64(m.GCCount))
+	gauge(InstanceGolangThreadNum, float64(m.ThreadNum))
+	gauge(InstanceGolangGoroutineNum, float64(m.GoroutineNum))
+	if m.Host || m.Container {
+		gauge(InstanceCPUUsedRate, m.CpuUsedRate)
+	}
+	// the container without memory limit has no used rate of its own
+	if m.Host || m.HostMemory || (m.Container && m.ContainerMemoryLimit > 0) {
+		gauge(InstanceMemUsedRate, m.MemUsedRate)
+	}
+	gauge(InstanceGolangHeapObjects, float64(m.HeapObjects))
+	gauge(InstanceGolangNextGC, float64(m.NextGC))
+	gauge(InstanceGolangMemoryLimit, float64(m.MemoryLimit))
+	gauge(InstanceGolangGCCountDelta, float64(m.GCCountDelta))
+	gauge(InstanceGolangGCTimeDelta, float64(m.GCPauseTimeDelta))
+	gauge(InstanceGolangGCPauseP50, float64(m.GCPauseP50))
+	gauge(InstanceGolangGCPauseP90, float64(m.GCPauseP90))
+	gauge(InstanceGolangGCPauseP99, float64(m.GCPauseP99))
+	gauge(InstanceGolangMutexWaitTime, float64(m.MutexWaitTimeDelta))
+	gauge(InstanceGolangCGOCallsDelta, float64(m.CGOCallsDelta))
+	if m.Container {
+		gauge(InstanceContainerCPULimit, m.ContainerCPULimit)
+		gauge(InstanceContainerCPUThrottled, float64(m.ContainerCPUThrottledDelta))
+		gauge(InstanceContainerMemoryUsage, float64(m.ContainerMemoryUsage))
+		gauge(InstanceContainerMemoryLimit, float64(m.ContainerMemoryLimit))
+		gauge(InstanceContainerOOMKills, float64(m.ContainerOOMKillsDelta))
+	}
+	if m.Process {
+		gauge(InstanceProcessRSS, float64(m.ProcessRSS))
+		gauge(InstanceProcessOpenFDs, float64(m.ProcessOpenFDs))
+		gauge(InstanceProcessMaxFDs, float64(m.ProcessMaxFDs))
+		gauge(InstanceProcessSockets, float64(m.ProcessSockets))
+		gauge(InstanceProcessCPUUserTime, float64(m.ProcessCPUUserTimeDelta))
+		gauge(InstanceProcessCPUSystemTime, float64(m.ProcessCPUSystemTimeDelta))
+		gauge(InstanceProcessVoluntaryCtxSwitch, float64(m.ProcessVoluntaryCtxSwitchesDelta))
+		gauge(InstanceProcessInvoluntaryCtxSwitch, float64(m.ProcessInvoluntaryCtxSwitchesDelta))
+	}
+	if len(m.SchedLatency) > 0 {
+		values = append(values, MeterValue{Type: MeterTypeHistogram, Name: InstanceGolangSchedLatency, Buckets: m.SchedLatency, Delta: true})
+	}
+	return append(values, m.Meters...)
+}
+
+// MetricCollectorOption allows for functional options to adjust behaviour
+// of a MetricCollector to be created by InitMetricCollector
+type MetricCollectorOption func(c *MetricCollector)
+
+// WithHostMetrics enables or disables the host CPU and memory metrics, they are enabled by default.
+// The container metrics are still collected when running in a container.
+func WithHostMetrics(enable bool) MetricCollectorOption {
+	return func(c *MetricCollector) {
+		c.hostDisabled = !enable
+	}
 }
 
 type MetricCollector struct {
@@ -70,19 +215,43 @@ type MetricCollector struct {
 	service  string
 	interval time.Duration
 	logger   logger.Log
+	runtime  *runtimeMetricsReader
+	cgroup   *cgroupReader
+	process  *processReader
+
+	// the meter sources snapshots of the last collection
+	snapshotsMu sync.Mutex
+	snapshots   meterSnapshots
+
+	hostDisabled bool
 }
 
-func InitMetricCollector(reporter MetricsReporter, interval *time.Duration, cancelCtx context.Context) {
+func InitMetricCollector(reporter MetricsReporter, interval *time.Duration, cancelCtx context.Context, opts ...MetricCollectorOption) {
 	collector := &MetricCollector{
 		ctx:      cancelCtx,
 		logger:   logger.NewDefaultLogger(log.New(os.Stderr, defaultLogPrefix, log.LstdFlags)),
 		reporter: reporter,
 		interval: defaultInterval,
+		runtime:  newRuntimeMetricsReader(),
+		cgroup:   newContainerCgroupReader(),
+		// the first collection reports the endpoint metrics since the start
+		snapshots: meters.snapshot(),
 	}
 
 	if interval != nil {
 		collector.interval = *interval
 	}
+	for _, opt := range opts {
+		opt(collector)
+	}
+	if process, err := newProcessReader(procSelf); err == nil {
+		collector.process = process
+	}
+	if !collector.hostDisabled {
+		// the first call of cpu.Percent(0) measures since the boot,
+		// call it here so the first collection measures since the start.
+		_, _ = cpu.Percent(0, false)
+	}
 
 	go collector.collect()
 }
@@ -110,30 +279,70 @@ func (c *MetricCollector) collect() {
 }
 
 func (c *MetricCollector) collectMeter() {
-	var rtm runtime.MemStats
-	runtime.ReadMemStats(&rtm)
-	v, _ := mem.VirtualMemory()
-	cpuPercent, _ := cpu.Percent(0, false)
+	now := time.Now()
 	threadNum, _ := runtime.ThreadCreateProfile(nil)
-	var stats debug.GCStats
-	debug.ReadGCStats(&stats)
 
 	runTimeMetric := RunTimeMetric{
-
-		Time:         tool.Millisecond(time.Now()),
-		HeapAlloc:    int64(rtm.HeapAlloc),
-		StackInUse:   int64(rtm.StackInuse),
-		GCCount:      int64(rtm.NumGC),
-		GCPauseTime:  int64(stats.PauseTotal),
-		GoroutineNum: int64(runtime.NumGoroutine()),
-		ThreadNum:    int64(threadNum),
-		CpuUsedRate:  cpuPercent[0],
-		MemUsedRate:  v.UsedPercent,
+		Time:      tool.Millisecond(now),
+		ThreadNum: int64(threadNum),
+	}
+	c.snapshotsMu.Lock()
+	runTimeMetric.Meters, c.snapshots = meters.collect(c.snapshots)
+	c.snapshotsMu.Unlock()
+	c.runtime.read(&runTimeMetric)
+	if c.cgroup != nil {
+		if err := c.cgroup.read(&runTimeMetric, now); err != nil {
+			c.logger.Errorf("read cgroup metrics err %v", err)
+		} else {
+			runTimeMetric.Container = true
+		}
+	}
+	if !c.hostDisabled {
+		switch {
+		case !runTimeMetric.Container:
+			c.collectHost(&runTimeMetric)
+		case runTimeMetric.ContainerMemoryLimit == 0:
+			// the container without memory limit shares the memory of the host
+			c.collectHostMemory(&runTimeMetric)
+		}
+	}
+	if c.process != nil {
+		if err := c.process.read(&runTimeMetric); err != nil {
+			c.logger.Errorf("read process metrics err %v", err)
+		} else {
+			runTimeMetric.Process = true
+		}
 	}
 
 	c.reporter.SendMetrics(runTimeMetric)
 }
 
+func (c *MetricCollector) collectHostMemory(m *RunTimeMetric) {
+	v, err := mem.VirtualMemory()
+	if err != nil {
+		c.logger.Errorf("read host memory err %v", err)
+		return
+	}
+	m.MemUsedRate = v.UsedPercent
+	m.HostMemory = true
+}
+
+func (c *MetricCollector) collectHost(m *RunTimeMetric) {
+	v, err := mem.VirtualMemory()
+	if err != nil {
+		c.logger.Errorf("read host memory err %v", err)
+		return
+	}
+	cpuPercent, err := cpu.Percent(0, false)
+	if err != nil || len(cpuPercent) == 0 {
+		c.logger.Errorf("read host cpu err %v", err)
+		return
+	}
+	m.CpuUsedRate = cpuPercent[0]
+	m.MemUsedRate = v.UsedPercent
+	m.Host = true
+}
+
 type MetricsReporter interface {
 	SendMetrics(runTimeMeter RunTimeMetric)
 }
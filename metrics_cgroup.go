@@ -0,0 +1,307 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultCgroupRoot = "/sys/fs/cgroup"
+	procSelfCgroup    = "/proc/self/cgroup"
+	// memory.limit_in_bytes of cgroup v1 is a page aligned max int64 when it is unlimited
+	cgroupV1UnlimitedMemory = uint64(1) << 62
+)
+
+// cgroupStats is a reading of the cgroup files
+type cgroupStats struct {
+	// the cumulative CPU time(NS) used by the cgroup
+	cpuUsage uint64
+	// the CPU quota in cores, 0 if unlimited
+	cpuLimit float64
+	// the number of periods the cgroup was throttled
+	cpuThrottled uint64
+	// the working set(bytes), usage minus inactive file cache
+	memoryUsage uint64
+	// the memory limit(bytes), 0 if unlimited
+	memoryLimit uint64
+	// the number of processes killed by the OOM killer
+	oomKills uint64
+}
+
+// cgroupReader reads the CPU and memory usage of the container from cgroup v1 or v2,
+// and keeps the previous reading to report the per-interval deltas.
+type cgroupReader struct {
+	mu        sync.Mutex
+	v2        bool
+	cpuDir    string
+	cpuAcct   string
+	memoryDir string
+
+	last     *cgroupStats
+	lastTime time.Time
+}
+
+// newContainerCgroupReader returns the cgroup reader of the current process,
+// or nil if it is not running in a container.
+func newContainerCgroupReader() *cgroupReader {
+	if !inContainer() {
+		return nil
+	}
+	r, err := newCgroupReader(defaultCgroupRoot, procSelfCgroup)
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+func inContainer() bool {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return true
+	}
+	for _, f := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(f); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// newCgroupReader detects the cgroup version mounted at root, and locates the cgroup
+// directories of the process by the cgroupFile in the format of /proc/self/cgroup.
+func newCgroupReader(root, cgroupFile string) (*cgroupReader, error) {
+	paths, err := parseProcCgroup(cgroupFile)
+	if err != nil {
+		return nil, err
+	}
+	r := &cgroupReader{}
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		r.v2 = true
+		dir := cgroupDir(root, paths[""])
+		r.cpuDir, r.cpuAcct, r.memoryDir = dir, dir, dir
+	} else {
+		r.cpuDir = cgroupDir(firstDir(root, "cpu", "cpu,cpuacct"), paths["cpu"])
+		r.cpuAcct = cgroupDir(firstDir(root, "cpuacct", "cpu,cpuacct"), paths["cpuacct"])
+		r.memoryDir = cgroupDir(filepath.Join(root, "memory"), paths["memory"])
+	}
+	stats, err := r.stats()
+	if err != nil {
+		return nil, err
+	}
+	r.last, r.lastTime = stats, time.Now()
+	return r, nil
+}
+
+// parseProcCgroup returns the cgroup path of each controller, the key of cgroup v2 is empty
+func parseProcCgroup(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	paths := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+	return paths, scanner.Err()
+}
+
+func firstDir(root string, names ...string) string {
+	for _, name := range names {
+		dir := filepath.Join(root, name)
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+	}
+	return filepath.Join(root, names[0])
+}
+
+// cgroupDir returns the nested cgroup directory if it is visible, otherwise the mount point,
+// which is the cgroup of the container when the cgroup namespace is enabled.
+func cgroupDir(mount, path string) string {
+	if path != "" && path != "/" {
+		dir := filepath.Join(mount, path)
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+	}
+	return mount
+}
+
+func (r *cgroupReader) stats() (*cgroupStats, error) {
+	if r.v2 {
+		return r.statsV2()
+	}
+	return r.statsV1()
+}
+
+func (r *cgroupReader) statsV2() (*cgroupStats, error) {
+	s := &cgroupStats{}
+	cpuStat, err := readKeyValues(filepath.Join(r.cpuDir, "cpu.stat"))
+	if err != nil {
+		return nil, err
+	}
+	s.cpuUsage = cpuStat["usage_usec"] * 1000
+	s.cpuThrottled = cpuStat["nr_throttled"]
+	if fields, err := readFields(filepath.Join(r.cpuDir, "cpu.max")); err == nil && len(fields) == 2 && fields[0] != "max" {
+		s.cpuLimit = parseCPUQuota(fields[0], fields[1])
+	}
+	if s.memoryUsage, err = readUint(filepath.Join(r.memoryDir, "memory.current")); err != nil {
+		return nil, err
+	}
+	if memoryStat, err := readKeyValues(filepath.Join(r.memoryDir, "memory.stat")); err == nil {
+		s.memoryUsage = subtractFloor(s.memoryUsage, memoryStat["inactive_file"])
+	}
+	if fields, err := readFields(filepath.Join(r.memoryDir, "memory.max")); err == nil && len(fields) == 1 && fields[0] != "max" {
+		s.memoryLimit, _ = strconv.ParseUint(fields[0], 10, 64)
+	}
+	if events, err := readKeyValues(filepath.Join(r.memoryDir, "memory.events")); err == nil {
+		s.oomKills = events["oom_kill"]
+	}
+	return s, nil
+}
+
+func (r *cgroupReader) statsV1() (*cgroupStats, error) {
+	s := &cgroupStats{}
+	var err error
+	if s.cpuUsage, err = readUint(filepath.Join(r.cpuAcct, "cpuacct.usage")); err != nil {
+		return nil, err
+	}
+	if cpuStat, err := readKeyValues(filepath.Join(r.cpuDir, "cpu.stat")); err == nil {
+		s.cpuThrottled = cpuStat["nr_throttled"]
+	}
+	quota, quotaErr := readFields(filepath.Join(r.cpuDir, "cpu.cfs_quota_us"))
+	period, periodErr := readFields(filepath.Join(r.cpuDir, "cpu.cfs_period_us"))
+	if quotaErr == nil && periodErr == nil && len(quota) == 1 && len(period) == 1 {
+		s.cpuLimit = parseCPUQuota(quota[0], period[0])
+	}
+	if s.memoryUsage, err = readUint(filepath.Join(r.memoryDir, "memory.usage_in_bytes")); err != nil {
+		return nil, err
+	}
+	if memoryStat, err := readKeyValues(filepath.Join(r.memoryDir, "memory.stat")); err == nil {
+		s.memoryUsage = subtractFloor(s.memoryUsage, memoryStat["total_inactive_file"])
+	}
+	if limit, err := readUint(filepath.Join(r.memoryDir, "memory.limit_in_bytes")); err == nil && limit < cgroupV1UnlimitedMemory {
+		s.memoryLimit = limit
+	}
+	if oomControl, err := readKeyValues(filepath.Join(r.memoryDir, "memory.oom_control")); err == nil {
+		s.oomKills = oomControl["oom_kill"]
+	}
+	return s, nil
+}
+
+// read fills the container part of the metric, the CPU used rate is against the CPU quota,
+// or all the CPUs if the quota is unlimited, the memory used rate is against the memory limit.
+func (r *cgroupReader) read(m *RunTimeMetric, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, err := r.stats()
+	if err != nil {
+		return err
+	}
+	cpuLimit := s.cpuLimit
+	if cpuLimit == 0 {
+		cpuLimit = float64(runtime.NumCPU())
+	}
+	if elapsed := now.Sub(r.lastTime); elapsed > 0 {
+		m.CpuUsedRate = float64(subtractFloor(s.cpuUsage, r.last.cpuUsage)) / (float64(elapsed) * cpuLimit) * 100
+	}
+	if s.memoryLimit > 0 {
+		m.MemUsedRate = float64(s.memoryUsage) / float64(s.memoryLimit) * 100
+	}
+	m.ContainerCPULimit = s.cpuLimit
+	m.ContainerMemoryUsage = int64(s.memoryUsage)
+	m.ContainerMemoryLimit = int64(s.memoryLimit)
+	m.ContainerCPUThrottledDelta = int64(subtractFloor(s.cpuThrottled, r.last.cpuThrottled))
+	m.ContainerOOMKillsDelta = int64(subtractFloor(s.oomKills, r.last.oomKills))
+	r.last, r.lastTime = s, now
+	return nil
+}
+
+func parseCPUQuota(quota, period string) float64 {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return q / p
+}
+
+func subtractFloor(a, b uint64) uint64 {
+	if a < b {
+		return 0
+	}
+	return a - b
+}
+
+func readFields(file string) ([]string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(content)), nil
+}
+
+func readUint(file string) (uint64, error) {
+	fields, err := readFields(file)
+	if err != nil {
+		return 0, err
+	}
+	if len(fields) != 1 {
+		return 0, errors.Errorf("unexpected content of %s", file)
+	}
+	return strconv.ParseUint(fields[0], 10, 64)
+}
+
+// readKeyValues reads the flat keyed file such as cpu.stat and memory.stat
+func readKeyValues(file string) (map[string]uint64, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values, nil
+}
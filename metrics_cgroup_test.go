@@ -0,0 +1,153 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func writeFixture(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCgroupReader_V2(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, map[string]string{
+		"proc/cgroup":                         "0::/kubepods/pod1\n",
+		"cgroup/cgroup.controllers":           "cpu memory\n",
+		"cgroup/kubepods/pod1/cpu.stat":       "usage_usec 1000000\nnr_periods 10\nnr_throttled 2\n",
+		"cgroup/kubepods/pod1/cpu.max":        "200000 100000\n",
+		"cgroup/kubepods/pod1/memory.current": "1048576\n",
+		"cgroup/kubepods/pod1/memory.stat":    "anon 524288\ninactive_file 524288\n",
+		"cgroup/kubepods/pod1/memory.max":     "2097152\n",
+		"cgroup/kubepods/pod1/memory.events":  "low 0\noom 1\noom_kill 1\n",
+	})
+	r, err := newCgroupReader(filepath.Join(dir, "cgroup"), filepath.Join(dir, "proc/cgroup"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFixture(t, dir, map[string]string{
+		"cgroup/kubepods/pod1/cpu.stat":      "usage_usec 2000000\nnr_periods 20\nnr_throttled 5\n",
+		"cgroup/kubepods/pod1/memory.events": "low 0\noom 3\noom_kill 3\n",
+	})
+	var m RunTimeMetric
+	if err := r.read(&m, r.lastTime.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	// 1s CPU time in 1s against 2 cores
+	if m.CpuUsedRate != 50 {
+		t.Errorf("the expected cpu used rate is 50, current is %v", m.CpuUsedRate)
+	}
+	if m.MemUsedRate != 25 || m.ContainerMemoryUsage != 524288 || m.ContainerMemoryLimit != 2097152 {
+		t.Errorf("unexpected memory metrics %v %d %d", m.MemUsedRate, m.ContainerMemoryUsage, m.ContainerMemoryLimit)
+	}
+	if m.ContainerCPULimit != 2 || m.ContainerCPUThrottledDelta != 3 || m.ContainerOOMKillsDelta != 2 {
+		t.Errorf("unexpected container metrics %v %d %d", m.ContainerCPULimit, m.ContainerCPUThrottledDelta, m.ContainerOOMKillsDelta)
+	}
+}
+
+func TestCgroupReader_V2Unlimited(t *testing.T) {
+	dir := t.TempDir()
+	// the cgroup namespace hides the nested path, the mount point is the cgroup of the container
+	writeFixture(t, dir, map[string]string{
+		"proc/cgroup":               "0::/\n",
+		"cgroup/cgroup.controllers": "cpu memory\n",
+		"cgroup/cpu.stat":           "usage_usec 0\n",
+		"cgroup/cpu.max":            "max 100000\n",
+		"cgroup/memory.current":     "1024\n",
+		"cgroup/memory.max":         "max\n",
+	})
+	r, err := newCgroupReader(filepath.Join(dir, "cgroup"), filepath.Join(dir, "proc/cgroup"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFixture(t, dir, map[string]string{
+		"cgroup/cpu.stat": "usage_usec 1000000\n",
+	})
+	var m RunTimeMetric
+	if err := r.read(&m, r.lastTime.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if want := 100 / float64(runtime.NumCPU()); math.Abs(m.CpuUsedRate-want) > 1e-9 {
+		t.Errorf("the expected cpu used rate is %v, current is %v", want, m.CpuUsedRate)
+	}
+	if m.ContainerCPULimit != 0 || m.ContainerMemoryLimit != 0 || m.MemUsedRate != 0 {
+		t.Errorf("unexpected unlimited container metrics %+v", m)
+	}
+}
+
+func TestCgroupReader_V1(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, map[string]string{
+		"proc/cgroup": "12:memory:/docker/abc\n4:cpu,cpuacct:/docker/abc\n1:name=systemd:/docker/abc\n",
+		"cgroup/cpu,cpuacct/docker/abc/cpuacct.usage":     "0\n",
+		"cgroup/cpu,cpuacct/docker/abc/cpu.cfs_quota_us":  "50000\n",
+		"cgroup/cpu,cpuacct/docker/abc/cpu.cfs_period_us": "100000\n",
+		"cgroup/cpu,cpuacct/docker/abc/cpu.stat":          "nr_periods 1\nnr_throttled 1\n",
+		"cgroup/memory/docker/abc/memory.usage_in_bytes":  "4096\n",
+		"cgroup/memory/docker/abc/memory.stat":            "cache 0\ntotal_inactive_file 1024\n",
+		"cgroup/memory/docker/abc/memory.limit_in_bytes":  "9223372036854771712\n",
+		"cgroup/memory/docker/abc/memory.oom_control":     "oom_kill_disable 0\nunder_oom 0\noom_kill 0\n",
+	})
+	r, err := newCgroupReader(filepath.Join(dir, "cgroup"), filepath.Join(dir, "proc/cgroup"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFixture(t, dir, map[string]string{
+		"cgroup/cpu,cpuacct/docker/abc/cpuacct.usage":    "250000000\n",
+		"cgroup/memory/docker/abc/memory.limit_in_bytes": "12288\n",
+		"cgroup/memory/docker/abc/memory.oom_control":    "oom_kill_disable 0\nunder_oom 0\noom_kill 1\n",
+	})
+	var m RunTimeMetric
+	if err := r.read(&m, r.lastTime.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	// 0.25s CPU time in 1s against 0.5 core
+	if m.CpuUsedRate != 50 || m.ContainerCPULimit != 0.5 {
+		t.Errorf("unexpected cpu metrics %v %v", m.CpuUsedRate, m.ContainerCPULimit)
+	}
+	if m.ContainerMemoryUsage != 3072 || m.MemUsedRate != 25 || m.ContainerOOMKillsDelta != 1 {
+		t.Errorf("unexpected memory metrics %+v", m)
+	}
+}
+
+func TestCgroupReader_Missing(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, map[string]string{
+		"proc/cgroup":               "0::/\n",
+		"cgroup/cgroup.controllers": "",
+	})
+	if _, err := newCgroupReader(filepath.Join(dir, "cgroup"), filepath.Join(dir, "proc/cgroup")); err == nil {
+		t.Error("expected an error without the cgroup files")
+	}
+	if _, err := newCgroupReader(filepath.Join(dir, "cgroup"), filepath.Join(dir, "proc/missing")); err == nil {
+		t.Error("expected an error without the proc cgroup file")
+	}
+}
@@ -0,0 +1,199 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	procSelf = "/proc/self"
+	// the clock ticks per second of /proc/[pid]/stat, USER_HZ is fixed to 100 in the proc ABI
+	procClockTicks = 100
+)
+
+// processStats is a reading of the proc files of the process
+type processStats struct {
+	rss         uint64
+	openFDs     uint64
+	maxFDs      uint64
+	sockets     uint64
+	cpuUser     uint64
+	cpuSystem   uint64
+	voluntary   uint64
+	involuntary uint64
+}
+
+// processReader reads the resource usage of the process from the proc filesystem,
+// and keeps the previous reading to report the per-interval deltas.
+type processReader struct {
+	mu   sync.Mutex
+	dir  string
+	last *processStats
+}
+
+// newProcessReader returns the reader of the proc directory such as /proc/self,
+// an error is returned if the proc filesystem is not available.
+func newProcessReader(dir string) (*processReader, error) {
+	r := &processReader{dir: dir}
+	stats, err := r.stats()
+	if err != nil {
+		return nil, err
+	}
+	r.last = stats
+	return r, nil
+}
+
+func (r *processReader) stats() (*processStats, error) {
+	s := &processStats{}
+	if err := r.readStat(s); err != nil {
+		return nil, err
+	}
+	status, err := readStatus(filepath.Join(r.dir, "status"))
+	if err != nil {
+		return nil, err
+	}
+	s.rss = status["VmRSS"] * 1024
+	r.readContextSwitches(s, status)
+	r.readFDs(s)
+	return s, nil
+}
+
+func (r *processReader) readStat(s *processStats) error {
+	content, err := os.ReadFile(filepath.Join(r.dir, "stat"))
+	if err != nil {
+		return err
+	}
+	// the command name in parentheses may contain spaces
+	i := strings.LastIndexByte(string(content), ')')
+	if i < 0 {
+		return errors.New("unexpected content of proc stat")
+	}
+	fields := strings.Fields(string(content[i+1:]))
+	// utime and stime are the 14th and 15th fields, the state after the command name is the 3rd
+	if len(fields) < 13 {
+		return errors.New("unexpected content of proc stat")
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return err
+	}
+	s.cpuUser = utime * 1e9 / procClockTicks
+	s.cpuSystem = stime * 1e9 / procClockTicks
+	return nil
+}
+
+// readContextSwitches sums the context switches of all the threads,
+// the status of the process only counts the main thread.
+func (r *processReader) readContextSwitches(s *processStats, status map[string]uint64) {
+	tasks, err := os.ReadDir(filepath.Join(r.dir, "task"))
+	if err != nil || len(tasks) == 0 {
+		s.voluntary, s.involuntary = status["voluntary_ctxt_switches"], status["nonvoluntary_ctxt_switches"]
+		return
+	}
+	for _, task := range tasks {
+		taskStatus, err := readStatus(filepath.Join(r.dir, "task", task.Name(), "status"))
+		if err != nil {
+			// the thread has exited
+			continue
+		}
+		s.voluntary += taskStatus["voluntary_ctxt_switches"]
+		s.involuntary += taskStatus["nonvoluntary_ctxt_switches"]
+	}
+}
+
+func (r *processReader) readFDs(s *processStats) {
+	fds, err := os.ReadDir(filepath.Join(r.dir, "fd"))
+	if err == nil {
+		s.openFDs = uint64(len(fds))
+		for _, fd := range fds {
+			if target, err := os.Readlink(filepath.Join(r.dir, "fd", fd.Name())); err == nil && strings.HasPrefix(target, "socket:") {
+				s.sockets++
+			}
+		}
+	}
+	f, err := os.Open(filepath.Join(r.dir, "limits"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "Max open files") {
+			// the soft limit, "unlimited" is reported as 0
+			if fields := strings.Fields(strings.TrimPrefix(line, "Max open files")); len(fields) > 0 {
+				s.maxFDs, _ = strconv.ParseUint(fields[0], 10, 64)
+			}
+			return
+		}
+	}
+}
+
+// read fills the process part of the metric
+func (r *processReader) read(m *RunTimeMetric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, err := r.stats()
+	if err != nil {
+		return err
+	}
+	m.ProcessRSS = int64(s.rss)
+	m.ProcessOpenFDs = int64(s.openFDs)
+	m.ProcessMaxFDs = int64(s.maxFDs)
+	m.ProcessSockets = int64(s.sockets)
+	m.ProcessCPUUserTimeDelta = int64(subtractFloor(s.cpuUser, r.last.cpuUser))
+	m.ProcessCPUSystemTimeDelta = int64(subtractFloor(s.cpuSystem, r.last.cpuSystem))
+	m.ProcessVoluntaryCtxSwitchesDelta = int64(subtractFloor(s.voluntary, r.last.voluntary))
+	m.ProcessInvoluntaryCtxSwitchesDelta = int64(subtractFloor(s.involuntary, r.last.involuntary))
+	r.last = s
+	return nil
+}
+
+// readStatus reads the numeric values of /proc/[pid]/status, the unit such as kB is dropped
+func readStatus(file string) (map[string]uint64, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(string(content), "\n") {
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(line[i+1:])
+		if len(fields) == 0 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			values[line[:i]] = v
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,108 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestProcessReader(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, map[string]string{
+		"stat":   "42 (my app) S 1 42 42 0 -1 4194560 100 0 0 0 100 50 0 0 20 0 8 0 100 1000 200 18446744073709551615\n",
+		"status": "Name:\tmy app\nVmRSS:\t    2048 kB\nvoluntary_ctxt_switches:\t1\nnonvoluntary_ctxt_switches:\t1\n",
+		"limits": "Limit                     Soft Limit           Hard Limit           Units\n" +
+			"Max open files            1024                 4096                 files\n",
+		"task/42/status": "voluntary_ctxt_switches:\t10\nnonvoluntary_ctxt_switches:\t2\n",
+		"task/43/status": "voluntary_ctxt_switches:\t5\nnonvoluntary_ctxt_switches:\t1\n",
+	})
+	if err := os.MkdirAll(filepath.Join(dir, "fd"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for fd, target := range map[string]string{"0": "/dev/null", "3": "socket:[1234]", "4": "socket:[1235]"} {
+		if err := os.Symlink(target, filepath.Join(dir, "fd", fd)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := newProcessReader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFixture(t, dir, map[string]string{
+		"stat":           "42 (my app) S 1 42 42 0 -1 4194560 100 0 0 0 150 70 0 0 20 0 8 0 100 1000 200 18446744073709551615\n",
+		"task/42/status": "voluntary_ctxt_switches:\t20\nnonvoluntary_ctxt_switches:\t4\n",
+	})
+	var m RunTimeMetric
+	if err := r.read(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.ProcessRSS != 2048*1024 || m.ProcessOpenFDs != 3 || m.ProcessSockets != 2 || m.ProcessMaxFDs != 1024 {
+		t.Errorf("unexpected process metrics %+v", m)
+	}
+	// 50 and 20 clock ticks
+	if m.ProcessCPUUserTimeDelta != 5e8 || m.ProcessCPUSystemTimeDelta != 2e8 {
+		t.Errorf("unexpected cpu time %d %d", m.ProcessCPUUserTimeDelta, m.ProcessCPUSystemTimeDelta)
+	}
+	if m.ProcessVoluntaryCtxSwitchesDelta != 10 || m.ProcessInvoluntaryCtxSwitchesDelta != 2 {
+		t.Errorf("unexpected context switches %d %d", m.ProcessVoluntaryCtxSwitchesDelta, m.ProcessInvoluntaryCtxSwitchesDelta)
+	}
+}
+
+func TestProcessReader_Self(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("proc filesystem is only available on linux")
+	}
+	r, err := newProcessReader(procSelf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m RunTimeMetric
+	if err := r.read(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.ProcessRSS <= 0 || m.ProcessOpenFDs <= 0 {
+		t.Errorf("unexpected process metrics %+v", m)
+	}
+}
+
+func TestProcessReader_Missing(t *testing.T) {
+	if _, err := newProcessReader(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error without the proc files")
+	}
+}
+
+type recordMetricsReporter chan RunTimeMetric
+
+func (r recordMetricsReporter) SendMetrics(m RunTimeMetric) {
+	r <- m
+}
+
+func TestMetricCollector_HostMetricsDisabled(t *testing.T) {
+	c := &MetricCollector{
+		reporter: make(recordMetricsReporter, 1),
+		runtime:  newRuntimeMetricsReader(),
+	}
+	WithHostMetrics(false)(c)
+	c.collectMeter()
+	m := <-c.reporter.(recordMetricsReporter)
+	if m.Host || m.CpuUsedRate != 0 || m.MemUsedRate != 0 {
+		t.Errorf("unexpected host metrics %+v", m)
+	}
+}
@@ -0,0 +1,230 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"math"
+	"runtime/metrics"
+	"sort"
+	"sync"
+)
+
+const (
+	runtimeHeapObjectsBytes = "/memory/classes/heap/objects:bytes"
+	runtimeHeapStacksBytes  = "/memory/classes/heap/stacks:bytes"
+	runtimeHeapObjects      = "/gc/heap/objects:objects"
+	runtimeHeapGoal         = "/gc/heap/goal:bytes"
+	runtimeGCCycles         = "/gc/cycles/total:gc-cycles"
+	runtimeGoroutines       = "/sched/goroutines:goroutines"
+	runtimeSchedLatencies   = "/sched/latencies:seconds"
+	runtimeMutexWait        = "/sync/mutex/wait/total:seconds"
+	runtimeCGOCalls         = "/cgo/go-to-c-calls:calls"
+	runtimeMemoryLimit      = "/gc/gomemlimit:bytes"
+)
+
+var (
+	// /gc/pauses:seconds is deprecated since Go 1.22
+	runtimeGCPauses = []string{"/sched/pauses/total/gc:seconds", "/gc/pauses:seconds"}
+	// the lower boundaries(NS) of the reported scheduler latency histogram
+	schedLatencyBuckets = []float64{0, 1e4, 1e5, 1e6, 1e7, 1e8}
+)
+
+// runtimeMetricsReader reads the Go runtime metrics without stopping the world,
+// and keeps the previous readings to report the per-interval deltas.
+type runtimeMetricsReader struct {
+	mu      sync.Mutex
+	samples []metrics.Sample
+	// the position of the supported metrics in samples
+	index map[string]int
+
+	lastGCCount      uint64
+	lastGCPauseTime  int64
+	lastCGOCalls     uint64
+	lastMutexWait    float64
+	lastGCPauses     []uint64
+	lastSchedLatency []uint64
+}
+
+func newRuntimeMetricsReader() *runtimeMetricsReader {
+	supported := make(map[string]struct{})
+	for _, d := range metrics.All() {
+		supported[d.Name] = struct{}{}
+	}
+	names := []string{runtimeHeapObjectsBytes, runtimeHeapStacksBytes, runtimeHeapObjects, runtimeHeapGoal,
+		runtimeGCCycles, runtimeGoroutines, runtimeSchedLatencies, runtimeMutexWait, runtimeCGOCalls, runtimeMemoryLimit}
+	for _, name := range runtimeGCPauses {
+		if _, ok := supported[name]; ok {
+			names = append(names, name)
+			break
+		}
+	}
+	r := &runtimeMetricsReader{index: make(map[string]int)}
+	for _, name := range names {
+		if _, ok := supported[name]; !ok {
+			continue
+		}
+		r.index[name] = len(r.samples)
+		r.samples = append(r.samples, metrics.Sample{Name: name})
+	}
+	// seed the previous readings, so the first deltas are since the reader is created instead of the process start
+	r.read(&RunTimeMetric{})
+	return r
+}
+
+func (r *runtimeMetricsReader) uint64(name string) uint64 {
+	if i, ok := r.index[name]; ok && r.samples[i].Value.Kind() == metrics.KindUint64 {
+		return r.samples[i].Value.Uint64()
+	}
+	return 0
+}
+
+func (r *runtimeMetricsReader) float64(name string) float64 {
+	if i, ok := r.index[name]; ok && r.samples[i].Value.Kind() == metrics.KindFloat64 {
+		return r.samples[i].Value.Float64()
+	}
+	return 0
+}
+
+func (r *runtimeMetricsReader) histogram(names ...string) *metrics.Float64Histogram {
+	for _, name := range names {
+		if i, ok := r.index[name]; ok && r.samples[i].Value.Kind() == metrics.KindFloat64Histogram {
+			return r.samples[i].Value.Float64Histogram()
+		}
+	}
+	return nil
+}
+
+// read fills the runtime part of the metric
+func (r *runtimeMetricsReader) read(m *RunTimeMetric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	metrics.Read(r.samples)
+	pauses := r.histogram(runtimeGCPauses...)
+
+	gcCount := r.uint64(runtimeGCCycles)
+	cgoCalls := r.uint64(runtimeCGOCalls)
+	mutexWait := r.float64(runtimeMutexWait)
+
+	m.HeapAlloc = int64(r.uint64(runtimeHeapObjectsBytes))
+	m.StackInUse = int64(r.uint64(runtimeHeapStacksBytes))
+	m.GCCount = int64(gcCount)
+	if pauses != nil {
+		m.GCPauseTime = int64(histogramSum(pauses.Counts, pauses.Buckets) * 1e9)
+	}
+	m.GoroutineNum = int64(r.uint64(runtimeGoroutines))
+	m.HeapObjects = int64(r.uint64(runtimeHeapObjects))
+	m.NextGC = int64(r.uint64(runtimeHeapGoal))
+	m.MemoryLimit = int64(r.uint64(runtimeMemoryLimit))
+
+	m.GCCountDelta = int64(gcCount - r.lastGCCount)
+	m.GCPauseTimeDelta = m.GCPauseTime - r.lastGCPauseTime
+	m.CGOCallsDelta = int64(cgoCalls - r.lastCGOCalls)
+	m.MutexWaitTimeDelta = int64((mutexWait - r.lastMutexWait) * 1e9)
+	r.lastGCCount, r.lastGCPauseTime, r.lastCGOCalls, r.lastMutexWait = gcCount, m.GCPauseTime, cgoCalls, mutexWait
+
+	if pauses != nil {
+		var delta []uint64
+		delta, r.lastGCPauses = histogramDelta(pauses, r.lastGCPauses)
+		m.GCPauseP50 = int64(histogramQuantile(delta, pauses.Buckets, 0.5) * 1e9)
+		m.GCPauseP90 = int64(histogramQuantile(delta, pauses.Buckets, 0.9) * 1e9)
+		m.GCPauseP99 = int64(histogramQuantile(delta, pauses.Buckets, 0.99) * 1e9)
+	}
+	if h := r.histogram(runtimeSchedLatencies); h != nil {
+		var delta []uint64
+		delta, r.lastSchedLatency = histogramDelta(h, r.lastSchedLatency)
+		m.SchedLatency = downsampleHistogram(delta, h.Buckets, 1e9, schedLatencyBuckets)
+	}
+}
+
+// histogramDelta returns the counts since the previous reading, and a copy of the current counts
+func histogramDelta(h *metrics.Float64Histogram, previous []uint64) (delta, current []uint64) {
+	current = append([]uint64(nil), h.Counts...)
+	delta = make([]uint64, len(current))
+	for i, c := range current {
+		if len(previous) == len(current) {
+			c -= previous[i]
+		}
+		delta[i] = c
+	}
+	return delta, current
+}
+
+// histogramSum estimates the total of the runtime histogram by the midpoints of the buckets,
+// the finite boundary is used for the buckets open on one side
+func histogramSum(counts []uint64, buckets []float64) float64 {
+	var sum float64
+	for i, c := range counts {
+		if c == 0 {
+			continue
+		}
+		lower, upper := buckets[i], buckets[i+1]
+		switch {
+		case math.IsInf(lower, -1):
+			sum += float64(c) * upper
+		case math.IsInf(upper, 1):
+			sum += float64(c) * lower
+		default:
+			sum += float64(c) * (lower + upper) / 2
+		}
+	}
+	return sum
+}
+
+// histogramQuantile returns the upper boundary of the bucket where the quantile falls into,
+// buckets are the boundaries of the runtime histogram, len(buckets) == len(counts)+1
+func histogramQuantile(counts []uint64, buckets []float64, q float64) float64 {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	var cumulative uint64
+	for i, c := range counts {
+		cumulative += c
+		if cumulative >= rank {
+			if math.IsInf(buckets[i+1], 1) {
+				return buckets[i]
+			}
+			return buckets[i+1]
+		}
+	}
+	return buckets[len(buckets)-1]
+}
+
+// downsampleHistogram merges the runtime histogram into the target buckets by the lower boundaries,
+// the runtime boundaries are multiplied by scale before comparing with the target ones.
+func downsampleHistogram(counts []uint64, buckets []float64, scale float64, targets []float64) []MeterBucket {
+	result := make([]MeterBucket, len(targets))
+	for i, b := range targets {
+		result[i].Bucket = b
+	}
+	for i, c := range counts {
+		lower := buckets[i] * scale
+		j := sort.Search(len(targets), func(j int) bool {
+			return targets[j] > lower
+		}) - 1
+		if j < 0 {
+			j = 0
+		}
+		result[j].Count += int64(c)
+	}
+	return result
+}
@@ -0,0 +1,119 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"math"
+	"reflect"
+	"runtime"
+	"runtime/metrics"
+	"testing"
+)
+
+func TestRuntimeMetricsReader(t *testing.T) {
+	runtime.GC()
+	r := newRuntimeMetricsReader()
+	var first RunTimeMetric
+	r.read(&first)
+	if first.GCCount <= 0 || first.GCCountDelta >= first.GCCount || first.GCPauseTimeDelta >= first.GCPauseTime {
+		t.Errorf("the first deltas should be since the reader is created, gc count %d delta %d, gc pause %d delta %d",
+			first.GCCount, first.GCCountDelta, first.GCPauseTime, first.GCPauseTimeDelta)
+	}
+	if first.HeapAlloc <= 0 || first.StackInUse <= 0 || first.GoroutineNum <= 0 || first.HeapObjects <= 0 || first.NextGC <= 0 {
+		t.Errorf("unexpected runtime metric %+v", first)
+	}
+	if len(first.SchedLatency) != len(schedLatencyBuckets) {
+		t.Errorf("the expected buckets of scheduler latency are %v, current are %+v", schedLatencyBuckets, first.SchedLatency)
+	}
+
+	runtime.GC()
+	var second RunTimeMetric
+	r.read(&second)
+	if second.GCCount < first.GCCount+1 {
+		t.Errorf("the expected gc count is at least %d, current is %d", first.GCCount+1, second.GCCount)
+	}
+	if second.GCCountDelta != second.GCCount-first.GCCount {
+		t.Errorf("the expected gc count delta is %d, current is %d", second.GCCount-first.GCCount, second.GCCountDelta)
+	}
+	if second.GCPauseTimeDelta != second.GCPauseTime-first.GCPauseTime {
+		t.Errorf("the expected gc pause delta is %d, current is %d", second.GCPauseTime-first.GCPauseTime, second.GCPauseTimeDelta)
+	}
+	if second.GCPauseP50 <= 0 || second.GCPauseP50 > second.GCPauseP99 {
+		t.Errorf("unexpected gc pause percentiles %d %d", second.GCPauseP50, second.GCPauseP99)
+	}
+}
+
+func TestHistogramDelta(t *testing.T) {
+	h := &metrics.Float64Histogram{Counts: []uint64{3, 5}, Buckets: []float64{0, 1, 2}}
+	delta, current := histogramDelta(h, nil)
+	if !reflect.DeepEqual(delta, []uint64{3, 5}) || !reflect.DeepEqual(current, []uint64{3, 5}) {
+		t.Errorf("unexpected delta %v and current %v", delta, current)
+	}
+	h.Counts = []uint64{4, 9}
+	delta, _ = histogramDelta(h, current)
+	if !reflect.DeepEqual(delta, []uint64{1, 4}) {
+		t.Errorf("the expected delta is [1 4], current is %v", delta)
+	}
+}
+
+func TestHistogramSum(t *testing.T) {
+	buckets := []float64{math.Inf(-1), 0, 2, 4, math.Inf(1)}
+	counts := []uint64{1, 3, 2, 1}
+	// 1*0 + 3*1 + 2*3 + 1*4
+	if got := histogramSum(counts, buckets); got != 13 {
+		t.Errorf("the expected sum is 13, current is %v", got)
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	buckets := []float64{0, 1, 2, 4, math.Inf(1)}
+	counts := []uint64{50, 40, 9, 1}
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0.5, 1},
+		{0.9, 2},
+		{0.99, 4},
+		{1, 4},
+	}
+	for _, tt := range tests {
+		if got := histogramQuantile(counts, buckets, tt.q); got != tt.want {
+			t.Errorf("the expected quantile %v is %v, current is %v", tt.q, tt.want, got)
+		}
+	}
+	if got := histogramQuantile([]uint64{0, 0, 0, 0}, buckets, 0.5); got != 0 {
+		t.Errorf("the expected quantile of empty histogram is 0, current is %v", got)
+	}
+}
+
+func TestDownsampleHistogram(t *testing.T) {
+	buckets := []float64{math.Inf(-1), 0, 1e-6, 1e-5, 1e-3, math.Inf(1)}
+	counts := []uint64{1, 2, 3, 4, 5}
+	got := downsampleHistogram(counts, buckets, 1e9, schedLatencyBuckets)
+	want := []MeterBucket{
+		{Bucket: 0, Count: 6},
+		{Bucket: 1e4, Count: 4},
+		{Bucket: 1e5, Count: 0},
+		{Bucket: 1e6, Count: 5},
+		{Bucket: 1e7, Count: 0},
+		{Bucket: 1e8, Count: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("the expected buckets are %+v, current are %+v", want, got)
+	}
+}
@@ -0,0 +1,200 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"bufio"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// NewFanOutMetricsReporter returns a MetricsReporter sending the metrics to all the reporters,
+// so one MetricCollector can feed multiple sinks.
+func NewFanOutMetricsReporter(reporters ...MetricsReporter) MetricsReporter {
+	return fanOutMetricsReporter(reporters)
+}
+
+type fanOutMetricsReporter []MetricsReporter
+
+func (f fanOutMetricsReporter) SendMetrics(m RunTimeMetric) {
+	for _, r := range f {
+		r.SendMetrics(m)
+	}
+}
+
+// PrometheusHandler is a MetricsReporter keeping the latest collected metrics,
+// and an http.Handler exposing them in the Prometheus text format.
+// The delta meters are accumulated since the handler is created, as the Prometheus counters
+// and histograms are cumulative, the accumulated meters other than the histograms are counters
+// named without the _delta suffix.
+type PrometheusHandler struct {
+	mu sync.Mutex
+	// the accumulated delta meters keyed by the name and the labels, in the order of their first reports
+	totals     map[string]*MeterValue
+	totalsKeys []string
+	latest     atomic.Value
+}
+
+// NewPrometheusHandler creates a PrometheusHandler, it exposes nothing until the metrics are collected
+func NewPrometheusHandler() *PrometheusHandler {
+	return &PrometheusHandler{totals: make(map[string]*MeterValue)}
+}
+
+// SendMetrics keeps the metrics to expose, and accumulates the delta meters
+func (h *PrometheusHandler) SendMetrics(m RunTimeMetric) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var values []MeterValue
+	for _, v := range m.MeterValues() {
+		if !v.Delta {
+			values = append(values, v)
+			continue
+		}
+		h.accumulate(v)
+	}
+	for _, key := range h.totalsKeys {
+		total := *h.totals[key]
+		total.Buckets = append([]MeterBucket(nil), total.Buckets...)
+		values = append(values, total)
+	}
+	h.latest.Store(values)
+}
+
+// accumulate adds the delta meter to its total
+func (h *PrometheusHandler) accumulate(v MeterValue) {
+	key := (&meterIdentity{name: v.Name, labels: v.Labels}).id()
+	total, ok := h.totals[key]
+	if !ok {
+		total = &MeterValue{Name: v.Name, Labels: v.Labels, Type: v.Type}
+		if v.Type != MeterTypeHistogram {
+			total.Type = MeterTypeCounter
+			total.Name = strings.TrimSuffix(v.Name, "_delta")
+		}
+		h.totals[key] = total
+		h.totalsKeys = append(h.totalsKeys, key)
+	}
+	total.Value += v.Value
+	if len(total.Buckets) != len(v.Buckets) {
+		// the buckets are expected to be unchanged, the total restarts otherwise
+		total.Buckets = make([]MeterBucket, len(v.Buckets))
+		for i, b := range v.Buckets {
+			total.Buckets[i].Bucket = b.Bucket
+		}
+	}
+	for i, b := range v.Buckets {
+		total.Buckets[i].Count += b.Count
+	}
+}
+
+// ServeHTTP writes the latest metrics in the Prometheus text format
+func (h *PrometheusHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	values, _ := h.latest.Load().([]MeterValue)
+	bw := bufio.NewWriter(w)
+	writePrometheus(bw, values)
+	_ = bw.Flush()
+}
+
+// writePrometheus writes the meters grouped by the name, the type line is written once for each name
+func writePrometheus(w *bufio.Writer, values []MeterValue) {
+	groups := make(map[string][]MeterValue)
+	var names []string
+	for _, v := range values {
+		name := prometheusName(v.Name)
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], v)
+	}
+	for _, name := range names {
+		group := groups[name]
+		w.WriteString("# TYPE " + name + " " + prometheusType(group[0].Type) + "\n")
+		for _, v := range group {
+			if v.Type != MeterTypeHistogram {
+				w.WriteString(name + prometheusLabels(v.Labels, "") + " " + prometheusFloat(v.Value) + "\n")
+				continue
+			}
+			// the buckets of the meter are the lower boundaries, the ones of Prometheus are the cumulative upper boundaries
+			var cumulative int64
+			for i, b := range v.Buckets {
+				cumulative += b.Count
+				le := math.Inf(1)
+				if i+1 < len(v.Buckets) {
+					le = v.Buckets[i+1].Bucket
+				}
+				w.WriteString(name + "_bucket" + prometheusLabels(v.Labels, prometheusFloat(le)) + " " + strconv.FormatInt(cumulative, 10) + "\n")
+			}
+			w.WriteString(name + "_sum" + prometheusLabels(v.Labels, "") + " " + prometheusFloat(v.Value) + "\n")
+			w.WriteString(name + "_count" + prometheusLabels(v.Labels, "") + " " + strconv.FormatInt(cumulative, 10) + "\n")
+		}
+	}
+}
+
+func prometheusType(t MeterType) string {
+	switch t {
+	case MeterTypeCounter:
+		return "counter"
+	case MeterTypeHistogram:
+		return "histogram"
+	default:
+		return "gauge"
+	}
+}
+
+// prometheusName replaces the characters not allowed in the metric and label names
+func prometheusName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func prometheusLabels(labels []MeterLabel, le string) string {
+	if len(labels) == 0 && le == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels)+1)
+	for _, l := range labels {
+		pairs = append(pairs, prometheusName(l.Name)+`="`+prometheusLabelEscaper.Replace(l.Value)+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func prometheusFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,152 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package go2sky
+
+import (
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	h := NewPrometheusHandler()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected nothing before the metrics are collected, current is %q", rec.Body.String())
+	}
+
+	h.SendMetrics(RunTimeMetric{
+		GoroutineNum: 10,
+		Meters: []MeterValue{
+			{Type: MeterTypeCounter, Name: "requests.total", Labels: []MeterLabel{{Name: "method", Value: `G"ET`}}, Value: 3},
+			{Type: MeterTypeCounter, Name: "requests.total", Labels: []MeterLabel{{Name: "method", Value: "POST"}}, Value: 1},
+			{Type: MeterTypeHistogram, Name: "latency", Value: 16, Buckets: []MeterBucket{
+				{Bucket: math.Inf(-1), Count: 1},
+				{Bucket: 0, Count: 2},
+				{Bucket: 10, Count: 1},
+			}},
+		},
+	})
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != prometheusContentType {
+		t.Errorf("unexpected content type %s", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE instance_golang_live_goroutines_num gauge\ninstance_golang_live_goroutines_num 10\n",
+		"# TYPE requests_total counter\nrequests_total{method=\"G\\\"ET\"} 3\nrequests_total{method=\"POST\"} 1\n",
+		"# TYPE latency histogram\nlatency_bucket{le=\"0\"} 1\nlatency_bucket{le=\"10\"} 3\nlatency_bucket{le=\"+Inf\"} 4\nlatency_sum 16\nlatency_count 4\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the exposition:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, InstanceCPUUsedRate) {
+		t.Errorf("unexpected host metrics in the exposition:\n%s", body)
+	}
+}
+
+func TestPrometheusHandler_Delta(t *testing.T) {
+	h := NewPrometheusHandler()
+	report := func(calls, sum float64, counts ...int64) string {
+		buckets := []MeterBucket{{Bucket: 0}, {Bucket: 10}}
+		for i, c := range counts {
+			buckets[i].Count = c
+		}
+		h.SendMetrics(RunTimeMetric{
+			SchedLatency: []MeterBucket{{Bucket: 0, Count: counts[0]}},
+			Meters: []MeterValue{
+				{Type: MeterTypeGauge, Name: "calls_delta", Value: calls, Delta: true},
+				{Type: MeterTypeHistogram, Name: "latency", Value: sum, Buckets: buckets, Delta: true},
+			},
+		})
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		return rec.Body.String()
+	}
+
+	report(2, 15, 1, 1)
+	body := report(1, 20, 0, 1)
+	for _, want := range []string{
+		"# TYPE calls counter\ncalls 3\n",
+		"# TYPE latency histogram\nlatency_bucket{le=\"10\"} 1\nlatency_bucket{le=\"+Inf\"} 3\nlatency_sum 35\nlatency_count 3\n",
+		"instance_golang_sched_latency_count 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the exposition:\n%s", want, body)
+		}
+	}
+}
+
+func TestFanOutMetricsReporter(t *testing.T) {
+	first, second := make(recordMetricsReporter, 1), make(recordMetricsReporter, 1)
+	NewFanOutMetricsReporter(first, second).SendMetrics(RunTimeMetric{Time: 1})
+	if (<-first).Time != 1 || (<-second).Time != 1 {
+		t.Error("expected the metrics sent to all the reporters")
+	}
+}
+
+func TestRunTimeMetric_MeterValues(t *testing.T) {
+	m := RunTimeMetric{
+		Host:         true,
+		CpuUsedRate:  50,
+		SchedLatency: []MeterBucket{{Bucket: 0, Count: 1}},
+		Meters:       []MeterValue{{Type: MeterTypeCounter, Name: "requests"}},
+	}
+	values := m.MeterValues()
+	names := make(map[string]MeterValue)
+	for _, v := range values {
+		names[v.Name] = v
+	}
+	if names[InstanceCPUUsedRate].Value != 50 || names[InstanceGolangSchedLatency].Type != MeterTypeHistogram {
+		t.Errorf("unexpected meter values %+v", values)
+	}
+	if _, ok := names[InstanceContainerCPULimit]; ok {
+		t.Errorf("unexpected container meters %+v", values)
+	}
+	if values[len(values)-1].Name != "requests" {
+		t.Errorf("expected the application meters at last, current is %+v", values[len(values)-1])
+	}
+}
+
+func TestRunTimeMetric_MeterValuesContainerMemory(t *testing.T) {
+	tests := []struct {
+		name   string
+		metric RunTimeMetric
+		expect bool
+	}{
+		{name: "limited", metric: RunTimeMetric{Container: true, ContainerMemoryLimit: 1024, MemUsedRate: 25}, expect: true},
+		{name: "unlimited", metric: RunTimeMetric{Container: true}, expect: false},
+		{name: "unlimited from host", metric: RunTimeMetric{Container: true, HostMemory: true, MemUsedRate: 40}, expect: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reported := false
+			for _, v := range tt.metric.MeterValues() {
+				if v.Name == InstanceMemUsedRate {
+					reported = v.Value == tt.metric.MemUsedRate
+				}
+			}
+			if reported != tt.expect {
+				t.Errorf("the expected memory used rate reported %t, current is %t", tt.expect, reported)
+			}
+		})
+	}
+}
@@ -23,16 +23,27 @@ import (
 )
 
 type NoopSpan struct {
+	// not nil when the endpoint metrics are enabled and the span is an entry or exit
+	measured *measuredSpan
 }
 
-func (*NoopSpan) SetOperationName(string) {
+func (n *NoopSpan) SetOperationName(name string) {
+	if n.measured != nil {
+		n.measured.operationName = name
+	}
 }
 
-func (*NoopSpan) GetOperationName() string {
+func (n *NoopSpan) GetOperationName() string {
+	if n.measured != nil {
+		return n.measured.operationName
+	}
 	return ""
 }
 
-func (*NoopSpan) SetPeer(string) {
+func (n *NoopSpan) SetPeer(peer string) {
+	if n.measured != nil {
+		n.measured.peer = peer
+	}
 }
 
 func (*NoopSpan) SetSpanLayer(agentv3.SpanLayer) {
@@ -47,18 +58,24 @@ func (*NoopSpan) Tag(Tag, string) {
 func (*NoopSpan) Log(time.Time, ...string) {
 }
 
-func (*NoopSpan) Error(time.Time, ...string) {
+func (n *NoopSpan) Error(time.Time, ...string) {
+	if n.measured != nil {
+		n.measured.isError = true
+	}
 }
 
-func (*NoopSpan) End() {
+func (n *NoopSpan) End() {
+	if n.measured != nil {
+		n.measured.end()
+	}
 }
 
-func (*NoopSpan) IsEntry() bool {
-	return false
+func (n *NoopSpan) IsEntry() bool {
+	return n.measured != nil && n.measured.spanType == SpanTypeEntry
 }
 
-func (*NoopSpan) IsExit() bool {
-	return false
+func (n *NoopSpan) IsExit() bool {
+	return n.measured != nil && n.measured.spanType == SpanTypeExit
 }
 
 func (*NoopSpan) IsValid() bool {
@@ -0,0 +1,67 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package chi
+
+import (
+	"net/http"
+
+	"github.com/SkyAPM/go2sky"
+	go2skyhttp "github.com/SkyAPM/go2sky/plugins/http"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Middleware returns the chi middleware tracing the requests, it should be registered by chi.Router.Use
+// to name the operations by the route patterns, which are resolved after the routing.
+// The chi routers resolve the patterns after the middlewares, so the span is named by the raw path until the next
+// handlers return, the logs and the operations read from the span in the handlers carry the raw path.
+// The span is marked as error when the next handlers panic, and the panic is propagated to the recoverer middleware.
+func Middleware(tracer *go2sky.Tracer, options ...go2skyhttp.ServerOption) (func(http.Handler) http.Handler, error) {
+	server, err := go2skyhttp.NewServer(tracer, options...)
+	if err != nil {
+		return nil, err
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span, traced := server.Start(r, "")
+			if span == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				if rctx := chi.RouteContext(r.Context()); rctx != nil {
+					span.SetRoute(rctx.RoutePattern())
+				}
+				status := ww.Status()
+				if p := recover(); p != nil {
+					span.Panic(p)
+					if status == 0 {
+						status = http.StatusInternalServerError
+					}
+					span.End(status, int64(ww.BytesWritten()))
+					panic(p)
+				}
+				if status == 0 {
+					status = http.StatusOK
+				}
+				span.End(status, int64(ww.BytesWritten()))
+			}()
+			next.ServeHTTP(ww, traced)
+		})
+	}, nil
+}
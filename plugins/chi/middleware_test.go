@@ -0,0 +1,110 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package chi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestMiddleware(t *testing.T) {
+	reporter := testutil.NewReporter(1)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracing, err := Middleware(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer, tracing)
+	router.Route("/users", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			if go2sky.ActiveSpan(r.Context()) == nil {
+				t.Error("the request should carry the span")
+			}
+			_, _ = w.Write([]byte("user"))
+		})
+	})
+	router.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		target string
+		name   string
+		status string
+		size   string
+		error  bool
+	}{
+		{target: "/users/1", name: "/GET/users/{id}", status: "200", size: "4"},
+		{target: "/panic", name: "/GET/panic", status: "500", size: "0", error: true},
+		{target: "/absent", name: "/GET/absent", status: "404", size: "19", error: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+			select {
+			case segment := <-reporter.Segments:
+				span := segment[0]
+				if span.OperationName() != tt.name || testutil.TagValue(span, go2sky.TagStatusCode) != tt.status ||
+					testutil.TagValue(span, go2sky.TagHTTPResponseBodySize) != tt.size || span.IsError() != tt.error {
+					t.Errorf("unexpected span %s: error %t, tags %v", span.OperationName(), span.IsError(), span.Tags())
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("the segment is not reported")
+			}
+		})
+	}
+}
+
+func TestMiddleware_MountedRoute(t *testing.T) {
+	reporter := testutil.NewReporter(1)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracing, err := Middleware(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handling string
+	users := chi.NewRouter()
+	users.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handling = go2sky.ActiveSpan(r.Context()).(go2sky.ReportedSpan).OperationName()
+	})
+	router := chi.NewRouter()
+	router.Use(tracing)
+	router.Mount("/orgs/{org}/users", users)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orgs/acme/users/1", nil))
+	// the pattern is resolved after the middleware, the span carries the raw path in the handler
+	if handling != "/GET/orgs/acme/users/1" {
+		t.Errorf("the expected operation in the handler is the raw path, current is %s", handling)
+	}
+	if name := reporter.Segment(t)[0].OperationName(); name != "/GET/orgs/{org}/users/{id}" {
+		t.Errorf("the expected operation is named by the mounted pattern, current is %s", name)
+	}
+}
@@ -0,0 +1,74 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package echo
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SkyAPM/go2sky"
+	go2skyhttp "github.com/SkyAPM/go2sky/plugins/http"
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware returns the echo middleware tracing the requests, the errors returned by the next handlers are logged on the spans,
+// and the status code of the uncommitted response is resolved from the echo.HTTPError as the error handler does.
+// The span is marked as error when the next handlers panic, and the panic is propagated to the recover middleware.
+func Middleware(tracer *go2sky.Tracer, options ...go2skyhttp.ServerOption) (echo.MiddlewareFunc, error) {
+	server, err := go2skyhttp.NewServer(tracer, options...)
+	if err != nil {
+		return nil, err
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			span, r := server.Start(c.Request(), c.Path())
+			if span == nil {
+				return next(c)
+			}
+			c.SetRequest(r)
+			res := c.Response()
+			defer func() {
+				if p := recover(); p != nil {
+					span.Panic(p)
+					status := res.Status
+					if !res.Committed {
+						status = http.StatusInternalServerError
+					}
+					span.End(status, res.Size)
+					panic(p)
+				}
+				status := res.Status
+				if err != nil {
+					span.Error(err)
+					if !res.Committed {
+						status = errorStatus(err)
+					}
+				}
+				span.End(status, res.Size)
+			}()
+			return next(c)
+		}
+	}, nil
+}
+
+func errorStatus(err error) int {
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,114 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package echo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+	"github.com/labstack/echo/v4"
+)
+
+func TestMiddleware(t *testing.T) {
+	reporter := testutil.NewReporter(1)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	middleware, err := Middleware(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.Use(middleware)
+	e.GET("/users/:id", func(c echo.Context) error {
+		if go2sky.ActiveSpan(c.Request().Context()) == nil {
+			t.Error("the request should carry the span")
+		}
+		return c.String(http.StatusOK, "user")
+	})
+	e.GET("/orders/:id", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "order not found")
+	})
+	e.GET("/fail", func(c echo.Context) error {
+		return errors.New("fail")
+	})
+
+	tests := []struct {
+		target string
+		name   string
+		status string
+		size   string
+		error  bool
+	}{
+		{target: "/users/1", name: "/GET/users/:id", status: "200", size: "4"},
+		{target: "/orders/1", name: "/GET/orders/:id", status: "404", size: "0", error: true},
+		{target: "/fail", name: "/GET/fail", status: "500", size: "0", error: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if status := strconv.Itoa(w.Code); status != tt.status {
+				t.Fatalf("the expected status %s, current is %s", tt.status, status)
+			}
+			select {
+			case segment := <-reporter.Segments:
+				span := segment[0]
+				if span.OperationName() != tt.name || testutil.TagValue(span, go2sky.TagStatusCode) != tt.status ||
+					testutil.TagValue(span, go2sky.TagHTTPResponseBodySize) != tt.size || span.IsError() != tt.error {
+					t.Errorf("unexpected span %s: error %t, logs %v, tags %v", span.OperationName(), span.IsError(), span.Logs(), span.Tags())
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("the segment is not reported")
+			}
+		})
+	}
+}
+
+func TestMiddleware_Panic(t *testing.T) {
+	reporter := testutil.NewReporter(1)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	middleware, err := Middleware(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := middleware(func(c echo.Context) error {
+		panic("boom")
+	})
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/panic", nil), httptest.NewRecorder())
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("the panic should be propagated, current is %v", p)
+		}
+		span := (<-reporter.Segments)[0]
+		if !span.IsError() || testutil.TagValue(span, go2sky.TagStatusCode) != "500" {
+			t.Errorf("the span should be marked as error, tags %v", span.Tags())
+		}
+	}()
+	_ = handler(c)
+}
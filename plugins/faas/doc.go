@@ -0,0 +1,22 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+/*
+Package faas traces the invocations of the functions deployed on the FaaS platforms.
+The wrapped functions create the entry span of each invocation, and flush the segment of the invocation
+synchronously before returning, as the platforms may freeze the instance afterwards.
+*/
+package faas
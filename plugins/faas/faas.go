@@ -0,0 +1,146 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package faas
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	go2skyhttp "github.com/SkyAPM/go2sky/plugins/http"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+const defaultEventOperationName = "Event"
+
+// coldStart is 1 until the first invocation of the instance
+var coldStart int32 = 1
+
+// invocationIDHeaders are the request headers of the invocation id on the platforms
+var invocationIDHeaders = []string{"Function-Execution-Id", "Ce-Id", "X-Request-Id"}
+
+// Wrap returns the http.Handler tracing the invocations of the function handler by the HTTP server spans
+// of plugins/http in the FaaS layer, the segment is flushed before the wrapped handler returns.
+// The span is marked as error with the stack when the handler panics, and the panic is propagated.
+func Wrap(handler http.Handler, options ...Option) http.Handler {
+	c := newConfig(options)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tracer := c.getTracer()
+		if tracer == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		var serverOptions []go2skyhttp.ServerOption
+		if c.name != "" {
+			serverOptions = append(serverOptions, go2skyhttp.WithServerOperationName(c.name))
+		}
+		server, err := go2skyhttp.NewServer(tracer, serverOptions...)
+		if err != nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		span, traced := server.Start(r, "")
+		if span == nil {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		invocationID := ""
+		for _, header := range invocationIDHeaders {
+			if invocationID = r.Header.Get(header); invocationID != "" {
+				break
+			}
+		}
+		c.decorate(span.Span(), invocationID)
+		defer c.flush(tracer, span.Span())
+		span.Serve(w, traced, handler)
+	})
+}
+
+// WrapEvent returns the function tracing the invocations of the event function fn,
+// the segment is flushed before the wrapped function returns.
+// The SkyWalking context is extracted from the extensions of the events implementing
+// Extensions() map[string]interface{}, and the invocation id is the ID() of the events implementing it,
+// such as the CloudEvents. The span is marked as error if fn returns an error.
+func WrapEvent[E any](fn func(context.Context, E) error, options ...Option) func(context.Context, E) error {
+	c := newConfig(options)
+	return func(ctx context.Context, event E) (err error) {
+		tracer := c.getTracer()
+		if tracer == nil {
+			return fn(ctx, event)
+		}
+		name := c.name
+		if name == "" {
+			name = defaultEventOperationName
+		}
+		span, nCtx, spanErr := tracer.CreateEntrySpan(ctx, name, func(key string) (string, error) {
+			if e, ok := any(event).(interface{ Extensions() map[string]interface{} }); ok {
+				if v, ok := e.Extensions()[key]; ok {
+					return fmt.Sprint(v), nil
+				}
+			}
+			return "", nil
+		})
+		if spanErr != nil {
+			return fn(ctx, event)
+		}
+		invocationID := ""
+		if e, ok := any(event).(interface{ ID() string }); ok {
+			invocationID = e.ID()
+		}
+		c.decorate(span, invocationID)
+
+		defer func() {
+			p := recover()
+			switch {
+			case p != nil:
+				span.Error(time.Now(), "panic", fmt.Sprint(p), "stack", string(debug.Stack()))
+			case err != nil:
+				span.Error(time.Now(), "error", err.Error())
+			}
+			span.End()
+			c.flush(tracer, span)
+			if p != nil {
+				panic(p)
+			}
+		}()
+		return fn(nCtx, event)
+	}
+}
+
+func (c *config) decorate(span go2sky.Span, invocationID string) {
+	span.SetSpanLayer(agentv3.SpanLayer_FAAS)
+	span.Tag(go2sky.TagFaaSColdStart, strconv.FormatBool(atomic.CompareAndSwapInt32(&coldStart, 1, 0)))
+	if invocationID != "" {
+		span.Tag(go2sky.TagFaaSInvocationID, invocationID)
+	}
+	for k, v := range c.extraTags {
+		span.Tag(go2sky.Tag(k), v)
+	}
+}
+
+// flush sends the segment of the invocation before returning, without waiting for the concurrent invocations.
+// It is not bound to the context of the invocation, which may be canceled when the invocation returns.
+func (c *config) flush(tracer *go2sky.Tracer, span go2sky.Span) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.flushTimeout)
+	defer cancel()
+	_ = tracer.FlushSegment(ctx, span)
+}
@@ -0,0 +1,250 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package faas
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+func newTracer(t *testing.T) (*go2sky.Tracer, *testutil.Reporter) {
+	reporter := testutil.NewReporter(10)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tracer, reporter
+}
+
+// flushedSpan returns the span of the segment, which must be sent before the invocation returns
+func flushedSpan(t *testing.T, reporter *testutil.Reporter) go2sky.ReportedSpan {
+	select {
+	case segment := <-reporter.Segments:
+		if len(segment) != 1 {
+			t.Fatalf("the expected 1 span, current is %d", len(segment))
+		}
+		return segment[0]
+	default:
+		t.Fatal("the segment is not flushed before returning")
+	}
+	return nil
+}
+
+func TestWrap(t *testing.T) {
+	tracer, reporter := newTracer(t)
+	tests := []struct {
+		name       string
+		options    []Option
+		header     http.Header
+		handler    http.HandlerFunc
+		expectName string
+		expectCode string
+		expectSize string
+		expectID   string
+		expectErr  bool
+	}{
+		{
+			name:   "ok",
+			header: http.Header{"Function-Execution-Id": {"exec-1"}},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				// the wrapped response writer keeps the http.Flusher of the platform
+				_, _ = w.Write([]byte("hello"))
+				w.(http.Flusher).Flush()
+			},
+			expectName: "/POST/invoke",
+			expectCode: "200",
+			expectSize: "5",
+			expectID:   "exec-1",
+		},
+		{
+			name:       "error status",
+			options:    []Option{WithFunctionName("orders"), WithTag("region", "eu")},
+			header:     http.Header{"Ce-Id": {"event-1"}},
+			handler:    func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) },
+			expectName: "orders",
+			expectCode: "502",
+			expectSize: "0",
+			expectID:   "event-1",
+			expectErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Wrap(tt.handler, append(tt.options, WithTracer(tracer))...)
+			r := httptest.NewRequest(http.MethodPost, "/invoke", nil)
+			r.Header = tt.header
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			span := flushedSpan(t, reporter)
+			if span.OperationName() != tt.expectName || span.SpanType() != agentv3.SpanType_Entry ||
+				span.SpanLayer() != agentv3.SpanLayer_FAAS || span.IsError() != tt.expectErr {
+				t.Errorf("unexpected span %s: type %s, layer %s, error %t", span.OperationName(), span.SpanType(), span.SpanLayer(), span.IsError())
+			}
+			if code := testutil.TagValue(span, go2sky.TagStatusCode); code != tt.expectCode {
+				t.Errorf("the expected status code %s, current is %s", tt.expectCode, code)
+			}
+			if size := testutil.TagValue(span, go2sky.TagHTTPResponseBodySize); size != tt.expectSize {
+				t.Errorf("the expected response size %s, current is %s", tt.expectSize, size)
+			}
+			if id := testutil.TagValue(span, go2sky.TagFaaSInvocationID); id != tt.expectID {
+				t.Errorf("the expected invocation id %s, current is %s", tt.expectID, id)
+			}
+		})
+	}
+}
+
+func TestWrap_ColdStart(t *testing.T) {
+	tracer, reporter := newTracer(t)
+	atomic.StoreInt32(&coldStart, 1)
+	handler := Wrap(http.NotFoundHandler(), WithTracer(tracer))
+	for _, expect := range []string{"true", "false"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		if coldStart := testutil.TagValue(flushedSpan(t, reporter), go2sky.TagFaaSColdStart); coldStart != expect {
+			t.Errorf("the expected cold start %s, current is %s", expect, coldStart)
+		}
+	}
+}
+
+func TestWrap_Panic(t *testing.T) {
+	tracer, reporter := newTracer(t)
+	handler := Wrap(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}), WithTracer(tracer))
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("the panic should be propagated, current is %v", p)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	span := flushedSpan(t, reporter)
+	if !span.IsError() || testutil.TagValue(span, go2sky.TagStatusCode) != "500" {
+		t.Errorf("the span should be marked as error with 500, current is %t %s", span.IsError(), testutil.TagValue(span, go2sky.TagStatusCode))
+	}
+}
+
+type event struct {
+	id         string
+	extensions map[string]interface{}
+}
+
+func (e event) ID() string                         { return e.id }
+func (e event) Extensions() map[string]interface{} { return e.extensions }
+
+func TestWrapEvent(t *testing.T) {
+	tracer, reporter := newTracer(t)
+	errFake := errors.New("fake error")
+
+	// the context of the producer is carried in the extensions
+	extensions := make(map[string]interface{})
+	span, ctx, err := tracer.CreateLocalSpan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exit, err := tracer.CreateExitSpan(ctx, "send", "broker", func(key, value string) error {
+		extensions[key] = value
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exit.End()
+	span.End()
+	select {
+	case <-reporter.Segments:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the producer segment is not reported")
+	}
+
+	fn := WrapEvent(func(ctx context.Context, e event) error {
+		if go2sky.TraceID(ctx) == go2sky.EmptyTraceID {
+			t.Error("the context should carry the span")
+		}
+		return errFake
+	}, WithTracer(tracer))
+	if err := fn(context.Background(), event{id: "event-1", extensions: extensions}); err != errFake {
+		t.Errorf("the error should be returned, current is %v", err)
+	}
+
+	entry := flushedSpan(t, reporter)
+	if entry.OperationName() != defaultEventOperationName || !entry.IsError() || testutil.TagValue(entry, go2sky.TagFaaSInvocationID) != "event-1" {
+		t.Errorf("unexpected span %s: error %t, tags %v", entry.OperationName(), entry.IsError(), entry.Tags())
+	}
+	if len(entry.Refs()) != 1 || entry.Refs()[0].TraceID != go2sky.TraceID(ctx) {
+		t.Errorf("the span should refer to the producer, current refs are %v", entry.Refs())
+	}
+}
+
+// gateReporter blocks sending the segments of the slow invocations until the gate is open
+type gateReporter struct {
+	*testutil.Reporter
+	gate chan struct{}
+}
+
+func (r *gateReporter) Send(spans []go2sky.ReportedSpan) {
+	if spans[len(spans)-1].OperationName() == "/GET/slow" {
+		<-r.gate
+	}
+	r.Reporter.Send(spans)
+}
+
+func TestWrap_ConcurrentFlush(t *testing.T) {
+	reporter := &gateReporter{Reporter: testutil.NewReporter(10), gate: make(chan struct{})}
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := Wrap(http.NotFoundHandler(), WithTracer(tracer), WithFlushTimeout(5*time.Second))
+	slow := make(chan struct{})
+	go func() {
+		defer close(slow)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+
+	// the invocation flushes its own segment while the slow one is still being sent
+	start := time.Now()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("the invocation should not wait for the others, current is %v", elapsed)
+	}
+	if span := flushedSpan(t, reporter.Reporter); span.OperationName() != "/GET/fast" {
+		t.Errorf("unexpected flushed span %s", span.OperationName())
+	}
+	close(reporter.gate)
+	<-slow
+}
+
+func TestWrapEvent_NoTracer(t *testing.T) {
+	called := false
+	fn := WrapEvent(func(context.Context, string) error {
+		called = true
+		return nil
+	})
+	if err := fn(context.Background(), "event"); err != nil || !called {
+		t.Errorf("the function should be called without tracer, current is %t %v", called, err)
+	}
+}
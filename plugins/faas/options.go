@@ -0,0 +1,94 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package faas
+
+import (
+	"os"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+)
+
+const defaultFlushTimeout = 2 * time.Second
+
+// functionNameEnvs are the environment variables of the function name on the platforms
+var functionNameEnvs = []string{"AWS_LAMBDA_FUNCTION_NAME", "K_SERVICE", "FUNCTION_TARGET"}
+
+type config struct {
+	tracer       *go2sky.Tracer
+	name         string
+	flushTimeout time.Duration
+	extraTags    map[string]string
+}
+
+// Option allows the wrappers to be optionally configured.
+type Option func(*config)
+
+// WithTracer setup the tracer of the spans, the global tracer is used by default.
+func WithTracer(tracer *go2sky.Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// WithFunctionName names the operations of the invocations,
+// the function name in the environment variables of the platform is used by default.
+func WithFunctionName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
+
+// WithFlushTimeout setup the deadline of flushing the segment before returning, 2s by default.
+func WithFlushTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.flushTimeout = timeout
+	}
+}
+
+// WithTag adds extra tag to the spans.
+func WithTag(key string, value string) Option {
+	return func(c *config) {
+		if c.extraTags == nil {
+			c.extraTags = make(map[string]string)
+		}
+		c.extraTags[key] = value
+	}
+}
+
+func newConfig(options []Option) *config {
+	c := &config{flushTimeout: defaultFlushTimeout}
+	for _, env := range functionNameEnvs {
+		if name := os.Getenv(env); name != "" {
+			c.name = name
+			break
+		}
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// getTracer returns the tracer of the invocation, the global tracer is resolved
+// on each invocation as it may be setup after the function is wrapped.
+func (c *config) getTracer() *go2sky.Tracer {
+	if c.tracer != nil {
+		return c.tracer
+	}
+	return go2sky.GetGlobalTracer()
+}
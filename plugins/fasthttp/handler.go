@@ -0,0 +1,79 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package fasthttp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/SkyAPM/go2sky"
+	go2skyhttp "github.com/SkyAPM/go2sky/plugins/http"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+type contextKey struct{}
+
+// Handler returns the fasthttp handler tracing the requests handled by next, Context returns the context carrying the span.
+// The span is marked as error when next panics, and the panic is propagated.
+func Handler(tracer *go2sky.Tracer, next fasthttp.RequestHandler, options ...go2skyhttp.ServerOption) (fasthttp.RequestHandler, error) {
+	server, err := go2skyhttp.NewServer(tracer, options...)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx *fasthttp.RequestCtx) {
+		r := &http.Request{}
+		if err := fasthttpadaptor.ConvertRequest(ctx, r, true); err != nil {
+			next(ctx)
+			return
+		}
+		span, traced := server.Start(r, "")
+		if span == nil {
+			next(ctx)
+			return
+		}
+		ctx.SetUserValue(contextKey{}, traced.Context())
+		defer func() {
+			if p := recover(); p != nil {
+				span.Panic(p)
+				span.End(http.StatusInternalServerError, responseSize(ctx))
+				panic(p)
+			}
+			span.End(ctx.Response.StatusCode(), responseSize(ctx))
+		}()
+		next(ctx)
+	}, nil
+}
+
+// Context returns the context carrying the span of the request, it is the ctx itself if the request is not traced
+func Context(ctx *fasthttp.RequestCtx) context.Context {
+	if c, ok := ctx.UserValue(contextKey{}).(context.Context); ok {
+		return c
+	}
+	return ctx
+}
+
+// responseSize returns the size of the response body, the content length is used for the streamed body
+func responseSize(ctx *fasthttp.RequestCtx) int64 {
+	if ctx.Response.IsBodyStream() {
+		if length := ctx.Response.Header.ContentLength(); length > 0 {
+			return int64(length)
+		}
+		return 0
+	}
+	return int64(len(ctx.Response.Body()))
+}
@@ -0,0 +1,94 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package fasthttp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+	go2skyhttp "github.com/SkyAPM/go2sky/plugins/http"
+	"github.com/SkyAPM/go2sky/propagation"
+	"github.com/valyala/fasthttp"
+)
+
+func TestHandler(t *testing.T) {
+	reporter := testutil.NewReporter(1)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := Handler(tracer, func(ctx *fasthttp.RequestCtx) {
+		if go2sky.ActiveSpan(Context(ctx)) == nil {
+			t.Error("the context should carry the span")
+		}
+		switch string(ctx.Path()) {
+		case "/users/1":
+			ctx.SetBodyString("user")
+		case "/panic":
+			panic("boom")
+		default:
+			ctx.Error("not found", fasthttp.StatusNotFound)
+		}
+	}, go2skyhttp.WithServerTag("framework", "fasthttp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		target string
+		name   string
+		status string
+		size   string
+		error  bool
+	}{
+		{target: "/users/1", name: "/GET/users/1", status: "200", size: "4"},
+		{target: "/orders/1", name: "/GET/orders/1", status: "404", size: "9", error: true},
+		{target: "/panic", name: "/GET/panic", status: "500", error: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			req := &fasthttp.Request{}
+			req.SetRequestURI("http://localhost" + tt.target)
+			req.Header.Set(propagation.Header, "1-MWYyZDRiZjQ3YmY3MTFlYWI3OTRhY2RlNDgwMDExMjI=-MWU3YzIwNGE3YmY3MTFlYWI4NThhY2RlNDgwMDExMjI=-0-c2VydmljZQ==-aW5zdGFuY2U=-L2FwaS92MS9oZWFsdGg=-ZXhhbXBsZS5jb206ODA4MA==")
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Init(req, nil, nil)
+			func() {
+				defer func() {
+					if p := recover(); p != nil && !tt.error {
+						t.Errorf("unexpected panic %v", p)
+					}
+				}()
+				handler(ctx)
+			}()
+			select {
+			case segment := <-reporter.Segments:
+				span := segment[0]
+				if span.OperationName() != tt.name || testutil.TagValue(span, go2sky.TagStatusCode) != tt.status ||
+					(tt.size != "" && testutil.TagValue(span, go2sky.TagHTTPResponseBodySize) != tt.size) || span.IsError() != tt.error ||
+					testutil.TagValue(span, "framework") != "fasthttp" {
+					t.Errorf("unexpected span %s: error %t, tags %v", span.OperationName(), span.IsError(), span.Tags())
+				}
+				if len(span.Refs()) != 1 || span.Context().TraceID != "1f2d4bf47bf711eab794acde48001122" {
+					t.Errorf("the span should refer to the caller, refs %v", span.Refs())
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("the segment is not reported")
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package gin
+
+import (
+	"net/http"
+
+	"github.com/SkyAPM/go2sky"
+	go2skyhttp "github.com/SkyAPM/go2sky/plugins/http"
+	"github.com/gin-gonic/gin"
+)
+
+const componentIDGINHttpServer = 5006
+
+// Middleware returns the gin middleware tracing the requests, the errors of the context are logged on the spans.
+// The span is marked as error when the next handlers panic, and the panic is propagated to the recovery middleware.
+func Middleware(tracer *go2sky.Tracer, options ...go2skyhttp.ServerOption) (gin.HandlerFunc, error) {
+	options = append([]go2skyhttp.ServerOption{go2skyhttp.WithServerComponent(componentIDGINHttpServer)}, options...)
+	server, err := go2skyhttp.NewServer(tracer, options...)
+	if err != nil {
+		return nil, err
+	}
+	return func(c *gin.Context) {
+		span, r := server.Start(c.Request, c.FullPath())
+		if span == nil {
+			c.Next()
+			return
+		}
+		c.Request = r
+		defer func() {
+			if p := recover(); p != nil {
+				span.Panic(p)
+				status := c.Writer.Status()
+				if !c.Writer.Written() {
+					status = http.StatusInternalServerError
+				}
+				span.End(status, responseSize(c))
+				panic(p)
+			}
+			for _, e := range c.Errors {
+				span.Error(e.Err)
+			}
+			span.End(c.Writer.Status(), responseSize(c))
+		}()
+		c.Next()
+	}, nil
+}
+
+func responseSize(c *gin.Context) int64 {
+	if size := c.Writer.Size(); size > 0 {
+		return int64(size)
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package gin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reporter := testutil.NewReporter(1)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	middleware, err := Middleware(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	engine.Use(gin.Recovery(), middleware)
+	engine.GET("/users/:id", func(c *gin.Context) {
+		if go2sky.ActiveSpan(c.Request.Context()) == nil {
+			t.Error("the request should carry the span")
+		}
+		c.String(http.StatusOK, "user")
+	})
+	engine.GET("/orders/:id", func(c *gin.Context) {
+		_ = c.Error(errors.New("order not found"))
+		c.Status(http.StatusNotFound)
+	})
+	engine.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		target string
+		name   string
+		status string
+		size   string
+		logs   int
+	}{
+		{target: "/users/1", name: "/GET/users/:id", status: "200", size: "4"},
+		{target: "/orders/1", name: "/GET/orders/:id", status: "404", size: "0", logs: 1},
+		{target: "/panic", name: "/GET/panic", status: "500", size: "0", logs: 1},
+		{target: "/absent", name: "/GET/absent", status: "404", size: "0", logs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			select {
+			case segment := <-reporter.Segments:
+				span := segment[0]
+				if span.OperationName() != tt.name || span.ComponentID() != componentIDGINHttpServer ||
+					testutil.TagValue(span, go2sky.TagStatusCode) != tt.status || testutil.TagValue(span, go2sky.TagHTTPResponseBodySize) != tt.size ||
+					len(span.Logs()) != tt.logs || span.IsError() != (tt.logs > 0) {
+					t.Errorf("unexpected span %s: component %d, error %t, logs %v, tags %v",
+						span.OperationName(), span.ComponentID(), span.IsError(), span.Logs(), span.Tags())
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("the segment is not reported")
+			}
+		})
+	}
+}
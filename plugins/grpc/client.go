@@ -0,0 +1,133 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package grpc
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/SkyAPM/go2sky"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor creates the exit spans of the unary calls
+func UnaryClientInterceptor(tracer *go2sky.Tracer, options ...Option) (grpc.UnaryClientInterceptor, error) {
+	c, err := newConfig(tracer, options)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if c.ignored(method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		span, spanCtx, err := c.createExitSpan(ctx, method, cc.Target())
+		if err != nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		err = invoker(spanCtx, method, req, reply, cc, opts...)
+		finish(span, err)
+		return err
+	}, nil
+}
+
+// StreamClientInterceptor creates the exit spans of the streams, the span ends when the stream is finished
+// by receiving the status, or the response of a not server streaming call, or the cancellation of the context.
+func StreamClientInterceptor(tracer *go2sky.Tracer, options ...Option) (grpc.StreamClientInterceptor, error) {
+	c, err := newConfig(tracer, options)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if c.ignored(method) {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		span, spanCtx, err := c.createExitSpan(ctx, method, cc.Target())
+		if err != nil {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		cs, err := streamer(spanCtx, desc, cc, method, opts...)
+		if err != nil {
+			finish(span, err)
+			return nil, err
+		}
+		stream := &clientStream{ClientStream: cs, serverStreams: desc.ServerStreams, span: span}
+		go func() {
+			// the stream context is done when the stream is finished or canceled
+			<-cs.Context().Done()
+			if err := ctx.Err(); err != nil {
+				stream.finish(status.FromContextError(err).Err())
+			}
+		}()
+		return stream, nil
+	}, nil
+}
+
+func (c *config) createExitSpan(ctx context.Context, method, target string) (go2sky.Span, context.Context, error) {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	span, err := c.tracer.CreateExitSpan(ctx, c.getOperationName(method), target, func(key, value string) error {
+		md.Set(key, value)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	c.decorate(span, method)
+	return span, metadata.NewOutgoingContext(ctx, md), nil
+}
+
+// clientStream ends the span once when the stream is finished
+type clientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	span          go2sky.Span
+	once          sync.Once
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	case !s.serverStreams:
+		s.finish(nil)
+	}
+	return err
+}
+
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.finish(err)
+	}
+	return md, err
+}
+
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		finish(s.span, err)
+	})
+}
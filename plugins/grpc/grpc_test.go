@@ -0,0 +1,195 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+const (
+	checkMethod = "/grpc.health.v1.Health/Check"
+	watchMethod = "/grpc.health.v1.Health/Watch"
+)
+
+// receive returns the spans of the segments by the span type
+func receive(t *testing.T, reporter *testutil.Reporter, n int) map[agentv3.SpanType]go2sky.ReportedSpan {
+	spans := make(map[agentv3.SpanType]go2sky.ReportedSpan)
+	for i := 0; i < n; i++ {
+		for _, s := range reporter.Segment(t) {
+			spans[s.SpanType()] = s
+		}
+	}
+	return spans
+}
+
+func newHealthClient(t *testing.T, serverOptions, clientOptions []Option) (healthpb.HealthClient, *health.Server, *testutil.Reporter) {
+	reporter := testutil.NewReporter(10)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unaryServer, err := UnaryServerInterceptor(tracer, serverOptions...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamServer, err := StreamServerInterceptor(tracer, serverOptions...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unaryClient, err := UnaryClientInterceptor(tracer, clientOptions...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamClient, err := StreamClientInterceptor(tracer, clientOptions...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.UnaryInterceptor(unaryServer), grpc.StreamInterceptor(streamServer))
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithUnaryInterceptor(unaryClient), grpc.WithStreamInterceptor(streamClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return healthpb.NewHealthClient(conn), healthServer, reporter
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	tests := []struct {
+		name    string
+		service string
+		code    codes.Code
+	}{
+		{name: "ok", service: "", code: codes.OK},
+		{name: "not found", service: "unknown", code: codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _, reporter := newHealthClient(t, nil, []Option{WithTag("client", "test")})
+			_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: tt.service})
+			if status.Code(err) != tt.code {
+				t.Fatalf("the expected code %s, current is %v", tt.code, err)
+			}
+
+			spans := receive(t, reporter, 2)
+			entry, exit := spans[agentv3.SpanType_Entry], spans[agentv3.SpanType_Exit]
+			if entry == nil || exit == nil {
+				t.Fatalf("the expected entry and exit spans, current is %v", spans)
+			}
+			if entry.Context().TraceID != exit.Context().TraceID || len(entry.Refs()) != 1 {
+				t.Errorf("the context is not propagated, entry %s, exit %s", entry.Context().TraceID, exit.Context().TraceID)
+			}
+			if exit.Peer() != "bufnet" || entry.Peer() == "" {
+				t.Errorf("unexpected peers, entry %s, exit %s", entry.Peer(), exit.Peer())
+			}
+			for _, span := range []go2sky.ReportedSpan{entry, exit} {
+				if span.OperationName() != checkMethod || testutil.TagValue(span, go2sky.TagRPCMethod) != checkMethod ||
+					span.ComponentID() != componentIDGRPC || span.SpanLayer() != agentv3.SpanLayer_RPCFramework {
+					t.Errorf("unexpected span %s %v", span.OperationName(), span.Tags())
+				}
+				if testutil.TagValue(span, go2sky.TagRPCStatusCode) != tt.code.String() || span.IsError() != (tt.code != codes.OK) {
+					t.Errorf("unexpected status of span %s, error %v", testutil.TagValue(span, go2sky.TagRPCStatusCode), span.IsError())
+				}
+			}
+			if testutil.TagValue(exit, "client") != "test" {
+				t.Errorf("the expected extra tag of the exit span, current is %v", exit.Tags())
+			}
+		})
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	client, healthServer, reporter := newHealthClient(t, []Option{WithOperationName(func(fullMethod string) string {
+		return fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	})}, nil)
+	healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_SERVING)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("unexpected watch response %v, error %v", resp, err)
+	}
+	cancel()
+
+	spans := receive(t, reporter, 2)
+	entry, exit := spans[agentv3.SpanType_Entry], spans[agentv3.SpanType_Exit]
+	if entry == nil || exit == nil {
+		t.Fatalf("the expected entry and exit spans, current is %v", spans)
+	}
+	if entry.Context().TraceID != exit.Context().TraceID {
+		t.Errorf("the context is not propagated, entry %s, exit %s", entry.Context().TraceID, exit.Context().TraceID)
+	}
+	if entry.OperationName() != "Watch" || testutil.TagValue(entry, go2sky.TagRPCMethod) != watchMethod {
+		t.Errorf("unexpected operation name %s", entry.OperationName())
+	}
+	if testutil.TagValue(exit, go2sky.TagRPCStatusCode) != codes.Canceled.String() || !exit.IsError() {
+		t.Errorf("the exit span should be canceled, current is %v", exit.Tags())
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	client, _, reporter := newHealthClient(t, []Option{WithIgnore(func(fullMethod string) bool {
+		return fullMethod == checkMethod
+	})}, nil)
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	spans := receive(t, reporter, 1)
+	if spans[agentv3.SpanType_Entry] != nil || spans[agentv3.SpanType_Exit] == nil {
+		t.Errorf("only the exit span should be created, current is %v", spans)
+	}
+}
+
+func TestInvalidTracer(t *testing.T) {
+	if _, err := UnaryServerInterceptor(nil); err != errInvalidTracer {
+		t.Errorf("the expected invalid tracer error, current is %v", err)
+	}
+	if _, err := StreamClientInterceptor(nil); err != errInvalidTracer {
+		t.Errorf("the expected invalid tracer error, current is %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package grpc
+
+import (
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/tool"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+const (
+	errInvalidTracer = tool.Error("invalid tracer")
+)
+
+const componentIDGRPC = 23
+
+type config struct {
+	tracer        *go2sky.Tracer
+	operationName func(fullMethod string) string
+	ignore        func(fullMethod string) bool
+	extraTags     map[string]string
+}
+
+// Option allows for functional options to adjust behaviour of the interceptors
+type Option func(*config)
+
+// WithOperationName setup the operation name of the method, the full method is used by default
+func WithOperationName(fn func(fullMethod string) string) Option {
+	return func(c *config) {
+		c.operationName = fn
+	}
+}
+
+// WithIgnore setup the methods not traced, such as the health checks
+func WithIgnore(fn func(fullMethod string) bool) Option {
+	return func(c *config) {
+		c.ignore = fn
+	}
+}
+
+// WithTag setup an extra tag of the spans
+func WithTag(key string, value string) Option {
+	return func(c *config) {
+		if c.extraTags == nil {
+			c.extraTags = make(map[string]string)
+		}
+		c.extraTags[key] = value
+	}
+}
+
+func newConfig(tracer *go2sky.Tracer, options []Option) (*config, error) {
+	if tracer == nil {
+		return nil, errInvalidTracer
+	}
+	c := &config{tracer: tracer}
+	for _, option := range options {
+		option(c)
+	}
+	return c, nil
+}
+
+func (c *config) ignored(fullMethod string) bool {
+	return c.ignore != nil && c.ignore(fullMethod)
+}
+
+func (c *config) getOperationName(fullMethod string) string {
+	if c.operationName != nil {
+		if name := c.operationName(fullMethod); name != "" {
+			return name
+		}
+	}
+	return fullMethod
+}
+
+func (c *config) decorate(span go2sky.Span, fullMethod string) {
+	span.SetComponent(componentIDGRPC)
+	span.SetSpanLayer(agentv3.SpanLayer_RPCFramework)
+	for k, v := range c.extraTags {
+		span.Tag(go2sky.Tag(k), v)
+	}
+	span.Tag(go2sky.TagRPCMethod, fullMethod)
+}
+
+// finish tags the status code and ends the span, the span is marked as error if the code is not OK
+func finish(span go2sky.Span, err error) {
+	code := status.Code(err)
+	span.Tag(go2sky.TagRPCStatusCode, code.String())
+	if code != codes.OK {
+		span.Error(time.Now(), "code", code.String(), "message", status.Convert(err).Message())
+	}
+	span.End()
+}
@@ -0,0 +1,94 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package grpc
+
+import (
+	"context"
+
+	"github.com/SkyAPM/go2sky"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// UnaryServerInterceptor creates the entry spans of the unary calls
+func UnaryServerInterceptor(tracer *go2sky.Tracer, options ...Option) (grpc.UnaryServerInterceptor, error) {
+	c, err := newConfig(tracer, options)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if c.ignored(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		span, spanCtx, err := c.createEntrySpan(ctx, info.FullMethod)
+		if err != nil {
+			return handler(ctx, req)
+		}
+		resp, err := handler(spanCtx, req)
+		finish(span, err)
+		return resp, err
+	}, nil
+}
+
+// StreamServerInterceptor creates the entry spans of the streams, the span ends when the handler returns
+func StreamServerInterceptor(tracer *go2sky.Tracer, options ...Option) (grpc.StreamServerInterceptor, error) {
+	c, err := newConfig(tracer, options)
+	if err != nil {
+		return nil, err
+	}
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if c.ignored(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		span, spanCtx, err := c.createEntrySpan(ss.Context(), info.FullMethod)
+		if err != nil {
+			return handler(srv, ss)
+		}
+		err = handler(srv, &serverStream{ServerStream: ss, ctx: spanCtx})
+		finish(span, err)
+		return err
+	}, nil
+}
+
+func (c *config) createEntrySpan(ctx context.Context, fullMethod string) (go2sky.Span, context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	span, spanCtx, err := c.tracer.CreateEntrySpan(ctx, c.getOperationName(fullMethod), func(key string) (string, error) {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0], nil
+		}
+		return "", nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	c.decorate(span, fullMethod)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		span.SetPeer(p.Addr.String())
+	}
+	return span, spanCtx, nil
+}
+
+// serverStream carries the context of the entry span to the handler
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -17,8 +17,13 @@
 package http
 
 import (
+	"io"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/SkyAPM/go2sky"
@@ -27,11 +32,16 @@ import (
 
 const componentIDGOHttpClient = 5005
 
+// tagRedirectFrom is the URL redirecting to the request
+const tagRedirectFrom go2sky.Tag = "http.redirect.from"
+
 type ClientConfig struct {
-	name      string
-	client    *http.Client
-	tracer    *go2sky.Tracer
-	extraTags map[string]string
+	name          string
+	operationName func(*http.Request) string
+	client        *http.Client
+	tracer        *go2sky.Tracer
+	extraTags     map[string]string
+	headers       []string
 }
 
 // ClientOption allows optional configuration of Client.
@@ -44,6 +54,20 @@ func WithClientOperationName(name string) ClientOption {
 	}
 }
 
+// WithClientOperationNameFunc names the operation of the request by fn, it overrides WithClientOperationName.
+func WithClientOperationNameFunc(fn func(*http.Request) string) ClientOption {
+	return func(c *ClientConfig) {
+		c.operationName = fn
+	}
+}
+
+// WithClientHeaders tags the values of the request headers in http.headers, one line of key=value per header
+func WithClientHeaders(names ...string) ClientOption {
+	return func(c *ClientConfig) {
+		c.headers = append(c.headers, names...)
+	}
+}
+
 // WithClientTag adds extra tag to client spans.
 func WithClientTag(key string, value string) ClientOption {
 	return func(c *ClientConfig) {
@@ -89,15 +113,23 @@ type transport struct {
 	delegated http.RoundTripper
 }
 
+// RoundTrip traces the request in an exit span, which ends when the response body is read to the end or closed,
+// whichever comes first. The span of the protocol switching response ends when the headers arrive.
+// Every request following the redirects is traced in its own span, tagged with the URL redirecting to it.
 func (t *transport) RoundTrip(req *http.Request) (res *http.Response, err error) {
-	span, err := t.tracer.CreateExitSpan(req.Context(), getOperationName(t.name, req), req.Host, func(key, value string) error {
-		req.Header.Set(key, value)
+	// the request must not be modified, and the injected header must not be copied to the redirected requests
+	traced := *req
+	traced.Header = req.Header.Clone()
+	if traced.Header == nil {
+		traced.Header = make(http.Header)
+	}
+	span, err := t.tracer.CreateExitSpan(req.Context(), t.getOperationName(req), getPeer(req), func(key, value string) error {
+		traced.Header.Set(key, value)
 		return nil
 	})
 	if err != nil {
 		return t.delegated.RoundTrip(req)
 	}
-	defer span.End()
 	span.SetComponent(componentIDGOHttpClient)
 	for k, v := range t.extraTags {
 		span.Tag(go2sky.Tag(k), v)
@@ -105,14 +137,113 @@ func (t *transport) RoundTrip(req *http.Request) (res *http.Response, err error)
 	span.Tag(go2sky.TagHTTPMethod, req.Method)
 	span.Tag(go2sky.TagURL, req.URL.String())
 	span.SetSpanLayer(agentv3.SpanLayer_Http)
-	res, err = t.delegated.RoundTrip(req)
+	if req.ContentLength > 0 {
+		span.Tag(go2sky.TagHTTPRequestBodySize, strconv.FormatInt(req.ContentLength, 10))
+	}
+	if headers := t.captureHeaders(req.Header); headers != "" {
+		span.Tag(go2sky.TagHTTPHeaders, headers)
+	}
+	if req.Response != nil && req.Response.Request != nil {
+		span.Tag(tagRedirectFrom, req.Response.Request.URL.String())
+	}
+	res, err = t.delegated.RoundTrip(&traced)
 	if err != nil {
 		span.Error(time.Now(), err.Error())
+		span.End()
 		return
 	}
 	span.Tag(go2sky.TagStatusCode, strconv.Itoa(res.StatusCode))
 	if res.StatusCode >= http.StatusBadRequest {
 		span.Error(time.Now(), "Errors on handling client")
 	}
+	if res.StatusCode == http.StatusSwitchingProtocols {
+		// the body is the connection of the switched protocol, which may last as long as the caller wants
+		span.End()
+		return res, nil
+	}
+	if res.Body == nil || res.Body == http.NoBody {
+		span.Tag(go2sky.TagHTTPResponseBodySize, "0")
+		span.End()
+		return res, nil
+	}
+	res.Body = &bodyWrapper{ReadCloser: res.Body, span: span, contentLength: res.ContentLength}
 	return res, nil
 }
+
+func (t *transport) getOperationName(req *http.Request) string {
+	if t.operationName != nil {
+		if name := t.operationName(req); name != "" {
+			return name
+		}
+	}
+	return getOperationName(t.name, req)
+}
+
+func (t *transport) captureHeaders(header http.Header) string {
+	var lines []string
+	for _, name := range t.headers {
+		if values := header.Values(name); len(values) > 0 {
+			lines = append(lines, name+"="+strings.Join(values, ","))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getPeer returns the host and port of the request URL, the port is the default of the scheme if absent
+func getPeer(req *http.Request) string {
+	host := req.URL.Host
+	if host == "" {
+		host = req.Host
+	}
+	if host == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	port := "80"
+	if req.URL.Scheme == "https" || req.URL.Scheme == "wss" {
+		port = "443"
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), port)
+}
+
+// bodyWrapper ends the span when the response body is read to the end, fails to be read or is closed,
+// the response size is tagged by the content length, or by the read bytes if the length is unknown.
+type bodyWrapper struct {
+	io.ReadCloser
+	span          go2sky.Span
+	contentLength int64
+	read          int64
+	once          sync.Once
+}
+
+func (b *bodyWrapper) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(&b.read, int64(n))
+	if err != nil {
+		b.end(err)
+	}
+	return n, err
+}
+
+func (b *bodyWrapper) Close() error {
+	err := b.ReadCloser.Close()
+	b.end(nil)
+	return err
+}
+
+// end ends the span once, the read error other than io.EOF marks it as error
+func (b *bodyWrapper) end(err error) {
+	b.once.Do(func() {
+		if err != nil && err != io.EOF {
+			b.span.Error(time.Now(), err.Error())
+		}
+		size := b.contentLength
+		if size < 0 {
+			size = atomic.LoadInt64(&b.read)
+		}
+		b.span.Tag(go2sky.TagHTTPResponseBodySize, strconv.FormatInt(size, 10))
+		b.span.End()
+	})
+}
@@ -0,0 +1,242 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+	"github.com/SkyAPM/go2sky/propagation"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+func TestGetPeer(t *testing.T) {
+	tests := []struct {
+		target string
+		host   string
+		expect string
+	}{
+		{target: "http://example.com/users", expect: "example.com:80"},
+		{target: "https://example.com/users", expect: "example.com:443"},
+		{target: "http://example.com:8080/users", expect: "example.com:8080"},
+		{target: "https://[::1]/users", expect: "[::1]:443"},
+		{target: "/users", host: "example.com:8080", expect: "example.com:8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.target, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if peer := getPeer(req); peer != tt.expect {
+				t.Errorf("the expected peer %s, current is %s", tt.expect, peer)
+			}
+		})
+	}
+}
+
+func TestClient_SpanEndsOnBodyClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	tracer, reporter := newTracer(t)
+	client, err := NewClient(tracer, WithClientOperationNameFunc(func(r *http.Request) string {
+		return "hello"
+	}), WithClientHeaders("X-Request-Id", "X-Absent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL, strings.NewReader("ping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Request-Id", "1")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get(propagation.Header) != "" {
+		t.Error("the request of the caller should not be modified")
+	}
+	select {
+	case <-reporter.Segments:
+		t.Fatal("the span should not end before the body is read")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err = io.ReadAll(res.Body); err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	span := reporter.Segment(t)[0]
+	if span.OperationName() != "hello" || span.SpanType() != agentv3.SpanType_Exit || span.Peer() != strings.TrimPrefix(server.URL, "http://") {
+		t.Errorf("unexpected span %s: type %v, peer %s", span.OperationName(), span.SpanType(), span.Peer())
+	}
+	if time.Duration(span.EndTime()-span.StartTime())*time.Millisecond < 100*time.Millisecond {
+		t.Errorf("the span should last until the body is read, current is %dms", span.EndTime()-span.StartTime())
+	}
+	if testutil.TagValue(span, go2sky.TagHTTPRequestBodySize) != "4" || testutil.TagValue(span, go2sky.TagHTTPResponseBodySize) != "5" ||
+		testutil.TagValue(span, go2sky.TagHTTPHeaders) != "X-Request-Id=1" {
+		t.Errorf("unexpected tags %v", span.Tags())
+	}
+}
+
+func TestClient_SpanEndsOnBodyEOF(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	tracer, reporter := newTracer(t)
+	client, err := NewClient(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	// the body is read to the end without being closed
+	if _, err = io.ReadAll(res.Body); err != nil {
+		t.Fatal(err)
+	}
+	span := reporter.Segment(t)[0]
+	if testutil.TagValue(span, go2sky.TagHTTPResponseBodySize) != "5" || span.IsError() {
+		t.Errorf("unexpected span, error %t, tags %v", span.IsError(), span.Tags())
+	}
+	_ = res.Body.Close()
+	select {
+	case <-reporter.Segments:
+		t.Error("the span should end only once")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_SwitchingProtocols(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		_ = rw.Flush()
+		_, _ = io.Copy(conn, rw)
+	}))
+	defer server.Close()
+	tracer, reporter := newTracer(t)
+	client, err := NewClient(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "echo")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	// the span ends with the headers while the connection is still open
+	span := reporter.Segment(t)[0]
+	if testutil.TagValue(span, go2sky.TagStatusCode) != "101" || span.IsError() {
+		t.Errorf("unexpected span, error %t, tags %v", span.IsError(), span.Tags())
+	}
+	if _, ok := res.Body.(io.ReadWriteCloser); !ok {
+		t.Fatal("the body of the switched protocol should be writable")
+	}
+	if _, err = res.Body.(io.Writer).Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	echo := make([]byte, 4)
+	if _, err = io.ReadFull(res.Body, echo); err != nil || string(echo) != "ping" {
+		t.Errorf("unexpected echo %s, error %v", echo, err)
+	}
+}
+
+func TestClient_Redirect(t *testing.T) {
+	var sw8 []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw8 = append(sw8, r.Header.Get(propagation.Header))
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	tracer, reporter := newTracer(t)
+	client, err := NewClient(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	span, ctx, err := tracer.CreateLocalSpan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/old", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	span.End()
+
+	var exits []go2sky.ReportedSpan
+	for _, s := range reporter.Segment(t) {
+		if s.SpanType() == agentv3.SpanType_Exit {
+			exits = append(exits, s)
+		}
+	}
+	sort.Slice(exits, func(i, j int) bool {
+		return exits[i].Context().SpanID < exits[j].Context().SpanID
+	})
+	if len(exits) != 2 || exits[0].OperationName() != "/GET/old" || exits[1].OperationName() != "/GET/new" {
+		t.Fatalf("the expected spans of the old and the new URLs, current is %v", exits)
+	}
+	if testutil.TagValue(exits[0], go2sky.TagStatusCode) != "302" || exits[0].IsError() {
+		t.Errorf("the redirect should not be an error, tags %v", exits[0].Tags())
+	}
+	if testutil.TagValue(exits[1], tagRedirectFrom) != server.URL+"/old" {
+		t.Errorf("unexpected redirect tag %s", testutil.TagValue(exits[1], tagRedirectFrom))
+	}
+	if len(sw8) != 2 || sw8[0] == "" || sw8[0] == sw8[1] {
+		t.Errorf("each request should carry the context of its own span, current is %v", sw8)
+	}
+}
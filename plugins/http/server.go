@@ -19,7 +19,9 @@ package http
 import (
 	"fmt"
 	"net/http"
+	"runtime/debug"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/SkyAPM/go2sky"
@@ -34,10 +36,13 @@ const (
 const componentIDGOHttpServer = 5004
 
 type handler struct {
-	tracer    *go2sky.Tracer
-	name      string
-	next      http.Handler
-	extraTags map[string]string
+	tracer        *go2sky.Tracer
+	componentID   int32
+	name          string
+	operationName func(*http.Request) string
+	ignore        func(*http.Request) bool
+	next          http.Handler
+	extraTags     map[string]string
 }
 
 // ServerOption allows Middleware to be optionally configured.
@@ -60,6 +65,29 @@ func WithServerOperationName(name string) ServerOption {
 	}
 }
 
+// WithServerOperationNameFunc names the operation of the request by fn, it overrides WithServerOperationName.
+// The operation is named by the route pattern when the next handler is a http.ServeMux by default,
+// such as /GET/users/{id} of the pattern "GET /users/{id}", otherwise by the request path.
+func WithServerOperationNameFunc(fn func(*http.Request) string) ServerOption {
+	return func(h *handler) {
+		h.operationName = fn
+	}
+}
+
+// WithServerIgnore setup the requests not traced, such as the health checks
+func WithServerIgnore(fn func(*http.Request) bool) ServerOption {
+	return func(h *handler) {
+		h.ignore = fn
+	}
+}
+
+// WithServerComponent setup the component id of the spans, such as the id of the web framework
+func WithServerComponent(id int32) ServerOption {
+	return func(h *handler) {
+		h.componentID = id
+	}
+}
+
 // NewServerMiddleware returns a http.Handler middleware with tracing.
 func NewServerMiddleware(tracer *go2sky.Tracer, options ...ServerOption) (func(http.Handler) http.Handler, error) {
 	if tracer == nil {
@@ -67,8 +95,9 @@ func NewServerMiddleware(tracer *go2sky.Tracer, options ...ServerOption) (func(h
 	}
 	return func(next http.Handler) http.Handler {
 		h := &handler{
-			tracer: tracer,
-			next:   next,
+			tracer:      tracer,
+			componentID: componentIDGOHttpServer,
+			next:        next,
 		}
 		for _, option := range options {
 			option(h)
@@ -78,41 +107,220 @@ func NewServerMiddleware(tracer *go2sky.Tracer, options ...ServerOption) (func(h
 }
 
 // ServeHTTP implements http.Handler.
+// The span is marked as error with the stack when the next handler panics, and the panic is propagated.
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	span, ctx, err := h.tracer.CreateEntrySpan(r.Context(), getOperationName(h.name, r), func(key string) (string, error) {
-		return r.Header.Get(key), nil
-	})
-	if err != nil {
+	route := ""
+	if mux, ok := h.next.(*http.ServeMux); ok {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			route = patternPath(pattern)
+		}
+	}
+	span, traced := h.start(r, route)
+	if span == nil {
 		if h.next != nil {
 			h.next.ServeHTTP(w, r)
 		}
 		return
 	}
-	span.SetComponent(componentIDGOHttpServer)
+	span.Serve(w, traced, h.next)
+}
+
+// Server creates the entry spans of the server requests, it is the core of NewServerMiddleware
+// shared by the web framework plugins, which name the operations by their matched routes.
+type Server struct {
+	h *handler
+}
+
+// NewServer returns the Server creating the spans by the tracer
+func NewServer(tracer *go2sky.Tracer, options ...ServerOption) (*Server, error) {
+	if tracer == nil {
+		return nil, errInvalidTracer
+	}
+	h := &handler{tracer: tracer, componentID: componentIDGOHttpServer}
+	for _, option := range options {
+		option(h)
+	}
+	return &Server{h: h}, nil
+}
+
+// Start creates the entry span of the request, the route names the operation as /METHOD/route
+// unless the operation name options are setup, and the request path is used if the route is empty.
+// It returns nil if the request is ignored or not traced, otherwise the returned request carries the span.
+func (s *Server) Start(r *http.Request, route string) (*ServerSpan, *http.Request) {
+	return s.h.start(r, route)
+}
+
+func (h *handler) start(r *http.Request, route string) (*ServerSpan, *http.Request) {
+	if h.ignore != nil && h.ignore(r) {
+		return nil, r
+	}
+	name, named := h.getOperationName(r, route)
+	span, ctx, err := h.tracer.CreateEntrySpan(r.Context(), name, func(key string) (string, error) {
+		return r.Header.Get(key), nil
+	})
+	if err != nil {
+		return nil, r
+	}
+	span.SetComponent(h.componentID)
 	for k, v := range h.extraTags {
 		span.Tag(go2sky.Tag(k), v)
 	}
 	span.Tag(go2sky.TagHTTPMethod, r.Method)
 	span.Tag(go2sky.TagURL, fmt.Sprintf("%s%s", r.Host, r.URL.Path))
 	span.SetSpanLayer(agentv3.SpanLayer_Http)
+	return &ServerSpan{span: span, method: r.Method, named: named}, r.WithContext(ctx)
+}
+
+// getOperationName returns the operation name of the request, and whether it is named by the options
+func (h *handler) getOperationName(r *http.Request, route string) (string, bool) {
+	if h.operationName != nil {
+		if name := h.operationName(r); name != "" {
+			return name, true
+		}
+	}
+	if h.name != "" {
+		return h.name, true
+	}
+	if route != "" {
+		return fmt.Sprintf("/%s%s", r.Method, route), false
+	}
+	return getOperationName("", r), false
+}
 
-	rww := &responseWriterWrapper{w: w, statusCode: 200}
+// ServerSpan is the entry span of a server request created by Server
+type ServerSpan struct {
+	span    go2sky.Span
+	method  string
+	named   bool
+	errored bool
+}
+
+// Span returns the go2sky span, such as to tag the span by the plugins built on Server
+func (s *ServerSpan) Span() go2sky.Span {
+	return s.span
+}
+
+// Serve serves the request by next with the response writer recording the status code and the response size,
+// then ends the span. The span is marked as error with the stack when next panics, and the panic is propagated.
+func (s *ServerSpan) Serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	rww, wrapped := wrapResponseWriter(w)
 	defer func() {
-		code := rww.statusCode
-		if code >= 400 {
-			span.Error(time.Now(), "Error on handling request")
+		if p := recover(); p != nil {
+			s.Panic(p)
+			code := rww.statusCode
+			if !rww.wroteHeader {
+				code = http.StatusInternalServerError
+			}
+			s.End(code, rww.size)
+			panic(p)
 		}
-		span.Tag(go2sky.TagStatusCode, strconv.Itoa(code))
-		span.End()
+		s.End(rww.statusCode, rww.size)
 	}()
-	if h.next != nil {
-		h.next.ServeHTTP(rww, r.WithContext(ctx))
+	if next != nil {
+		next.ServeHTTP(wrapped, r)
+	}
+}
+
+// SetRoute names the operation by the route matched after the span is created,
+// such as by the routers resolving the routes in the next handlers.
+// The operation named by the options is kept.
+func (s *ServerSpan) SetRoute(route string) {
+	if !s.named && route != "" {
+		s.span.SetOperationName(fmt.Sprintf("/%s%s", s.method, route))
+	}
+}
+
+// Error marks the span as error by the error of handling the request, such as returned by the framework
+func (s *ServerSpan) Error(err error) {
+	s.errored = true
+	s.span.Error(time.Now(), "error", err.Error())
+}
+
+// Panic marks the span as error by the panic of handling the request, the stack is logged
+func (s *ServerSpan) Panic(p interface{}) {
+	s.errored = true
+	s.span.Error(time.Now(), "panic", fmt.Sprint(p), "stack", string(debug.Stack()))
+}
+
+// End tags the status code and the response size then ends the span,
+// the span is marked as error if the status code is 4xx or 5xx and it is not marked yet.
+func (s *ServerSpan) End(statusCode int, size int64) {
+	if statusCode >= 400 && !s.errored {
+		s.span.Error(time.Now(), "Error on handling request")
+	}
+	s.span.Tag(go2sky.TagStatusCode, strconv.Itoa(statusCode))
+	s.span.Tag(go2sky.TagHTTPResponseBodySize, strconv.FormatInt(size, 10))
+	s.span.End()
+}
+
+// patternPath returns the path of the ServeMux pattern "[METHOD ][HOST]/[PATH]"
+func patternPath(pattern string) string {
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i+1:], " \t")
+	}
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
 	}
+	return strings.TrimSuffix(pattern, "{$}")
 }
 
 type responseWriterWrapper struct {
-	w          http.ResponseWriter
-	statusCode int
+	w           http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	size        int64
+}
+
+// wrapResponseWriter returns the wrapper recording the status code and the response size,
+// and the http.ResponseWriter implementing the same http.Flusher, http.Hijacker and http.Pusher as w.
+func wrapResponseWriter(w http.ResponseWriter) (*responseWriterWrapper, http.ResponseWriter) {
+	rww := &responseWriterWrapper{w: w, statusCode: http.StatusOK}
+	flusher, isFlusher := w.(http.Flusher)
+	hijacker, isHijacker := w.(http.Hijacker)
+	pusher, isPusher := w.(http.Pusher)
+	switch {
+	case isFlusher && isHijacker && isPusher:
+		return rww, struct {
+			*responseWriterWrapper
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{rww, flusher, hijacker, pusher}
+	case isFlusher && isHijacker:
+		return rww, struct {
+			*responseWriterWrapper
+			http.Flusher
+			http.Hijacker
+		}{rww, flusher, hijacker}
+	case isFlusher && isPusher:
+		return rww, struct {
+			*responseWriterWrapper
+			http.Flusher
+			http.Pusher
+		}{rww, flusher, pusher}
+	case isHijacker && isPusher:
+		return rww, struct {
+			*responseWriterWrapper
+			http.Hijacker
+			http.Pusher
+		}{rww, hijacker, pusher}
+	case isFlusher:
+		return rww, struct {
+			*responseWriterWrapper
+			http.Flusher
+		}{rww, flusher}
+	case isHijacker:
+		return rww, struct {
+			*responseWriterWrapper
+			http.Hijacker
+		}{rww, hijacker}
+	case isPusher:
+		return rww, struct {
+			*responseWriterWrapper
+			http.Pusher
+		}{rww, pusher}
+	}
+	return rww, rww
 }
 
 func (rww *responseWriterWrapper) Header() http.Header {
@@ -120,14 +328,25 @@ func (rww *responseWriterWrapper) Header() http.Header {
 }
 
 func (rww *responseWriterWrapper) Write(bytes []byte) (int, error) {
-	return rww.w.Write(bytes)
+	rww.wroteHeader = true
+	n, err := rww.w.Write(bytes)
+	rww.size += int64(n)
+	return n, err
 }
 
 func (rww *responseWriterWrapper) WriteHeader(statusCode int) {
-	rww.statusCode = statusCode
+	if !rww.wroteHeader {
+		rww.statusCode = statusCode
+		rww.wroteHeader = true
+	}
 	rww.w.WriteHeader(statusCode)
 }
 
+// Unwrap returns the original http.ResponseWriter for http.ResponseController
+func (rww *responseWriterWrapper) Unwrap() http.ResponseWriter {
+	return rww.w
+}
+
 func getOperationName(name string, r *http.Request) string {
 	if name == "" {
 		return fmt.Sprintf("/%s%s", r.Method, r.URL.Path)
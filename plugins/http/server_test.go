@@ -0,0 +1,242 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:debug httpmuxgo121=0
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+)
+
+func newTracer(t *testing.T) (*go2sky.Tracer, *testutil.Reporter) {
+	reporter := testutil.NewReporter(10)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tracer, reporter
+}
+
+func serve(t *testing.T, tracer *go2sky.Tracer, next http.Handler, r *http.Request, options ...ServerOption) *httptest.ResponseRecorder {
+	middleware, err := NewServerMiddleware(tracer, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	middleware(next).ServeHTTP(w, r)
+	return w
+}
+
+func TestServerMiddleware_OperationName(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("example.com/orders/{$}", func(w http.ResponseWriter, r *http.Request) {})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name    string
+		next    http.Handler
+		target  string
+		options []ServerOption
+		expect  string
+	}{
+		{name: "method pattern", next: mux, target: "/users/123", expect: "/GET/users/{id}"},
+		{name: "host pattern", next: mux, target: "http://example.com/orders/", expect: "/GET/orders/"},
+		{name: "not found", next: mux, target: "/orders/1", expect: "/GET/orders/1"},
+		{name: "handler", next: handler, target: "/users/123", expect: "/GET/users/123"},
+		{name: "static", next: mux, target: "/users/123", options: []ServerOption{WithServerOperationName("users")}, expect: "users"},
+		{
+			name:   "func",
+			next:   handler,
+			target: "/users/123",
+			options: []ServerOption{WithServerOperationName("users"), WithServerOperationNameFunc(func(r *http.Request) string {
+				return "/" + strings.Split(r.URL.Path, "/")[1]
+			})},
+			expect: "/users",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, reporter := newTracer(t)
+			serve(t, tracer, tt.next, httptest.NewRequest(http.MethodGet, tt.target, nil), tt.options...)
+			if name := reporter.Segment(t)[0].OperationName(); name != tt.expect {
+				t.Errorf("the expected operation name %s, current is %s", tt.expect, name)
+			}
+		})
+	}
+}
+
+func TestServerMiddleware_Ignore(t *testing.T) {
+	tracer, reporter := newTracer(t)
+	called := false
+	w := serve(t, tracer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}), httptest.NewRequest(http.MethodGet, "/health", nil), WithServerIgnore(func(r *http.Request) bool {
+		return r.URL.Path == "/health"
+	}))
+	if !called || w.Code != http.StatusOK {
+		t.Fatal("the ignored request should be served")
+	}
+	select {
+	case <-reporter.Segments:
+		t.Error("the ignored request should not be traced")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestServerMiddleware_Response(t *testing.T) {
+	tests := []struct {
+		name   string
+		next   http.HandlerFunc
+		status string
+		size   string
+		error  bool
+	}{
+		{
+			name: "ok",
+			next: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("hello"))
+			},
+			status: "200",
+			size:   "5",
+		},
+		{
+			name: "error",
+			next: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "not found", http.StatusNotFound)
+			},
+			status: "404",
+			size:   "10",
+			error:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, reporter := newTracer(t)
+			serve(t, tracer, tt.next, httptest.NewRequest(http.MethodGet, "/", nil))
+			span := reporter.Segment(t)[0]
+			if testutil.TagValue(span, go2sky.TagStatusCode) != tt.status || testutil.TagValue(span, go2sky.TagHTTPResponseBodySize) != tt.size ||
+				span.IsError() != tt.error {
+				t.Errorf("unexpected span: error %t, tags %v", span.IsError(), span.Tags())
+			}
+		})
+	}
+}
+
+func TestServerMiddleware_Panic(t *testing.T) {
+	tracer, reporter := newTracer(t)
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("the panic should be propagated, current is %v", p)
+		}
+		span := reporter.Segment(t)[0]
+		if !span.IsError() || testutil.TagValue(span, go2sky.TagStatusCode) != "500" {
+			t.Fatalf("the span should be marked as error, tags %v", span.Tags())
+		}
+		logs := span.Logs()
+		if len(logs) != 1 || !strings.Contains(logs[0].GetData()[1].GetValue(), "TestServerMiddleware_Panic") {
+			t.Errorf("the stack should be logged, current is %v", logs)
+		}
+	}()
+	serve(t, tracer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestServerMiddleware_ResponseWriterInterfaces(t *testing.T) {
+	tracer, reporter := newTracer(t)
+	middleware, err := NewServerMiddleware(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, isFlusher := w.(http.Flusher)
+		_, isHijacker := w.(http.Hijacker)
+		_, isPusher := w.(http.Pusher)
+		if !isFlusher || !isHijacker || isPusher {
+			t.Errorf("the interfaces of the response writer are not preserved: flusher %t, hijacker %t, pusher %t",
+				isFlusher, isHijacker, isPusher)
+		}
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Error(err)
+		}
+	})))
+	defer server.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	reporter.Segment(t)
+}
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    string
+		setRoute string
+		options  []ServerOption
+		err      error
+		expect   string
+	}{
+		{name: "route", route: "/users/:id", expect: "/GET/users/:id"},
+		{name: "path", expect: "/GET/users/1"},
+		{name: "set route", setRoute: "/users/{id}", expect: "/GET/users/{id}"},
+		{name: "named", route: "/users/:id", setRoute: "/users/{id}", options: []ServerOption{WithServerOperationName("users")}, expect: "users"},
+		{name: "error", route: "/users/:id", err: errors.New("invalid user"), expect: "/GET/users/:id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, reporter := newTracer(t)
+			server, err := NewServer(tracer, append(tt.options, WithServerComponent(5006))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			span, r := server.Start(httptest.NewRequest(http.MethodGet, "/users/1", nil), tt.route)
+			if go2sky.ActiveSpan(r.Context()) == nil {
+				t.Fatal("the request should carry the span")
+			}
+			span.SetRoute(tt.setRoute)
+			if tt.err != nil {
+				span.Error(tt.err)
+			}
+			span.End(http.StatusBadRequest, 3)
+			reported := reporter.Segment(t)[0]
+			if reported.OperationName() != tt.expect || reported.ComponentID() != 5006 || !reported.IsError() ||
+				testutil.TagValue(reported, go2sky.TagStatusCode) != "400" || testutil.TagValue(reported, go2sky.TagHTTPResponseBodySize) != "3" {
+				t.Errorf("unexpected span %s: component %d, tags %v", reported.OperationName(), reported.ComponentID(), reported.Tags())
+			}
+			if len(reported.Logs()) != 1 {
+				t.Errorf("the span should be marked as error once, current is %v", reported.Logs())
+			}
+		})
+	}
+}
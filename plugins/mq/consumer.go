@@ -0,0 +1,85 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mq
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/propagation"
+)
+
+// Consumer creates the entry spans of consuming the messages
+type Consumer struct {
+	config *config
+}
+
+// NewConsumer returns the Consumer creating the spans by the tracer
+func NewConsumer(tracer *go2sky.Tracer, options ...Option) (*Consumer, error) {
+	c, err := newConfig(tracer, options)
+	if err != nil {
+		return nil, err
+	}
+	return &Consumer{config: c}, nil
+}
+
+// CreateSpan creates the entry span of consuming the message from the topic,
+// the span refers to the SkyWalking context extracted from the header of the message.
+// The span should be ended by End after the message is handled.
+func (c *Consumer) CreateSpan(ctx context.Context, topic string, header Header) (go2sky.Span, context.Context, error) {
+	span, nCtx, err := c.config.tracer.CreateEntrySpan(ctx, c.operationName(topic), func(key string) (string, error) {
+		return header.Get(key), nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	c.config.decorate(span, topic)
+	return span, nCtx, nil
+}
+
+// CreateBatchSpan creates one entry span of consuming the batch of messages from the topic,
+// the span refers to all the SkyWalking contexts extracted from the headers of the messages.
+// The headers without the valid context are skipped, and the same context is referred only once.
+func (c *Consumer) CreateBatchSpan(ctx context.Context, topic string, headers []Header) (go2sky.Span, context.Context, error) {
+	opts := []go2sky.SpanOption{go2sky.WithSpanType(go2sky.SpanTypeEntry), go2sky.WithOperationName(c.operationName(topic))}
+	referred := make(map[string]bool)
+	for _, header := range headers {
+		sc := &propagation.SpanContext{}
+		if err := sc.Decode(func(key string) (string, error) {
+			return header.Get(key), nil
+		}); err != nil || !sc.Valid {
+			continue
+		}
+		key := fmt.Sprintf("%s-%d", sc.ParentSegmentID, sc.ParentSpanID)
+		if referred[key] {
+			continue
+		}
+		referred[key] = true
+		opts = append(opts, go2sky.WithContext(sc))
+	}
+	span, nCtx, err := c.config.tracer.CreateLocalSpan(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.config.decorate(span, topic)
+	return span, nCtx, nil
+}
+
+func (c *Consumer) operationName(topic string) string {
+	return fmt.Sprintf("%s/%s/Consumer", c.config.name, topic)
+}
@@ -0,0 +1,34 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mq
+
+// Header is the carrier of the SkyWalking context in the message headers
+type Header interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// MapHeader is the Header of the messages whose headers are the string map
+type MapHeader map[string]string
+
+func (h MapHeader) Get(key string) string {
+	return h[key]
+}
+
+func (h MapHeader) Set(key, value string) {
+	h[key] = value
+}
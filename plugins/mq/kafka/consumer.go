@@ -0,0 +1,61 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package kafka
+
+import (
+	"context"
+	"errors"
+
+	"github.com/IBM/sarama"
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/plugins/mq"
+)
+
+var errEmptyBatch = errors.New("empty batch of messages")
+
+// Consumer creates the entry spans of consuming the sarama.ConsumerMessage
+type Consumer struct {
+	consumer *mq.Consumer
+}
+
+// NewConsumer returns the Consumer creating the spans by the tracer
+func NewConsumer(tracer *go2sky.Tracer, options ...mq.Option) (*Consumer, error) {
+	options = append([]mq.Option{mq.WithName(name), mq.WithComponent(componentIDKafkaConsumer)}, options...)
+	c, err := mq.NewConsumer(tracer, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Consumer{consumer: c}, nil
+}
+
+// CreateSpan creates the entry span of consuming the message, it should be ended by mq.End after the message is handled
+func (c *Consumer) CreateSpan(ctx context.Context, msg *sarama.ConsumerMessage) (go2sky.Span, context.Context, error) {
+	return c.consumer.CreateSpan(ctx, msg.Topic, consumerHeader{msg: msg})
+}
+
+// CreateBatchSpan creates one entry span of consuming the messages, the topic of the first message is tagged.
+// The span refers to the contexts of all the messages, it should be ended by mq.End after the messages are handled.
+func (c *Consumer) CreateBatchSpan(ctx context.Context, msgs []*sarama.ConsumerMessage) (go2sky.Span, context.Context, error) {
+	if len(msgs) == 0 {
+		return nil, nil, errEmptyBatch
+	}
+	headers := make([]mq.Header, 0, len(msgs))
+	for _, msg := range msgs {
+		headers = append(headers, consumerHeader{msg: msg})
+	}
+	return c.consumer.CreateBatchSpan(ctx, msgs[0].Topic, headers)
+}
@@ -0,0 +1,63 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package kafka
+
+import (
+	"github.com/IBM/sarama"
+)
+
+// producerHeader is the carrier of the record headers of the producer message
+type producerHeader struct {
+	msg *sarama.ProducerMessage
+}
+
+func (h producerHeader) Get(key string) string {
+	for _, header := range h.msg.Headers {
+		if string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+func (h producerHeader) Set(key, value string) {
+	for i, header := range h.msg.Headers {
+		if string(header.Key) == key {
+			h.msg.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	h.msg.Headers = append(h.msg.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+// consumerHeader is the carrier of the record headers of the consumer message
+type consumerHeader struct {
+	msg *sarama.ConsumerMessage
+}
+
+func (h consumerHeader) Get(key string) string {
+	for _, header := range h.msg.Headers {
+		if header != nil && string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+func (h consumerHeader) Set(key, value string) {
+	h.msg.Headers = append(h.msg.Headers, &sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
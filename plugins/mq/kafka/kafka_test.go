@@ -0,0 +1,166 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+	"github.com/SkyAPM/go2sky/plugins/mq"
+	"github.com/SkyAPM/go2sky/propagation"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+var errFake = errors.New("fake error")
+
+// fakeProducer fails to send the messages of the topic "fail"
+type fakeProducer struct {
+	sarama.SyncProducer
+	sent []*sarama.ProducerMessage
+}
+
+func (p *fakeProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	if msg.Topic == "fail" {
+		return 0, 0, errFake
+	}
+	p.sent = append(p.sent, msg)
+	return 0, int64(len(p.sent)), nil
+}
+
+func (p *fakeProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	var errs sarama.ProducerErrors
+	for _, msg := range msgs {
+		if _, _, err := p.SendMessage(msg); err != nil {
+			errs = append(errs, &sarama.ProducerError{Msg: msg, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func newTracer(t *testing.T) (*go2sky.Tracer, *testutil.Reporter) {
+	reporter := testutil.NewReporter(10)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tracer, reporter
+}
+
+// spans returns the spans of the segment reported next, sorted by the span id
+
+func TestSyncProducer(t *testing.T) {
+	tracer, reporter := newTracer(t)
+	fake := &fakeProducer{}
+	producer, err := WrapSyncProducer(fake, tracer, mq.WithBroker("localhost:9092"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	span, ctx, err := tracer.CreateLocalSpan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = producer.SendMessageContext(ctx, &sarama.ProducerMessage{Topic: "orders"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = producer.SendMessagesContext(ctx, []*sarama.ProducerMessage{{Topic: "payments"}, {Topic: "fail"}}); err != nil {
+		t.Fatal(err)
+	}
+	span.End()
+
+	spans := reporter.Segment(t)
+	if len(spans) != 4 {
+		t.Fatalf("the expected 4 spans, current is %d", len(spans))
+	}
+	expected := []struct {
+		name  string
+		error bool
+	}{
+		{name: "Kafka/orders/Producer"},
+		{name: "Kafka/payments/Producer"},
+		{name: "Kafka/fail/Producer", error: true},
+	}
+	for i, e := range expected {
+		s := spans[i+1]
+		if s.OperationName() != e.name || s.IsError() != e.error || s.SpanType() != agentv3.SpanType_Exit ||
+			s.Peer() != "localhost:9092" || s.ComponentID() != componentIDKafkaProducer {
+			t.Errorf("unexpected span %s: error %t, peer %s, component %d", s.OperationName(), s.IsError(), s.Peer(), s.ComponentID())
+		}
+	}
+	if len(fake.sent) != 2 {
+		t.Fatalf("the expected 2 sent messages, current is %d", len(fake.sent))
+	}
+	for _, msg := range fake.sent {
+		if (producerHeader{msg: msg}).Get(propagation.Header) == "" {
+			t.Errorf("the context is not injected into the message of %s", msg.Topic)
+		}
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	tracer, reporter := newTracer(t)
+	producer, err := WrapSyncProducer(&fakeProducer{}, tracer, mq.WithBroker("localhost:9092"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgs []*sarama.ConsumerMessage
+	for _, topic := range []string{"orders", "orders"} {
+		msg := &sarama.ProducerMessage{Topic: topic}
+		if _, _, err = producer.SendMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+		reporter.Segment(t)
+		consumed := &sarama.ConsumerMessage{Topic: msg.Topic}
+		for i := range msg.Headers {
+			consumed.Headers = append(consumed.Headers, &msg.Headers[i])
+		}
+		msgs = append(msgs, consumed)
+	}
+
+	consumer, err := NewConsumer(tracer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	span, _, err := consumer.CreateSpan(context.Background(), msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	mq.End(span, nil)
+	if entry := reporter.Segment(t)[0]; entry.OperationName() != "Kafka/orders/Consumer" || len(entry.Refs()) != 1 ||
+		entry.ComponentID() != componentIDKafkaConsumer {
+		t.Errorf("unexpected span %s: refs %d, component %d", entry.OperationName(), len(entry.Refs()), entry.ComponentID())
+	}
+
+	span, _, err = consumer.CreateBatchSpan(context.Background(), msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mq.End(span, nil)
+	if entry := reporter.Segment(t)[0]; entry.SpanType() != agentv3.SpanType_Entry || len(entry.Refs()) != 2 {
+		t.Errorf("unexpected batch span %s: type %v, refs %d", entry.OperationName(), entry.SpanType(), len(entry.Refs()))
+	}
+
+	if _, _, err = consumer.CreateBatchSpan(context.Background(), nil); err != errEmptyBatch {
+		t.Errorf("the expected empty batch error, current is %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package kafka
+
+import (
+	"context"
+
+	"github.com/IBM/sarama"
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/plugins/mq"
+)
+
+const (
+	componentIDKafkaProducer = 40
+	componentIDKafkaConsumer = 41
+)
+
+const name = "Kafka"
+
+// SyncProducer traces the messages sent by the wrapped sarama.SyncProducer
+type SyncProducer struct {
+	sarama.SyncProducer
+	producer *mq.Producer
+}
+
+// WrapSyncProducer returns the SyncProducer sending the messages by the producer,
+// the broker addresses are required by mq.WithBroker as the peer of the spans.
+func WrapSyncProducer(producer sarama.SyncProducer, tracer *go2sky.Tracer, options ...mq.Option) (*SyncProducer, error) {
+	options = append([]mq.Option{mq.WithName(name), mq.WithComponent(componentIDKafkaProducer)}, options...)
+	p, err := mq.NewProducer(tracer, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &SyncProducer{SyncProducer: producer, producer: p}, nil
+}
+
+// SendMessage sends the message in a new trace, use SendMessageContext to continue the trace of the context
+func (p *SyncProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+	return p.SendMessageContext(context.Background(), msg)
+}
+
+// SendMessageContext sends the message in the exit span created from the context
+func (p *SyncProducer) SendMessageContext(ctx context.Context, msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+	span, err := p.producer.CreateSpan(ctx, msg.Topic, producerHeader{msg: msg})
+	if err != nil {
+		return 0, 0, err
+	}
+	partition, offset, err = p.SyncProducer.SendMessage(msg)
+	mq.End(span, err)
+	return
+}
+
+// SendMessages sends the messages in a new trace, use SendMessagesContext to continue the trace of the context
+func (p *SyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	return p.SendMessagesContext(context.Background(), msgs)
+}
+
+// SendMessagesContext sends the messages in the exit spans created from the context, one span for each message.
+// Only the spans of the failed messages are marked as error when the error is sarama.ProducerErrors.
+func (p *SyncProducer) SendMessagesContext(ctx context.Context, msgs []*sarama.ProducerMessage) error {
+	spans := make(map[*sarama.ProducerMessage]go2sky.Span, len(msgs))
+	for _, msg := range msgs {
+		span, err := p.producer.CreateSpan(ctx, msg.Topic, producerHeader{msg: msg})
+		if err != nil {
+			for _, s := range spans {
+				s.End()
+			}
+			return err
+		}
+		spans[msg] = span
+	}
+	err := p.SyncProducer.SendMessages(msgs)
+	if errs, ok := err.(sarama.ProducerErrors); ok {
+		for _, e := range errs {
+			if span, ok := spans[e.Msg]; ok {
+				mq.End(span, e.Err)
+				delete(spans, e.Msg)
+			}
+		}
+		err = nil
+	}
+	for _, span := range spans {
+		mq.End(span, err)
+	}
+	return err
+}
@@ -0,0 +1,161 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+	"github.com/SkyAPM/go2sky/propagation"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+func newTracer(t *testing.T) (*go2sky.Tracer, *testutil.Reporter) {
+	reporter := testutil.NewReporter(10)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tracer, reporter
+}
+
+// produce sends a message by the producer, then returns the header and the reported exit span
+func produce(t *testing.T, tracer *go2sky.Tracer, reporter *testutil.Reporter, topic string, err error) (MapHeader, go2sky.ReportedSpan) {
+	producer, e := NewProducer(tracer, WithName("Kafka"), WithBroker("localhost:9092"), WithComponent(40), WithTag("env", "test"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	header := MapHeader{}
+	span, e := producer.CreateSpan(context.Background(), topic, header)
+	if e != nil {
+		t.Fatal(e)
+	}
+	End(span, err)
+	return header, reporter.Segment(t)[0]
+}
+
+func TestProducer(t *testing.T) {
+	tracer, reporter := newTracer(t)
+	header, span := produce(t, tracer, reporter, "orders", errors.New("send failed"))
+	if header[propagation.Header] == "" {
+		t.Fatal("the context is not injected into the header")
+	}
+	if span.SpanType() != agentv3.SpanType_Exit || span.OperationName() != "Kafka/orders/Producer" || span.Peer() != "localhost:9092" ||
+		span.ComponentID() != 40 || span.SpanLayer() != agentv3.SpanLayer_MQ {
+		t.Errorf("unexpected span %s: type %v, peer %s, component %d", span.OperationName(), span.SpanType(), span.Peer(), span.ComponentID())
+	}
+	if testutil.TagValue(span, go2sky.TagMQTopic) != "orders" || testutil.TagValue(span, go2sky.TagMQBroker) != "localhost:9092" || testutil.TagValue(span, "env") != "test" {
+		t.Errorf("unexpected tags %v", span.Tags())
+	}
+	if !span.IsError() {
+		t.Error("the span should be marked as error")
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	tracer, reporter := newTracer(t)
+	header, exit := produce(t, tracer, reporter, "orders", nil)
+
+	consumer, err := NewConsumer(tracer, WithName("Kafka"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	span, _, err := consumer.CreateSpan(context.Background(), "orders", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	End(span, nil)
+	entry := reporter.Segment(t)[0]
+	if entry.SpanType() != agentv3.SpanType_Entry || entry.OperationName() != "Kafka/orders/Consumer" || entry.IsError() {
+		t.Errorf("unexpected span %s: type %v", entry.OperationName(), entry.SpanType())
+	}
+	if entry.Context().TraceID != exit.Context().TraceID || len(entry.Refs()) != 1 ||
+		entry.Refs()[0].ParentSegmentID != exit.Context().SegmentID {
+		t.Errorf("the entry span does not refer to the producer span")
+	}
+}
+
+func TestConsumer_Batch(t *testing.T) {
+	tracer, reporter := newTracer(t)
+	first, firstExit := produce(t, tracer, reporter, "orders", nil)
+	second, secondExit := produce(t, tracer, reporter, "orders", nil)
+
+	tests := []struct {
+		name    string
+		headers []Header
+		refs    []go2sky.ReportedSpan
+	}{
+		{
+			name:    "multiple refs",
+			headers: []Header{first, second},
+			refs:    []go2sky.ReportedSpan{firstExit, secondExit},
+		},
+		{
+			name:    "skip the duplicated and invalid contexts",
+			headers: []Header{first, MapHeader{}, first, MapHeader{propagation.Header: "invalid"}},
+			refs:    []go2sky.ReportedSpan{firstExit},
+		},
+		{
+			name:    "no context",
+			headers: []Header{MapHeader{}},
+		},
+	}
+	consumer, err := NewConsumer(tracer, WithName("Kafka"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span, _, err := consumer.CreateBatchSpan(context.Background(), "orders", tt.headers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			End(span, nil)
+			entry := reporter.Segment(t)[0]
+			if entry.SpanType() != agentv3.SpanType_Entry || entry.OperationName() != "Kafka/orders/Consumer" {
+				t.Errorf("unexpected span %s: type %v", entry.OperationName(), entry.SpanType())
+			}
+			if len(entry.Refs()) != len(tt.refs) {
+				t.Fatalf("the expected %d refs, current is %d", len(tt.refs), len(entry.Refs()))
+			}
+			for i, ref := range entry.Refs() {
+				if ref.ParentSegmentID != tt.refs[i].Context().SegmentID || ref.TraceID != tt.refs[i].Context().TraceID {
+					t.Errorf("the ref %d does not refer to the producer span", i)
+				}
+			}
+		})
+	}
+}
+
+func TestNewProducer_InvalidBroker(t *testing.T) {
+	tracer, _ := newTracer(t)
+	if _, err := NewProducer(tracer, WithName("Kafka")); err != errInvalidBroker {
+		t.Errorf("the expected invalid broker error, current is %v", err)
+	}
+}
+
+func TestNewProducer_InvalidTracer(t *testing.T) {
+	if _, err := NewProducer(nil); err != errInvalidTracer {
+		t.Errorf("the expected invalid tracer error, current is %v", err)
+	}
+	if _, err := NewConsumer(nil); err != errInvalidTracer {
+		t.Errorf("the expected invalid tracer error, current is %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mq
+
+import (
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/tool"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+const (
+	errInvalidTracer = tool.Error("invalid tracer")
+	errInvalidBroker = tool.Error("invalid broker")
+)
+
+const defaultName = "MQ"
+
+type config struct {
+	tracer    *go2sky.Tracer
+	name      string
+	broker    string
+	component int32
+	extraTags map[string]string
+}
+
+// Option allows for functional options to adjust behaviour of the producers and the consumers
+type Option func(*config)
+
+// WithName setup the name of the message queue, it prefixes the operation names, such as Kafka
+func WithName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
+
+// WithBroker setup the broker addresses, it is the peer of the producer spans and required by the producers
+func WithBroker(broker string) Option {
+	return func(c *config) {
+		c.broker = broker
+	}
+}
+
+// WithComponent setup the component id of the spans
+func WithComponent(id int32) Option {
+	return func(c *config) {
+		c.component = id
+	}
+}
+
+// WithTag setup an extra tag of the spans
+func WithTag(key string, value string) Option {
+	return func(c *config) {
+		if c.extraTags == nil {
+			c.extraTags = make(map[string]string)
+		}
+		c.extraTags[key] = value
+	}
+}
+
+func newConfig(tracer *go2sky.Tracer, options []Option) (*config, error) {
+	if tracer == nil {
+		return nil, errInvalidTracer
+	}
+	c := &config{tracer: tracer, name: defaultName}
+	for _, option := range options {
+		option(c)
+	}
+	return c, nil
+}
+
+func (c *config) decorate(span go2sky.Span, topic string) {
+	if c.component != 0 {
+		span.SetComponent(c.component)
+	}
+	span.SetSpanLayer(agentv3.SpanLayer_MQ)
+	for k, v := range c.extraTags {
+		span.Tag(go2sky.Tag(k), v)
+	}
+	span.Tag(go2sky.TagMQTopic, topic)
+	if c.broker != "" {
+		span.Tag(go2sky.TagMQBroker, c.broker)
+	}
+}
+
+// End ends the span created by the producers or the consumers, the span is marked as error if err is not nil
+func End(span go2sky.Span, err error) {
+	if err != nil {
+		span.Error(time.Now(), err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,57 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mq
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SkyAPM/go2sky"
+)
+
+// Producer creates the exit spans of sending the messages
+type Producer struct {
+	config *config
+}
+
+// NewProducer returns the Producer creating the spans by the tracer, the broker is required by WithBroker
+func NewProducer(tracer *go2sky.Tracer, options ...Option) (*Producer, error) {
+	c, err := newConfig(tracer, options)
+	if err != nil {
+		return nil, err
+	}
+	if c.broker == "" {
+		return nil, errInvalidBroker
+	}
+	return &Producer{config: c}, nil
+}
+
+// CreateSpan creates the exit span of sending the message to the topic,
+// the SkyWalking context is injected into the header of the message.
+// The span should be ended by End after the message is sent.
+func (p *Producer) CreateSpan(ctx context.Context, topic string, header Header) (go2sky.Span, error) {
+	span, err := p.config.tracer.CreateExitSpan(ctx, fmt.Sprintf("%s/%s/Producer", p.config.name, topic), p.config.broker,
+		func(key, value string) error {
+			header.Set(key, value)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	p.config.decorate(span, topic)
+	return span, nil
+}
@@ -0,0 +1,83 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package redis
+
+import (
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	cacheOpRead  = "read"
+	cacheOpWrite = "write"
+)
+
+// readCommands and writeCommands are the commands on the keys, the key is the first argument
+var (
+	readCommands = commandSet(
+		"get", "getrange", "getbit", "mget", "strlen", "exists", "ttl", "pttl", "type",
+		"hget", "hmget", "hgetall", "hkeys", "hvals", "hlen", "hexists", "hscan", "hstrlen",
+		"lindex", "llen", "lrange", "scard", "sismember", "smembers", "srandmember", "sscan",
+		"zcard", "zcount", "zlexcount", "zrange", "zrangebylex", "zrangebyscore", "zrank",
+		"zrevrange", "zrevrangebylex", "zrevrangebyscore", "zrevrank", "zscore", "zscan",
+		"pfcount", "geodist", "geohash", "geopos", "georadius_ro", "georadiusbymember_ro",
+		"xlen", "xrange", "xrevrange", "getex",
+	)
+	writeCommands = commandSet(
+		"set", "setex", "setnx", "setrange", "setbit", "psetex", "mset", "msetnx", "getset", "getdel",
+		"append", "incr", "incrby", "incrbyfloat", "decr", "decrby", "del", "unlink",
+		"expire", "expireat", "pexpire", "pexpireat", "persist", "rename", "renamenx",
+		"hset", "hsetnx", "hmset", "hdel", "hincrby", "hincrbyfloat",
+		"lpush", "lpushx", "rpush", "rpushx", "lpop", "rpop", "blpop", "brpop", "linsert", "lrem",
+		"lset", "ltrim", "rpoplpush", "brpoplpush", "lmove", "blmove",
+		"sadd", "srem", "spop", "smove", "sdiffstore", "sinterstore", "sunionstore",
+		"zadd", "zincrby", "zrem", "zremrangebylex", "zremrangebyrank", "zremrangebyscore",
+		"zpopmin", "zpopmax", "zinterstore", "zunionstore", "pfadd", "pfmerge", "geoadd",
+		"xadd", "xdel", "xtrim",
+	)
+)
+
+func commandSet(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// cacheOp returns the cache operation of the command, it is empty if the command is not on the keys
+func cacheOp(cmd redis.Cmder) string {
+	switch name := cmd.Name(); {
+	case writeCommands[name]:
+		return cacheOpWrite
+	case readCommands[name]:
+		return cacheOpRead
+	}
+	return ""
+}
+
+// cacheKey returns the key of the command, it is empty if the command is not on the keys
+func cacheKey(cmd redis.Cmder) string {
+	if cacheOp(cmd) == "" {
+		return ""
+	}
+	if args := cmd.Args(); len(args) > 1 {
+		return fmt.Sprint(args[1])
+	}
+	return ""
+}
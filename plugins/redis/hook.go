@@ -0,0 +1,160 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/redis/go-redis/v9"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+type hook struct {
+	config *config
+}
+
+// NewHook returns the hook creating an exit span for each command and each pipeline,
+// the addresses of the server are required by WithPeer
+func NewHook(tracer *go2sky.Tracer, options ...Option) (redis.Hook, error) {
+	c, err := newConfig(tracer, options)
+	if err != nil {
+		return nil, err
+	}
+	return &hook{config: c}, nil
+}
+
+// InstrumentClient adds the hook to the client, the addresses of the client are the peer by default
+func InstrumentClient(client redis.UniversalClient, tracer *go2sky.Tracer, options ...Option) error {
+	if peer := clientPeer(client); peer != "" {
+		options = append([]Option{WithPeer(peer)}, options...)
+	}
+	h, err := NewHook(tracer, options...)
+	if err != nil {
+		return err
+	}
+	client.AddHook(h)
+	return nil
+}
+
+func clientPeer(client redis.UniversalClient) string {
+	switch c := client.(type) {
+	case *redis.Client:
+		return c.Options().Addr
+	case *redis.ClusterClient:
+		return strings.Join(c.Options().Addrs, ",")
+	case *redis.Ring:
+		addrs := make([]string, 0, len(c.Options().Addrs))
+		for _, addr := range c.Options().Addrs {
+			addrs = append(addrs, addr)
+		}
+		sort.Strings(addrs)
+		return strings.Join(addrs, ",")
+	}
+	return ""
+}
+
+func (h *hook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		span, err := h.createSpan(ctx, strings.ToUpper(cmd.Name()))
+		if err != nil {
+			return next(ctx, cmd)
+		}
+		span.Tag(go2sky.TagCacheCmd, cmd.Name())
+		if op := cacheOp(cmd); op != "" {
+			span.Tag(go2sky.TagCacheOp, op)
+		}
+		if key := cacheKey(cmd); key != "" && h.config.keyMaxLength > 0 {
+			span.Tag(go2sky.TagCacheKey, truncate(key, h.config.keyMaxLength))
+		}
+		err = next(ctx, cmd)
+		endSpan(span, err)
+		return err
+	}
+}
+
+func (h *hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		span, err := h.createSpan(ctx, "pipeline")
+		if err != nil {
+			return next(ctx, cmds)
+		}
+		names := make([]string, 0, len(cmds))
+		keys := make([]string, 0, len(cmds))
+		op := ""
+		for _, cmd := range cmds {
+			names = append(names, cmd.Name())
+			if key := cacheKey(cmd); key != "" {
+				keys = append(keys, key)
+			}
+			// the pipeline writes if any of its commands writes
+			if cmdOp := cacheOp(cmd); cmdOp == cacheOpWrite || op == "" {
+				op = cmdOp
+			}
+		}
+		span.Tag(go2sky.TagCacheCmd, strings.Join(names, ","))
+		if op != "" {
+			span.Tag(go2sky.TagCacheOp, op)
+		}
+		if len(keys) > 0 && h.config.keyMaxLength > 0 {
+			span.Tag(go2sky.TagCacheKey, truncate(strings.Join(keys, ","), h.config.keyMaxLength))
+		}
+		err = next(ctx, cmds)
+		// the span is marked as error by the failed command even if the pipeline succeeds
+		spanErr := err
+		for _, cmd := range cmds {
+			if spanErr != nil && !errors.Is(spanErr, redis.Nil) {
+				break
+			}
+			spanErr = cmd.Err()
+		}
+		endSpan(span, spanErr)
+		return err
+	}
+}
+
+func (h *hook) createSpan(ctx context.Context, operation string) (go2sky.Span, error) {
+	span, err := h.config.tracer.CreateExitSpan(ctx, cacheType+"/"+operation, h.config.peer, func(key, value string) error {
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	span.SetComponent(componentIDRedis)
+	span.SetSpanLayer(agentv3.SpanLayer_Cache)
+	for k, v := range h.config.extraTags {
+		span.Tag(go2sky.Tag(k), v)
+	}
+	span.Tag(go2sky.TagCacheType, cacheType)
+	return span, nil
+}
+
+// endSpan ends the span, the span is marked as error unless the error is redis.Nil of the missing keys
+func endSpan(span go2sky.Span, err error) {
+	if err != nil && !errors.Is(err, redis.Nil) {
+		span.Error(time.Now(), err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,87 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package redis
+
+import (
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/tool"
+)
+
+const (
+	errInvalidTracer = tool.Error("invalid tracer")
+	errInvalidPeer   = tool.Error("invalid peer")
+)
+
+const (
+	componentIDRedis = 7
+	cacheType        = "Redis"
+)
+
+type config struct {
+	tracer       *go2sky.Tracer
+	peer         string
+	keyMaxLength int
+	extraTags    map[string]string
+}
+
+// Option allows for functional options to adjust behaviour of the hook
+type Option func(*config)
+
+// WithPeer setup the peer of the spans, it is required by NewHook, the addresses of the client are used by InstrumentClient
+func WithPeer(peer string) Option {
+	return func(c *config) {
+		c.peer = peer
+	}
+}
+
+// WithCacheKey enables the cache.key tag, the keys are truncated to maxLength
+func WithCacheKey(maxLength int) Option {
+	return func(c *config) {
+		c.keyMaxLength = maxLength
+	}
+}
+
+// WithTag setup an extra tag of the spans
+func WithTag(key string, value string) Option {
+	return func(c *config) {
+		if c.extraTags == nil {
+			c.extraTags = make(map[string]string)
+		}
+		c.extraTags[key] = value
+	}
+}
+
+func newConfig(tracer *go2sky.Tracer, options []Option) (*config, error) {
+	if tracer == nil {
+		return nil, errInvalidTracer
+	}
+	c := &config{tracer: tracer}
+	for _, option := range options {
+		option(c)
+	}
+	if c.peer == "" {
+		return nil, errInvalidPeer
+	}
+	return c, nil
+}
+
+func truncate(s string, maxLength int) string {
+	if len(s) <= maxLength {
+		return s
+	}
+	return s[:maxLength] + "..."
+}
@@ -0,0 +1,188 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package redis
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/SkyAPM/go2sky/internal/testutil"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+)
+
+// traceRedis runs fn with the client connected to a fake redis server in a local span,
+// then returns the exit spans of the segment
+func traceRedis(t *testing.T, fn func(ctx context.Context, client *redis.Client), options ...Option) ([]go2sky.ReportedSpan, string) {
+	server := miniredis.RunT(t)
+	reporter := testutil.NewReporter(1)
+	tracer, err := go2sky.NewTracer("service", go2sky.WithReporter(reporter), go2sky.WithInstance("instance"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	if err = InstrumentClient(client, tracer, options...); err != nil {
+		t.Fatal(err)
+	}
+
+	span, ctx, err := tracer.CreateLocalSpan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn(ctx, client)
+	span.End()
+
+	select {
+	case segment := <-reporter.Segments:
+		var exits []go2sky.ReportedSpan
+		for _, s := range segment {
+			if s.SpanType() == agentv3.SpanType_Exit {
+				exits = append(exits, s)
+			}
+		}
+		sort.Slice(exits, func(i, j int) bool {
+			return exits[i].Context().SpanID < exits[j].Context().SpanID
+		})
+		return exits, server.Addr()
+	case <-time.After(5 * time.Second):
+		t.Fatal("the segment is not reported")
+	}
+	return nil, ""
+}
+
+func TestHook(t *testing.T) {
+	tests := []struct {
+		name   string
+		fn     func(ctx context.Context, client *redis.Client)
+		expect []string
+	}{
+		{
+			name: "commands",
+			fn: func(ctx context.Context, client *redis.Client) {
+				client.Set(ctx, "user:1", "alice", 0)
+				client.Get(ctx, "user:1")
+				client.Get(ctx, "user:2")
+				client.Ping(ctx)
+			},
+			expect: []string{
+				"Redis/SET|set|write|user:1|false",
+				"Redis/GET|get|read|user:1|false",
+				"Redis/GET|get|read|user:2|false",
+				"Redis/PING|ping|||false",
+			},
+		},
+		{
+			name: "error",
+			fn: func(ctx context.Context, client *redis.Client) {
+				client.Set(ctx, "name", "alice", 0)
+				client.Incr(ctx, "name")
+			},
+			expect: []string{
+				"Redis/SET|set|write|name|false",
+				"Redis/INCR|incr|write|name|true",
+			},
+		},
+		{
+			name: "pipeline",
+			fn: func(ctx context.Context, client *redis.Client) {
+				_, _ = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+					pipe.Get(ctx, "a")
+					pipe.HGet(ctx, "b", "field")
+					return nil
+				})
+				_, _ = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+					pipe.Get(ctx, "a")
+					pipe.Set(ctx, "c", "1", 0)
+					pipe.Incr(ctx, "c")
+					pipe.LPush(ctx, "c", "1")
+					return nil
+				})
+			},
+			expect: []string{
+				"Redis/pipeline|get,hget|read|a,b|false",
+				"Redis/pipeline|get,set,incr,lpush|write|a,c,c,c|true",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans, addr := traceRedis(t, tt.fn, WithCacheKey(16))
+			var actual []string
+			for _, s := range spans {
+				if s.Peer() != addr || s.ComponentID() != componentIDRedis || s.SpanLayer() != agentv3.SpanLayer_Cache ||
+					testutil.TagValue(s, go2sky.TagCacheType) != "Redis" {
+					t.Errorf("unexpected span %s: peer %s, component %d, tags %v", s.OperationName(), s.Peer(), s.ComponentID(), s.Tags())
+				}
+				actual = append(actual, strings.Join([]string{s.OperationName(), testutil.TagValue(s, go2sky.TagCacheCmd),
+					testutil.TagValue(s, go2sky.TagCacheOp), testutil.TagValue(s, go2sky.TagCacheKey), strconv.FormatBool(s.IsError())}, "|"))
+			}
+			if strings.Join(actual, "\n") != strings.Join(tt.expect, "\n") {
+				t.Errorf("the expected spans\n%s\ncurrent is\n%s", strings.Join(tt.expect, "\n"), strings.Join(actual, "\n"))
+			}
+		})
+	}
+}
+
+func TestHook_CacheKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		expect  string
+	}{
+		{name: "disabled"},
+		{name: "truncated", options: []Option{WithCacheKey(8)}, expect: "session:..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans, _ := traceRedis(t, func(ctx context.Context, client *redis.Client) {
+				client.Get(ctx, "session:0123456789")
+			}, tt.options...)
+			if len(spans) != 1 {
+				t.Fatalf("the expected 1 span, current is %d", len(spans))
+			}
+			if key := testutil.TagValue(spans[0], go2sky.TagCacheKey); key != tt.expect {
+				t.Errorf("the expected key %q, current is %q", tt.expect, key)
+			}
+		})
+	}
+}
+
+func TestNewHook_InvalidPeer(t *testing.T) {
+	tracer, err := go2sky.NewTracer("service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewHook(tracer); err != errInvalidPeer {
+		t.Errorf("the expected invalid peer error, current is %v", err)
+	}
+	if _, err = NewHook(tracer, WithPeer("redis:6379")); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestNewHook_InvalidTracer(t *testing.T) {
+	if _, err := NewHook(nil); err != errInvalidTracer {
+		t.Errorf("the expected invalid tracer error, current is %v", err)
+	}
+}
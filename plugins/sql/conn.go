@@ -0,0 +1,185 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+)
+
+var (
+	errNamedParameters = errors.New("sql: driver does not support the use of Named Parameters")
+	errIsolationLevel  = errors.New("sql: driver does not support non-default isolation level")
+	errReadOnly        = errors.New("sql: driver does not support read-only transactions")
+)
+
+// conn traces the connection, the optional interfaces not implemented by the driver
+// fall back to the behaviour of database/sql
+type conn struct {
+	driver.Conn
+	cfg *config
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	span := c.cfg.createSpan(ctx, "Prepare", query, nil)
+	var s driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err = p.PrepareContext(ctx, query)
+	} else {
+		s, err = c.Conn.Prepare(query)
+	}
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: s, conn: c.Conn, query: query, cfg: c.cfg}, nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	span := c.cfg.createSpan(ctx, "Begin", "", nil)
+	var t driver.Tx
+	var err error
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		t, err = b.BeginTx(ctx, opts)
+	} else if opts.Isolation != driver.IsolationLevel(0) {
+		err = errIsolationLevel
+	} else if opts.ReadOnly {
+		err = errReadOnly
+	} else {
+		t, err = c.Conn.Begin() //nolint:staticcheck
+	}
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{Tx: t, ctx: ctx, cfg: c.cfg}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execerCtx, ok := c.Conn.(driver.ExecerContext)
+	execer, ok1 := c.Conn.(driver.Execer) //nolint:staticcheck
+	if !ok && !ok1 {
+		return nil, driver.ErrSkip
+	}
+	span := c.cfg.createSpan(ctx, "Exec", query, args)
+	var res driver.Result
+	var err error
+	if ok {
+		res, err = execerCtx.ExecContext(ctx, query, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			res, err = execer.Exec(query, values)
+		}
+	}
+	endSpan(span, err)
+	return res, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryerCtx, ok := c.Conn.(driver.QueryerContext)
+	queryer, ok1 := c.Conn.(driver.Queryer) //nolint:staticcheck
+	if !ok && !ok1 {
+		return nil, driver.ErrSkip
+	}
+	span := c.cfg.createSpan(ctx, "Query", query, args)
+	var r driver.Rows
+	var err error
+	if ok {
+		r, err = queryerCtx.QueryContext(ctx, query, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			r, err = queryer.Query(query, values)
+		}
+	}
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return c.cfg.wrapRows(ctx, query, r), nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// tx traces the commit and rollback in the context beginning the transaction
+type tx struct {
+	driver.Tx
+	ctx context.Context
+	cfg *config
+}
+
+func (t *tx) Commit() error {
+	span := t.cfg.createSpan(t.ctx, "Commit", "", nil)
+	err := t.Tx.Commit()
+	endSpan(span, err)
+	return err
+}
+
+func (t *tx) Rollback() error {
+	span := t.cfg.createSpan(t.ctx, "Rollback", "", nil)
+	err := t.Tx.Rollback()
+	endSpan(span, err)
+	return err
+}
+
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errNamedParameters
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
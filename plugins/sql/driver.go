@@ -0,0 +1,137 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+
+	"github.com/SkyAPM/go2sky"
+)
+
+// Open opens the database like sql.Open, the connections of the registered driver are traced.
+// The peer is parsed from the data source name unless it is set by WithPeer, it is unknown if it could not be parsed.
+func Open(driverName, dataSourceName string, tracer *go2sky.Tracer, dbType DBType, options ...Option) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	if err = db.Close(); err != nil {
+		return nil, err
+	}
+	wrapped, err := Wrap(d, tracer, dbType, options...)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := wrapped.(driver.DriverContext).OpenConnector(dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+// Wrap wraps the driver to trace the connections opened by it, the peer is parsed from the data source name
+// of each connection unless it is set by WithPeer
+func Wrap(d driver.Driver, tracer *go2sky.Tracer, dbType DBType, options ...Option) (driver.Driver, error) {
+	c, err := newConfig(tracer, dbType, options)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedDriver{Driver: d, cfg: c}, nil
+}
+
+// WrapConnector wraps the connector to trace the connections opened by it, the result could be used by sql.OpenDB.
+// The peer is required since the data source name of the connector is unknown.
+func WrapConnector(connector driver.Connector, tracer *go2sky.Tracer, dbType DBType, options ...Option) (driver.Connector, error) {
+	c, err := newConfig(tracer, dbType, options)
+	if err != nil {
+		return nil, err
+	}
+	if c.peer == "" {
+		return nil, errInvalidPeer
+	}
+	return &tracedConnector{Connector: connector, driver: &tracedDriver{Driver: connector.Driver(), cfg: c}, cfg: c}, nil
+}
+
+type tracedDriver struct {
+	driver.Driver
+	cfg *config
+}
+
+func (d *tracedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, cfg: d.cfg.forDataSource(name)}, nil
+}
+
+// OpenConnector implements driver.DriverContext, the driver without it is connected by the name
+func (d *tracedDriver) OpenConnector(name string) (driver.Connector, error) {
+	cfg := d.cfg.forDataSource(name)
+	if dc, ok := d.Driver.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &tracedConnector{Connector: connector, driver: d, cfg: cfg}, nil
+	}
+	return &tracedConnector{Connector: &dsnConnector{name: name, driver: d.Driver}, driver: d, cfg: cfg}, nil
+}
+
+type tracedConnector struct {
+	driver.Connector
+	driver driver.Driver
+	cfg    *config
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: cn, cfg: c.cfg}, nil
+}
+
+func (c *tracedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Close closes the connector if it is an io.Closer, it is called by sql.DB.Close
+func (c *tracedConnector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// dsnConnector connects by the name like database/sql does for the drivers without driver.DriverContext
+type dsnConnector struct {
+	name   string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
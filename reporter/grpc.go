@@ -20,13 +20,18 @@ import (
 	"context"
 	"io"
 	"log"
+	"math"
 	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/SkyAPM/go2sky"
 	"github.com/SkyAPM/go2sky/internal/tool"
 	glog "github.com/SkyAPM/go2sky/log"
 	"github.com/SkyAPM/go2sky/logger"
+	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
@@ -44,6 +49,9 @@ const (
 	defaultCDSInterval         = 20 * time.Second
 	defaultLogPrefix           = "go2sky-gRPC"
 	authKey                    = "Authentication"
+
+	heartbeatPeriodKey               = "collector.heartbeat_period"
+	getAgentDynamicConfigIntervalKey = "collector.get_agent_dynamic_config_interval"
 )
 
 func applyGRPCReporterOption(r *gRPCReporter, opts ...GRPCReporterOption) error {
@@ -67,6 +75,10 @@ func NewGRPCReporter(serverAddr string, opts ...GRPCReporterOption) (go2sky.Repo
 		logCh:         make(chan *logv3.LogData, maxSendQueueSize),
 		checkInterval: defaultCheckInterval,
 		cdsInterval:   defaultCDSInterval, // cds default on
+
+		logBatchSize:     defaultLogBatchSize,
+		logFlushInterval: defaultLogFlushInterval,
+		logRetryInterval: defaultLogRetryInterval,
 	}
 
 	// init a cancel ctx and cancel function
@@ -81,6 +93,8 @@ func NewGRPCReporter(serverAddr string, opts ...GRPCReporterOption) (go2sky.Repo
 		r.meterCh = make(chan []*agentv3.MeterData, maxSendQueueSize)
 	}
 
+	r.logLimiter = newLogRateLimiter(r.logRateLimits)
+
 	var credsDialOption grpc.DialOption
 	if r.creds != nil {
 		// use tls
@@ -101,8 +115,14 @@ func NewGRPCReporter(serverAddr string, opts ...GRPCReporterOption) (go2sky.Repo
 	r.meterClient = agentv3.NewMeterReportServiceClient(r.conn)
 	r.logClient = logv3.NewLogReportServiceClient(r.conn)
 	if r.cdsInterval > 0 {
-		r.cdsClient = configuration.NewConfigurationDiscoveryServiceClient(r.conn)
-		r.cdsService = go2sky.NewConfigDiscoveryService()
+		if r.configSource == nil {
+			r.cdsClient = configuration.NewConfigurationDiscoveryServiceClient(r.conn)
+		}
+		r.dynamicCDSInterval = go2sky.NewDynamicConfig(getAgentDynamicConfigIntervalKey, r.cdsInterval, parseIntervalSeconds)
+		r.dynamicLogMinLevel = go2sky.NewDynamicConfig(logMinLevelKey, r.logMinLevel, parseLogMinLevel)
+	}
+	if r.checkInterval > 0 {
+		r.dynamicCheckInterval = go2sky.NewDynamicConfig(heartbeatPeriodKey, r.checkInterval, parseIntervalSeconds)
 	}
 	return r, nil
 }
@@ -110,11 +130,10 @@ func NewGRPCReporter(serverAddr string, opts ...GRPCReporterOption) (go2sky.Repo
 type gRPCReporter struct {
 	ctx              context.Context
 	cancelFunc       context.CancelFunc
-	service          string
-	serviceInstance  string
 	instanceProps    map[string]string
 	logger           logger.Log
 	sendCh           chan *agentv3.SegmentObject
+	flushCh          chan chan error // the flush requests of the send pipeline
 	meterCh          chan []*agentv3.MeterData
 	logCh            chan *logv3.LogData
 	conn             *grpc.ClientConn
@@ -125,8 +144,24 @@ type gRPCReporter struct {
 	checkInterval    time.Duration
 	cdsInterval      time.Duration
 	meterInterval    *time.Duration
-	cdsService       *go2sky.ConfigDiscoveryService
+	hostMetricsOff   bool
+	metricsReporters []go2sky.MetricsReporter
 	cdsClient        configuration.ConfigurationDiscoveryServiceClient
+	configSource     go2sky.ConfigSource
+
+	// the intervals which could be changed by CDS
+	dynamicCheckInterval *go2sky.DynamicConfig[time.Duration]
+	dynamicCDSInterval   *go2sky.DynamicConfig[time.Duration]
+
+	// the log batching, filtering and the dropped logs
+	logBatchSize       int
+	logFlushInterval   time.Duration
+	logRetryInterval   time.Duration
+	logMinLevel        go2sky.LogLevel
+	logRateLimits      map[go2sky.LogLevel]int
+	logLimiter         *logRateLimiter
+	logDropped         logDropCounter
+	dynamicLogMinLevel *go2sky.DynamicConfig[go2sky.LogLevel]
 
 	// set report strategy
 	rs ReportStrategy
@@ -134,8 +169,13 @@ type gRPCReporter struct {
 	md    metadata.MD
 	creds credentials.TransportCredentials
 
-	// bootFlag is set if Boot be executed
-	bootFlag bool
+	// bootFlag is set if Boot be executed, the identities count the boots of each service instance,
+	// the boots are kept in order and the first one is the primary, bootMu guards them
+	bootMu     sync.Mutex
+	bootFlag   bool
+	identities map[serviceIdentity]int
+	boots      []*identityBoot
+	closeOnce  sync.Once
 
 	// Instance belong layer name which define in the backend
 	layer string
@@ -145,18 +185,220 @@ type gRPCReporter struct {
 	processStatusHookEnable bool
 }
 
+// serviceIdentity is a service instance reported by the reporter
+type serviceIdentity struct {
+	service         string
+	serviceInstance string
+}
+
+// identityBoot is a boot of a service instance, the dynamic configurations of its service are fetched
+// for the watchers of the booting tracer
+type identityBoot struct {
+	id       serviceIdentity
+	watchers []go2sky.AgentConfigChangeWatcher // the watchers of the tracer, without the ones of the reporter
+	cds      *go2sky.ConfigDiscoveryService
+}
+
+// Boot starts the pipelines reporting as the service instance, the repeated boots don't start them again.
 func (r *gRPCReporter) Boot(service string, serviceInstance string, cdsWatchers []go2sky.AgentConfigChangeWatcher) {
-	r.service = service
-	r.serviceInstance = serviceInstance
-	r.initSendPipeline()
-	r.check()
-	r.initCDS(cdsWatchers)
-	r.initMetricsCollector()
-	r.initSendLogPipeline()
-	r.bootFlag = true
+	r.boot(serviceIdentity{service: service, serviceInstance: serviceInstance}, cdsWatchers)
+}
+
+// boot starts the pipelines by the first boot, then the heartbeat of each new service instance.
+// Every boot fetches the dynamic configurations of its service for its watchers, the watchers of
+// the reporter are bound to the primary boot, which also reports the meters and the process status.
+func (r *gRPCReporter) boot(id serviceIdentity, cdsWatchers []go2sky.AgentConfigChangeWatcher) *identityBoot {
+	r.bootMu.Lock()
+	defer r.bootMu.Unlock()
+	if r.identities == nil {
+		r.identities = make(map[serviceIdentity]int)
+	}
+	b := &identityBoot{id: id, watchers: r.tracerWatchers(cdsWatchers)}
+	r.boots = append(r.boots, b)
+	r.identities[id]++
+	if !r.bootFlag {
+		r.initSendPipeline()
+	}
+	if r.identities[id] == 1 {
+		r.check(id)
+	}
+	r.initCDS(b)
+	if !r.bootFlag {
+		r.initMetricsCollector()
+		r.initSendLogPipeline()
+		r.bootFlag = true
+	}
+	return b
+}
+
+// release releases the boot, the primary role moves to the earliest remaining boot,
+// and the reporter is closed when all of them are released
+func (r *gRPCReporter) release(b *identityBoot) {
+	r.bootMu.Lock()
+	for i, booted := range r.boots {
+		if booted != b {
+			continue
+		}
+		r.boots = append(r.boots[:i:i], r.boots[i+1:]...)
+		if i == 0 && len(r.boots) > 0 {
+			r.bindWatchers(r.boots[0])
+		}
+		break
+	}
+	if r.identities[b.id]--; r.identities[b.id] > 0 {
+		r.bootMu.Unlock()
+		return
+	}
+	delete(r.identities, b.id)
+	last := len(r.identities) == 0
+	r.bootMu.Unlock()
+	if last {
+		r.Close()
+	}
+}
+
+// released returns whether the boot is released
+func (r *gRPCReporter) released(b *identityBoot) bool {
+	r.bootMu.Lock()
+	defer r.bootMu.Unlock()
+	for _, booted := range r.boots {
+		if booted == b {
+			return false
+		}
+	}
+	return true
+}
+
+// tracerWatchers removes the watchers of the reporter, which are only bound to the primary boot
+func (r *gRPCReporter) tracerWatchers(watchers []go2sky.AgentConfigChangeWatcher) []go2sky.AgentConfigChangeWatcher {
+	own := r.DynamicConfigs()
+	result := make([]go2sky.AgentConfigChangeWatcher, 0, len(watchers))
+WatcherLoop:
+	for _, w := range watchers {
+		for _, o := range own {
+			if w == o {
+				continue WatcherLoop
+			}
+		}
+		result = append(result, w)
+	}
+	return result
+}
+
+// bindWatchers binds the watchers of the boot, with the ones of the reporter if it is the primary boot
+func (r *gRPCReporter) bindWatchers(b *identityBoot) {
+	if b.cds == nil {
+		return
+	}
+	watchers := b.watchers
+	if len(r.boots) > 0 && r.boots[0] == b {
+		watchers = append(r.DynamicConfigs(), watchers...)
+	}
+	b.cds.BindWatchers(watchers)
+}
+
+// booted returns whether the service instance is booted and not released
+func (r *gRPCReporter) booted(id serviceIdentity) bool {
+	r.bootMu.Lock()
+	defer r.bootMu.Unlock()
+	return r.identities[id] > 0
+}
+
+// primary returns the service instance of the primary boot
+func (r *gRPCReporter) primary() serviceIdentity {
+	r.bootMu.Lock()
+	defer r.bootMu.Unlock()
+	if len(r.boots) == 0 {
+		return serviceIdentity{}
+	}
+	return r.boots[0].id
+}
+
+// ForService implements go2sky.MultiServiceReporter, the service instances share the connection and the pipelines
+func (r *gRPCReporter) ForService(service string, serviceInstance string) go2sky.Reporter {
+	return &serviceReporter{r: r, id: serviceIdentity{service: service, serviceInstance: serviceInstance}}
+}
+
+// serviceReporter reports as a service instance by the shared gRPCReporter
+type serviceReporter struct {
+	r    *gRPCReporter
+	id   serviceIdentity
+	boot *identityBoot
+	// 0 not booted 1 booted 2 closed
+	state int32
+}
+
+func (s *serviceReporter) Boot(_ string, _ string, cdsWatchers []go2sky.AgentConfigChangeWatcher) {
+	if atomic.CompareAndSwapInt32(&s.state, 0, 1) {
+		s.boot = s.r.boot(s.id, cdsWatchers)
+	}
+}
+
+func (s *serviceReporter) Send(spans []go2sky.ReportedSpan) {
+	s.r.send(s.id, spans)
+}
+
+func (s *serviceReporter) SendLog(logData go2sky.ReportedLogData) {
+	s.r.sendLog(s.id, logData)
+}
+
+// Flush implements go2sky.FlushableReporter, it confirms the delivery of the segments of all the service instances
+func (s *serviceReporter) Flush(ctx context.Context) error {
+	return s.r.Flush(ctx)
+}
+
+// Close releases the service instance, the shared reporter is closed with the last one
+func (s *serviceReporter) Close() {
+	if atomic.CompareAndSwapInt32(&s.state, 1, 2) {
+		s.r.release(s.boot)
+	}
+}
+
+// DynamicConfigs implements go2sky.DynamicConfigReporter
+func (r *gRPCReporter) DynamicConfigs() (watchers []go2sky.AgentConfigChangeWatcher) {
+	if r.dynamicCheckInterval != nil {
+		watchers = append(watchers, r.dynamicCheckInterval)
+	}
+	if r.dynamicCDSInterval != nil {
+		watchers = append(watchers, r.dynamicCDSInterval)
+	}
+	if r.dynamicLogMinLevel != nil {
+		watchers = append(watchers, r.dynamicLogMinLevel)
+	}
+	return
+}
+
+func (r *gRPCReporter) heartbeatPeriod() time.Duration {
+	if r.dynamicCheckInterval != nil {
+		return r.dynamicCheckInterval.Get()
+	}
+	return r.checkInterval
+}
+
+func (r *gRPCReporter) fetchConfigInterval() time.Duration {
+	if r.dynamicCDSInterval != nil {
+		return r.dynamicCDSInterval.Get()
+	}
+	return r.cdsInterval
+}
+
+// parseIntervalSeconds parses the interval configured in seconds
+func parseIntervalSeconds(value string) (time.Duration, error) {
+	seconds, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		return 0, err
+	}
+	if seconds <= 0 {
+		return 0, errors.Errorf("interval %d must be positive", seconds)
+	}
+	return time.Duration(seconds) * time.Second, nil
 }
 
 func (r *gRPCReporter) Send(spans []go2sky.ReportedSpan) {
+	r.send(r.primary(), spans)
+}
+
+func (r *gRPCReporter) send(id serviceIdentity, spans []go2sky.ReportedSpan) {
 	spanSize := len(spans)
 	if spanSize < 1 {
 		return
@@ -167,8 +409,8 @@ func (r *gRPCReporter) Send(spans []go2sky.ReportedSpan) {
 		TraceId:         rootCtx.TraceID,
 		TraceSegmentId:  rootCtx.SegmentID,
 		Spans:           make([]*agentv3.SpanObject, spanSize),
-		Service:         r.service,
-		ServiceInstance: r.serviceInstance,
+		Service:         id.service,
+		ServiceInstance: id.serviceInstance,
 	}
 	for i, s := range spans {
 		spanCtx := s.Context()
@@ -193,8 +435,8 @@ func (r *gRPCReporter) Send(spans []go2sky.ReportedSpan) {
 				TraceId:               spanCtx.TraceID,
 				ParentTraceSegmentId:  spanCtx.ParentSegmentID,
 				ParentSpanId:          spanCtx.ParentSpanID,
-				ParentService:         r.service,
-				ParentServiceInstance: r.serviceInstance,
+				ParentService:         id.service,
+				ParentServiceInstance: id.serviceInstance,
 			})
 		}
 		if len(s.Refs()) > 0 {
@@ -226,26 +468,59 @@ func (r *gRPCReporter) Send(spans []go2sky.ReportedSpan) {
 	}
 }
 
-func (r *gRPCReporter) Close() {
-	// close meter collection goroutine
-	r.cancelFunc()
-	if r.meterCh != nil {
-		// close meter send channel
-		close(r.meterCh)
+// Flush blocks until the segments queued before are received by the backend or the context is done.
+// The send pipeline closes the stream to confirm the delivery, and opens a new one for the next segments.
+func (r *gRPCReporter) Flush(ctx context.Context) error {
+	r.bootMu.Lock()
+	booted := r.bootFlag
+	r.bootMu.Unlock()
+	if !booted || r.traceClient == nil {
+		return nil
 	}
-
-	if r.sendCh != nil && r.bootFlag {
-		close(r.sendCh)
-	} else {
-		r.closeGRPCConn()
-		cleanupProcessDirectory(r)
+	done := make(chan error, 1)
+	select {
+	case r.flushCh <- done:
+	case <-r.ctx.Done():
+		// the closing reporter sends the queued segments itself
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-
-	if r.logCh != nil {
-		close(r.logCh)
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
+// Close closes the pipelines and the connection, the repeated closes are ignored
+func (r *gRPCReporter) Close() {
+	r.closeOnce.Do(func() {
+		r.bootMu.Lock()
+		booted := r.bootFlag
+		r.bootMu.Unlock()
+
+		// close meter collection goroutine
+		r.cancelFunc()
+		if r.meterCh != nil {
+			// close meter send channel
+			close(r.meterCh)
+		}
+
+		if r.sendCh != nil && booted {
+			close(r.sendCh)
+		} else {
+			r.closeGRPCConn()
+			cleanupProcessDirectory(r)
+		}
+
+		if r.logCh != nil {
+			close(r.logCh)
+		}
+	})
+}
+
 func (r *gRPCReporter) closeGRPCConn() {
 	if r.conn != nil {
 		if err := r.conn.Close(); err != nil {
@@ -258,6 +533,7 @@ func (r *gRPCReporter) initSendPipeline() {
 	if r.traceClient == nil {
 		return
 	}
+	r.flushCh = make(chan chan error)
 	go func() {
 	StreamLoop:
 		for {
@@ -267,80 +543,140 @@ func (r *gRPCReporter) initSendPipeline() {
 				time.Sleep(5 * time.Second)
 				continue StreamLoop
 			}
-			for s := range r.sendCh {
-				if r.rs != nil && !r.rs(s) {
-					continue
-				}
-				err = stream.Send(s)
-				if err != nil {
-					r.logger.Errorf("send segment error %v", err)
-					r.closeStream(stream)
+			for {
+				select {
+				case s, ok := <-r.sendCh:
+					if !ok {
+						r.closeStream(stream)
+						r.closeGRPCConn()
+						return
+					}
+					if err = r.sendSegment(stream, s); err != nil {
+						r.closeStream(stream)
+						continue StreamLoop
+					}
+				case done := <-r.flushCh:
+					done <- r.flushStream(stream)
 					continue StreamLoop
 				}
 			}
-			r.closeStream(stream)
-			r.closeGRPCConn()
-			break
 		}
 	}()
 }
 
-func (r *gRPCReporter) initCDS(cdsWatchers []go2sky.AgentConfigChangeWatcher) {
-	if r.cdsClient == nil {
+func (r *gRPCReporter) sendSegment(stream agentv3.TraceSegmentReportService_CollectClient, s *agentv3.SegmentObject) error {
+	if r.rs != nil && !r.rs(s) {
+		return nil
+	}
+	err := stream.Send(s)
+	if err != nil {
+		r.logger.Errorf("send segment error %v", err)
+	}
+	return err
+}
+
+// flushStream sends the queued segments, then closes the stream, which returns after the backend receives all of them
+func (r *gRPCReporter) flushStream(stream agentv3.TraceSegmentReportService_CollectClient) error {
+	for n := len(r.sendCh); n > 0; n-- {
+		s, ok := <-r.sendCh
+		if !ok {
+			break
+		}
+		if err := r.sendSegment(stream, s); err != nil {
+			r.closeStream(stream)
+			return err
+		}
+	}
+	if _, err := stream.CloseAndRecv(); err != nil && err != io.EOF {
+		r.logger.Errorf("send segment stream closing error %v", err)
+		return err
+	}
+	return nil
+}
+
+// initCDS fetches the dynamic configurations of the service of the boot until it is released
+func (r *gRPCReporter) initCDS(b *identityBoot) {
+	if r.dynamicCDSInterval == nil {
 		return
 	}
+	source := r.configSource
+	if source == nil {
+		source = &grpcConfigSource{client: r.cdsClient, service: b.id.service, md: r.md}
+	}
 
 	// bind watchers
-	r.cdsService.BindWatchers(cdsWatchers)
+	b.cds = go2sky.NewConfigDiscoveryService(go2sky.WithConfigDiscoveryLogger(r.logger))
+	r.bindWatchers(b)
 
 	// fetch config
 	go func() {
 		for {
-			if r.conn.GetState() == connectivity.Shutdown {
+			if r.conn.GetState() == connectivity.Shutdown || r.released(b) {
 				break
 			}
 
-			configurations, err := r.cdsClient.FetchConfigurations(context.Background(), &configuration.ConfigurationSyncRequest{
-				Service: r.service,
-				Uuid:    r.cdsService.UUID,
-			})
-
+			version, configs, err := source.Fetch(context.Background(), b.cds.Version())
 			if err != nil {
 				r.logger.Errorf("fetch dynamic configuration error %v", err)
-				time.Sleep(r.cdsInterval)
+				time.Sleep(r.fetchConfigInterval())
 				continue
 			}
+			b.cds.Apply(version, configs)
 
-			if len(configurations.GetCommands()) > 0 && configurations.GetCommands()[0].Command == "ConfigurationDiscoveryCommand" {
-				command := configurations.GetCommands()[0]
-				r.cdsService.HandleCommand(command)
-			}
-
-			time.Sleep(r.cdsInterval)
+			time.Sleep(r.fetchConfigInterval())
 		}
 	}()
 }
 
+// grpcConfigSource fetches the configurations from the Configuration Discovery Service of the backend
+type grpcConfigSource struct {
+	client  configuration.ConfigurationDiscoveryServiceClient
+	service string
+	md      metadata.MD
+}
+
+func (s *grpcConfigSource) Fetch(ctx context.Context, currentVersion string) (string, map[string]string, error) {
+	configurations, err := s.client.FetchConfigurations(metadata.NewOutgoingContext(ctx, s.md), &configuration.ConfigurationSyncRequest{
+		Service: s.service,
+		Uuid:    currentVersion,
+	})
+	if err != nil {
+		return currentVersion, nil, err
+	}
+
+	if len(configurations.GetCommands()) > 0 && configurations.GetCommands()[0].Command == "ConfigurationDiscoveryCommand" {
+		version, configs := go2sky.CommandConfigurations(configurations.GetCommands()[0])
+		return version, configs, nil
+	}
+	return currentVersion, nil, nil
+}
+
 func (r *gRPCReporter) initMetricsCollector() {
 	if r.meterInterval != nil && *r.meterInterval <= 0 {
 		r.logger.Info("user choose to close the meter collection")
 		return
 	}
-	go2sky.InitMetricCollector(r, r.meterInterval, r.ctx)
+	var reporter go2sky.MetricsReporter = r
+	if len(r.metricsReporters) > 0 {
+		reporter = go2sky.NewFanOutMetricsReporter(append([]go2sky.MetricsReporter{r}, r.metricsReporters...)...)
+	}
+	go2sky.InitMetricCollector(reporter, r.meterInterval, r.ctx, go2sky.WithHostMetrics(!r.hostMetricsOff))
 	r.initSendMeterPipeline()
 }
 
+// SendMetrics reports the meters as the service instance of the primary boot
 func (r *gRPCReporter) SendMetrics(m go2sky.RunTimeMetric) {
-
-	meterDataList := make([]*agentv3.MeterData, 0)
-	meterDataList = append(meterDataList, r.generateMeter(go2sky.InstanceGolangHeap, float64(m.HeapAlloc), m.Time))
-	meterDataList = append(meterDataList, r.generateMeter(go2sky.InstanceGolangStack, float64(m.StackInUse), m.Time))
-	meterDataList = append(meterDataList, r.generateMeter(go2sky.InstanceGolangGCTime, float64(m.GCPauseTime), m.Time))
-	meterDataList = append(meterDataList, r.generateMeter(go2sky.InstanceGolangGCCount, float64(m.GCCount), m.Time))
-	meterDataList = append(meterDataList, r.generateMeter(go2sky.InstanceGolangThreadNum, float64(m.ThreadNum), m.Time))
-	meterDataList = append(meterDataList, r.generateMeter(go2sky.InstanceGolangGoroutineNum, float64(m.GoroutineNum), m.Time))
-	meterDataList = append(meterDataList, r.generateMeter(go2sky.InstanceCPUUsedRate, m.CpuUsedRate, m.Time))
-	meterDataList = append(meterDataList, r.generateMeter(go2sky.InstanceMemUsedRate, m.MemUsedRate, m.Time))
+	id := r.primary()
+	meterValues := m.MeterValues()
+	meterDataList := make([]*agentv3.MeterData, 0, len(meterValues))
+	for _, meter := range meterValues {
+		meterDataList = append(meterDataList, r.generateMeter(id, meter, m.Time))
+	}
+	if r.logClient != nil {
+		for _, meter := range r.logDropped.collect() {
+			meterDataList = append(meterDataList, r.generateMeter(id, meter, m.Time))
+		}
+	}
 
 	defer func() {
 		// recover the panic caused by close sendCh
@@ -359,18 +695,42 @@ func (r *gRPCReporter) SendMetrics(m go2sky.RunTimeMetric) {
 	}
 }
 
-func (r *gRPCReporter) generateMeter(name string, value float64, time int64) *agentv3.MeterData {
-	return &agentv3.MeterData{
-		Metric: &agentv3.MeterData_SingleValue{
+func (r *gRPCReporter) generateMeter(id serviceIdentity, meter go2sky.MeterValue, time int64) *agentv3.MeterData {
+	labels := make([]*agentv3.Label, 0, len(meter.Labels))
+	for _, l := range meter.Labels {
+		labels = append(labels, &agentv3.Label{Name: l.Name, Value: l.Value})
+	}
+	data := &agentv3.MeterData{
+		Timestamp:       time,
+		Service:         id.service,
+		ServiceInstance: id.serviceInstance,
+	}
+	if meter.Type != go2sky.MeterTypeHistogram {
+		data.Metric = &agentv3.MeterData_SingleValue{
 			SingleValue: &agentv3.MeterSingleValue{
-				Name:  name,
-				Value: value,
+				Name:   meter.Name,
+				Labels: labels,
+				Value:  meter.Value,
 			},
+		}
+		return data
+	}
+	values := make([]*agentv3.MeterBucketValue, 0, len(meter.Buckets))
+	for _, b := range meter.Buckets {
+		values = append(values, &agentv3.MeterBucketValue{
+			Bucket:             b.Bucket,
+			Count:              b.Count,
+			IsNegativeInfinity: math.IsInf(b.Bucket, -1),
+		})
+	}
+	data.Metric = &agentv3.MeterData_Histogram{
+		Histogram: &agentv3.MeterHistogram{
+			Name:   meter.Name,
+			Labels: labels,
+			Values: values,
 		},
-		Timestamp:       time,
-		Service:         r.service,
-		ServiceInstance: r.serviceInstance,
 	}
+	return data
 }
 
 func (r *gRPCReporter) initSendMeterPipeline() {
@@ -405,16 +765,28 @@ func (r *gRPCReporter) initSendMeterPipeline() {
 }
 
 func (r *gRPCReporter) SendLog(logData go2sky.ReportedLogData) {
+	r.sendLog(r.primary(), logData)
+}
 
-	if r.logClient == nil || logData == nil {
+func (r *gRPCReporter) sendLog(id serviceIdentity, logData go2sky.ReportedLogData) {
+	if r.logClient == nil || logData == nil || !r.acceptLog(logData.ErrorLevel()) {
 		return
 	}
 
 	reportLogData := logv3.LogData{}
-	reportLogData.Service = r.service
-	reportLogData.ServiceInstance = r.serviceInstance
+	reportLogData.Service = id.service
+	reportLogData.ServiceInstance = id.serviceInstance
 	reportLogData.Layer = r.layer
-	reportLogData.Body = &logv3.LogDataBody{Type: "text", Content: &logv3.LogDataBody_Text{Text: &logv3.TextLog{Text: logData.Data()}}}
+	reportLogData.Timestamp = tool.Millisecond(time.Now())
+	bodyType := go2sky.LogBodyTypeText
+	if structured, ok := logData.(go2sky.StructuredLogData); ok {
+		bodyType = structured.BodyType()
+		reportLogData.Endpoint = structured.Endpoint()
+		if ts := structured.Timestamp(); !ts.IsZero() {
+			reportLogData.Timestamp = tool.Millisecond(ts)
+		}
+	}
+	reportLogData.Body = generateLogBody(bodyType, logData.Data())
 
 	logLevelTag := &commonv3.KeyStringValuePair{
 		Key:   "LEVEL",
@@ -423,6 +795,11 @@ func (r *gRPCReporter) SendLog(logData go2sky.ReportedLogData) {
 
 	logTags := []*commonv3.KeyStringValuePair{}
 	logTags = append(logTags, logLevelTag)
+	if tagged, ok := logData.(go2sky.TaggedLogData); ok {
+		for _, tag := range tagged.Tags() {
+			logTags = append(logTags, &commonv3.KeyStringValuePair{Key: tag.Key, Value: tag.Value})
+		}
+	}
 	reportLogData.Tags = &logv3.LogTags{Data: logTags}
 
 	if logData.Context() != nil {
@@ -435,6 +812,9 @@ func (r *gRPCReporter) SendLog(logData go2sky.ReportedLogData) {
 		traceContext.SpanId = skyCtx.SpanID
 
 		reportLogData.TraceContext = &traceContext
+		if reportLogData.Endpoint == "" {
+			reportLogData.Endpoint = go2sky.Endpoint(logData.Context())
+		}
 	}
 
 	defer func() {
@@ -450,41 +830,72 @@ func (r *gRPCReporter) SendLog(logData go2sky.ReportedLogData) {
 		return
 	case r.logCh <- &reportLogData:
 	default:
+		atomic.AddInt64(&r.logDropped.queueFull, 1)
 		r.logger.Errorf("reach max send buffer")
 	}
 }
 
+func generateLogBody(bodyType go2sky.LogBodyType, data string) *logv3.LogDataBody {
+	switch bodyType {
+	case go2sky.LogBodyTypeJSON:
+		return &logv3.LogDataBody{Type: string(bodyType), Content: &logv3.LogDataBody_Json{Json: &logv3.JSONLog{Json: data}}}
+	case go2sky.LogBodyTypeYAML:
+		return &logv3.LogDataBody{Type: string(bodyType), Content: &logv3.LogDataBody_Yaml{Yaml: &logv3.YAMLLog{Yaml: data}}}
+	default:
+		return &logv3.LogDataBody{Type: string(go2sky.LogBodyTypeText), Content: &logv3.LogDataBody_Text{Text: &logv3.TextLog{Text: data}}}
+	}
+}
+
+// initSendLogPipeline sends the logs in a long-lived stream. The logs are taken from the channel in batches,
+// a batch is sent when it is full or at the flush interval. The stream is reopened after the retry interval
+// when it is broken, and the logs wait in the channel until it is opened.
 func (r *gRPCReporter) initSendLogPipeline() {
 	if r.logClient == nil {
 		return
 	}
 	go func() {
+		batch := make([]*logv3.LogData, 0, r.logBatchSize)
+		ticker := time.NewTicker(r.logFlushInterval)
+		defer ticker.Stop()
 	StreamLoop:
 		for {
 			stream, err := r.logClient.Collect(metadata.NewOutgoingContext(context.Background(), r.md))
 			if err != nil {
 				r.logger.Errorf("open stream error %v", err)
-				time.Sleep(5 * time.Second)
+				time.Sleep(r.logRetryInterval)
 				continue StreamLoop
 			}
-			for s := range r.logCh {
-				err = stream.Send(s)
+			for {
+				select {
+				case l, ok := <-r.logCh:
+					if !ok {
+						if r.sendLogBatch(stream, batch) == nil {
+							r.closeLogStream(stream)
+						}
+						r.closeGRPCConn()
+						return
+					}
+					batch = append(batch, l)
+					if len(batch) < r.logBatchSize {
+						continue
+					}
+				case <-ticker.C:
+				}
+				err = r.sendLogBatch(stream, batch)
+				batch = batch[:0]
 				if err != nil {
-					r.logger.Errorf("send segment error %v", err)
 					r.closeLogStream(stream)
+					time.Sleep(r.logRetryInterval)
 					continue StreamLoop
 				}
 			}
-			r.closeLogStream(stream)
-			r.closeGRPCConn()
-			break
 		}
 	}()
 }
 
 func (r *gRPCReporter) closeLogStream(stream logv3.LogReportService_CollectClient) {
 
-	if r.conn.GetState() == connectivity.Shutdown {
+	if r.conn != nil && r.conn.GetState() == connectivity.Shutdown {
 		return
 	}
 
@@ -512,7 +923,7 @@ func (r *gRPCReporter) closeStream(stream agentv3.TraceSegmentReportService_Coll
 	}
 }
 
-func (r *gRPCReporter) reportInstanceProperties() (err error) {
+func (r *gRPCReporter) reportInstanceProperties(id serviceIdentity) (err error) {
 	props := buildOSInfo()
 	if r.instanceProps != nil {
 		for k, v := range r.instanceProps {
@@ -523,50 +934,52 @@ func (r *gRPCReporter) reportInstanceProperties() (err error) {
 		}
 	}
 	_, err = r.managementClient.ReportInstanceProperties(metadata.NewOutgoingContext(context.Background(), r.md), &managementv3.InstanceProperties{
-		Service:         r.service,
-		ServiceInstance: r.serviceInstance,
+		Service:         id.service,
+		ServiceInstance: id.serviceInstance,
 		Properties:      props,
 		Layer:           r.layer,
 	})
 	return err
 }
 
-func (r *gRPCReporter) check() {
-	if r.checkInterval < 0 || r.conn == nil || r.managementClient == nil {
+// check keeps the service instance alive until it is released or the connection is closed
+func (r *gRPCReporter) check(id serviceIdentity) {
+	// the heartbeat is turned off by a non-positive interval instead of sending continuously
+	if r.checkInterval <= 0 || r.conn == nil || r.managementClient == nil {
 		return
 	}
 	go func() {
 		instancePropertiesSubmitted := false
 		for {
-			if r.conn.GetState() == connectivity.Shutdown {
+			if r.conn.GetState() == connectivity.Shutdown || !r.booted(id) {
 				break
 			}
 
 			// report the process status
-			if r.processStatusHookEnable {
-				reportProcess(r)
+			if r.processStatusHookEnable && id == r.primary() {
+				reportProcess(r, id)
 			}
 
 			if !instancePropertiesSubmitted {
-				err := r.reportInstanceProperties()
+				err := r.reportInstanceProperties(id)
 				if err != nil {
 					r.logger.Errorf("report serviceInstance properties error %v", err)
-					time.Sleep(r.checkInterval)
+					time.Sleep(r.heartbeatPeriod())
 					continue
 				}
 				instancePropertiesSubmitted = true
 			}
 
 			_, err := r.managementClient.KeepAlive(metadata.NewOutgoingContext(context.Background(), r.md), &managementv3.InstancePingPkg{
-				Service:         r.service,
-				ServiceInstance: r.serviceInstance,
+				Service:         id.service,
+				ServiceInstance: id.serviceInstance,
 				Layer:           r.layer,
 			})
 
 			if err != nil {
 				r.logger.Errorf("send keep alive signal error %v", err)
 			}
-			time.Sleep(r.checkInterval)
+			time.Sleep(r.heartbeatPeriod())
 		}
 	}()
 }
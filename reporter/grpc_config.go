@@ -0,0 +1,110 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/credentials"
+)
+
+func init() {
+	go2sky.RegisterReporterFactory(go2sky.ReporterTypeGRPC, newGRPCReporterFromConfig)
+	go2sky.RegisterReporterFactory(go2sky.ReporterTypeLog, func(*go2sky.ReporterConfig) (go2sky.Reporter, error) {
+		return NewLogReporter()
+	})
+}
+
+// newGRPCReporterFromConfig creates the gRPC reporter from the agent configuration
+func newGRPCReporterFromConfig(c *go2sky.ReporterConfig) (go2sky.Reporter, error) {
+	opts := []GRPCReporterOption{
+		WithCheckInterval(time.Duration(c.HeartbeatPeriod) * time.Second),
+		WithCDS(time.Duration(c.DynamicConfigInterval) * time.Second),
+		WithMaxSendQueueSize(c.MaxSendQueueSize),
+		WithMeterCollectPeriod(time.Duration(c.MeterCollectPeriod) * time.Second),
+		WithProcessStatusHook(c.ProcessStatusHook),
+		WithHostMetrics(c.HostMetrics),
+		WithLogBatch(c.LogBatchSize, 0),
+	}
+	if c.LogMinLevel != "" {
+		level, err := go2sky.ParseLogLevel(c.LogMinLevel)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithLogMinLevel(level))
+	}
+	for level, limit := range c.LogRateLimit {
+		opts = append(opts, WithLogRateLimit(go2sky.LogLevel(level), limit))
+	}
+	if c.Authentication != "" {
+		opts = append(opts, WithAuthentication(c.Authentication))
+	}
+	if c.Layer != "" {
+		opts = append(opts, WithLayer(c.Layer))
+	}
+	if c.DynamicConfigFile != "" {
+		opts = append(opts, WithConfigSource(go2sky.NewFileConfigSource(c.DynamicConfigFile)))
+	}
+	if len(c.InstanceProperties) > 0 {
+		props := make(map[string]string, len(c.InstanceProperties))
+		for k, v := range c.InstanceProperties {
+			props[k] = v
+		}
+		opts = append(opts, WithInstanceProps(props))
+	}
+	if len(c.ProcessLabels) > 0 {
+		opts = append(opts, WithProcessLabels(c.ProcessLabels))
+	}
+	if c.TLS.Enabled() {
+		creds, err := newTLSCredentials(&c.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithTransportCredentials(creds))
+	}
+	return NewGRPCReporter(c.BackendServices, opts...)
+}
+
+// newTLSCredentials loads the trusted CA and the client certificate for mutual TLS,
+// the system root CAs are used when no trusted CA is set
+func newTLSCredentials(c *go2sky.TLSConfig) (credentials.TransportCredentials, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.CAPath != "" {
+		pem, err := os.ReadFile(c.CAPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "reporter.tls.ca_path")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("reporter.tls.ca_path: no certificate found in %s", c.CAPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.CertChainPath != "" || c.KeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertChainPath, c.KeyPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "reporter.tls.cert_chain_path")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
@@ -17,12 +17,14 @@
 package reporter
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/SkyAPM/go2sky"
 	"github.com/pkg/errors"
 )
 
@@ -35,6 +37,17 @@ const (
 	swAgentCollectorMaxSendQueueSize              = "SW_AGENT_COLLECTOR_MAX_SEND_QUEUE_SIZE"
 	swAgentProcessStatusHookEnable                = "SW_AGENT_PROCESS_STATUS_HOOK_ENABLE"
 	swAgentProcessLabels                          = "SW_AGENT_PROCESS_LABELS"
+	swAgentMeterCollectPeriod                     = "SW_AGENT_METER_COLLECT_PERIOD"
+	swAgentHostMetricsEnable                      = "SW_AGENT_HOST_METRICS_ENABLE"
+	swAgentLogMinLevel                            = "SW_AGENT_LOG_MIN_LEVEL"
+	swAgentLogBatchSize                           = "SW_AGENT_LOG_BATCH_SIZE"
+	swAgentLogRateLimitJSON                       = "SW_AGENT_LOG_RATE_LIMIT_JSON"
+	swAgentInstancePropertiesJSON                 = "SW_AGENT_INSTANCE_PROPERTIES_JSON"
+	swAgentDynamicConfigFile                      = "SW_AGENT_DYNAMIC_CONFIG_FILE"
+	swAgentForceTLS                               = "SW_AGENT_FORCE_TLS"
+	swAgentSSLTrustedCAPath                       = "SW_AGENT_SSL_TRUSTED_CA_PATH"
+	swAgentSSLCertChainPath                       = "SW_AGENT_SSL_CERT_CHAIN_PATH"
+	swAgentSSLKeyPath                             = "SW_AGENT_SSL_KEY_PATH"
 )
 
 // serverAddrFormEnv read the backend service address in the environment variable
@@ -74,7 +87,7 @@ func gRPCReporterOptionsFormEnv() (opts []GRPCReporterOption, err error) {
 	if value := os.Getenv(swAgentCollectorMaxSendQueueSize); value != "" {
 		size, err1 := strconv.ParseInt(value, 0, 64)
 		if err1 != nil {
-			return nil, err
+			return nil, errors.Wrap(err1, fmt.Sprintf("%s=%s", swAgentCollectorMaxSendQueueSize, value))
 		}
 		opts = append(opts, WithMaxSendQueueSize(int(size)))
 	}
@@ -82,11 +95,93 @@ func gRPCReporterOptionsFormEnv() (opts []GRPCReporterOption, err error) {
 	if value := os.Getenv(swAgentProcessStatusHookEnable); value != "" {
 		enable, err1 := strconv.ParseBool(value)
 		if err1 != nil {
-			return nil, err
+			return nil, errors.Wrap(err1, fmt.Sprintf("%s=%s", swAgentProcessStatusHookEnable, value))
 		}
 		opts = append(opts, WithProcessStatusHook(enable))
 	}
 
+	if value := os.Getenv(swAgentMeterCollectPeriod); value != "" {
+		period, err1 := strconv.ParseInt(value, 0, 64)
+		if err1 != nil {
+			return nil, errors.Wrap(err1, fmt.Sprintf("%s=%s", swAgentMeterCollectPeriod, value))
+		}
+		opts = append(opts, WithMeterCollectPeriod(time.Duration(period)*time.Second))
+	}
+
+	if value := os.Getenv(swAgentHostMetricsEnable); value != "" {
+		enable, err1 := strconv.ParseBool(value)
+		if err1 != nil {
+			return nil, errors.Wrap(err1, fmt.Sprintf("%s=%s", swAgentHostMetricsEnable, value))
+		}
+		opts = append(opts, WithHostMetrics(enable))
+	}
+
+	if value := os.Getenv(swAgentLogMinLevel); value != "" {
+		level, err1 := go2sky.ParseLogLevel(value)
+		if err1 != nil {
+			return nil, errors.Wrap(err1, fmt.Sprintf("%s=%s", swAgentLogMinLevel, value))
+		}
+		opts = append(opts, WithLogMinLevel(level))
+	}
+
+	if value := os.Getenv(swAgentLogBatchSize); value != "" {
+		size, err1 := strconv.ParseInt(value, 0, 64)
+		if err1 != nil {
+			return nil, errors.Wrap(err1, fmt.Sprintf("%s=%s", swAgentLogBatchSize, value))
+		}
+		opts = append(opts, WithLogBatch(int(size), 0))
+	}
+
+	if value := os.Getenv(swAgentLogRateLimitJSON); value != "" {
+		limits := make(map[string]int)
+		if err1 := json.Unmarshal([]byte(value), &limits); err1 != nil {
+			return nil, errors.Wrap(err1, fmt.Sprintf("%s=%s", swAgentLogRateLimitJSON, value))
+		}
+		for level, limit := range limits {
+			opts = append(opts, WithLogRateLimit(go2sky.LogLevel(level), limit))
+		}
+	}
+
+	if value := os.Getenv(swAgentInstancePropertiesJSON); value != "" {
+		props := make(map[string]string)
+		if err1 := json.Unmarshal([]byte(value), &props); err1 != nil {
+			return nil, errors.Wrap(err1, fmt.Sprintf("%s=%s", swAgentInstancePropertiesJSON, value))
+		}
+		// merge into the properties set by the code
+		opts = append(opts, func(r *gRPCReporter) {
+			if r.instanceProps == nil {
+				r.instanceProps = make(map[string]string)
+			}
+			for k, v := range props {
+				r.instanceProps[k] = v
+			}
+		})
+	}
+
+	if value := os.Getenv(swAgentDynamicConfigFile); value != "" {
+		opts = append(opts, WithConfigSource(go2sky.NewFileConfigSource(value)))
+	}
+
+	tlsConfig := &go2sky.TLSConfig{
+		CAPath:        os.Getenv(swAgentSSLTrustedCAPath),
+		CertChainPath: os.Getenv(swAgentSSLCertChainPath),
+		KeyPath:       os.Getenv(swAgentSSLKeyPath),
+	}
+	if value := os.Getenv(swAgentForceTLS); value != "" {
+		force, err1 := strconv.ParseBool(value)
+		if err1 != nil {
+			return nil, errors.Wrap(err1, fmt.Sprintf("%s=%s", swAgentForceTLS, value))
+		}
+		tlsConfig.Force = force
+	}
+	if tlsConfig.Enabled() {
+		creds, err1 := newTLSCredentials(tlsConfig)
+		if err1 != nil {
+			return nil, err1
+		}
+		opts = append(opts, WithTransportCredentials(creds))
+	}
+
 	if value := os.Getenv(swAgentProcessLabels); value != "" {
 		labels := strings.Split(value, ",")
 		opts = append(opts, WithProcessLabels(labels))
@@ -0,0 +1,161 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporter
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	logv3 "skywalking.apache.org/repo/goapi/collect/logging/v3"
+)
+
+const (
+	logMinLevelKey          = "log.min_level"
+	defaultLogBatchSize     = 100
+	defaultLogFlushInterval = time.Second
+	defaultLogRetryInterval = 5 * time.Second
+
+	logDroppedMeterName = "instance_log_dropped_delta"
+	logDroppedReasonKey = "reason"
+)
+
+// logRanks orders the levels, the unknown levels are ranked as info
+var logRanks = map[go2sky.LogLevel]int{
+	go2sky.LogLevelDebug: 0,
+	go2sky.LogLevelInfo:  1,
+	go2sky.LogLevelWarn:  2,
+	go2sky.LogLevelError: 3,
+}
+
+func logRank(level go2sky.LogLevel) int {
+	if rank, ok := logRanks[go2sky.LogLevel(strings.ToLower(string(level)))]; ok {
+		return rank
+	}
+	return logRanks[go2sky.LogLevelInfo]
+}
+
+// parseLogMinLevel parses the minimum level, the empty value reports all the levels
+func parseLogMinLevel(value string) (go2sky.LogLevel, error) {
+	if value == "" {
+		return "", nil
+	}
+	return go2sky.ParseLogLevel(value)
+}
+
+// logRateLimiter limits the logs of each level per second, the levels without a limit are not limited
+type logRateLimiter struct {
+	limits map[go2sky.LogLevel]int
+
+	mu     sync.Mutex
+	second int64
+	counts map[go2sky.LogLevel]int
+}
+
+func newLogRateLimiter(limits map[go2sky.LogLevel]int) *logRateLimiter {
+	if len(limits) == 0 {
+		return nil
+	}
+	return &logRateLimiter{limits: limits, counts: make(map[go2sky.LogLevel]int, len(limits))}
+}
+
+func (l *logRateLimiter) allow(level go2sky.LogLevel, now time.Time) bool {
+	if l == nil {
+		return true
+	}
+	level = go2sky.LogLevel(strings.ToLower(string(level)))
+	limit, ok := l.limits[level]
+	if !ok {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if second := now.Unix(); second != l.second {
+		l.second = second
+		for k := range l.counts {
+			delete(l.counts, k)
+		}
+	}
+	if l.counts[level] >= limit {
+		return false
+	}
+	l.counts[level]++
+	return true
+}
+
+// logDropCounter counts the dropped logs by the reasons since the last collection,
+// they are reported as the delta gauges like the endpoint meters
+type logDropCounter struct {
+	rateLimited int64
+	queueFull   int64
+	sendFailed  int64
+}
+
+func (c *logDropCounter) collect() []go2sky.MeterValue {
+	counts := []struct {
+		reason string
+		count  *int64
+	}{
+		{"rate_limited", &c.rateLimited},
+		{"queue_full", &c.queueFull},
+		{"send_failed", &c.sendFailed},
+	}
+	values := make([]go2sky.MeterValue, 0, len(counts))
+	for _, d := range counts {
+		values = append(values, go2sky.MeterValue{
+			Type:   go2sky.MeterTypeGauge,
+			Name:   logDroppedMeterName,
+			Labels: []go2sky.MeterLabel{{Name: logDroppedReasonKey, Value: d.reason}},
+			Value:  float64(atomic.SwapInt64(d.count, 0)),
+			Delta:  true,
+		})
+	}
+	return values
+}
+
+func (r *gRPCReporter) minLogLevel() go2sky.LogLevel {
+	if r.dynamicLogMinLevel != nil {
+		return r.dynamicLogMinLevel.Get()
+	}
+	return r.logMinLevel
+}
+
+// acceptLog filters the log by the minimum level and the rate limit
+func (r *gRPCReporter) acceptLog(level go2sky.LogLevel) bool {
+	if minLevel := r.minLogLevel(); minLevel != "" && logRank(level) < logRank(minLevel) {
+		return false
+	}
+	if !r.logLimiter.allow(level, time.Now()) {
+		atomic.AddInt64(&r.logDropped.rateLimited, 1)
+		return false
+	}
+	return true
+}
+
+// sendLogBatch sends the logs in the stream, the rest of the batch is dropped if the stream is broken
+func (r *gRPCReporter) sendLogBatch(stream logv3.LogReportService_CollectClient, batch []*logv3.LogData) error {
+	for i, l := range batch {
+		if err := stream.Send(l); err != nil {
+			r.logger.Errorf("send log error %v", err)
+			atomic.AddInt64(&r.logDropped.sendFailed, int64(len(batch)-i))
+			return err
+		}
+	}
+	return nil
+}
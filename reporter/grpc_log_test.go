@@ -0,0 +1,217 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporter
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+	"google.golang.org/grpc"
+	commonv3 "skywalking.apache.org/repo/goapi/collect/common/v3"
+	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+	logv3 "skywalking.apache.org/repo/goapi/collect/logging/v3"
+)
+
+// recordLogClient records the sent logs and the opened and closed streams,
+// the first failOpen opens and the first failSend sends fail
+type recordLogClient struct {
+	logs     chan *logv3.LogData
+	closed   chan struct{}
+	opened   int32
+	failOpen int32
+	failSend int32
+}
+
+func (c *recordLogClient) Collect(ctx context.Context, _ ...grpc.CallOption) (logv3.LogReportService_CollectClient, error) {
+	if atomic.AddInt32(&c.failOpen, -1) >= 0 {
+		return nil, errors.New("unavailable")
+	}
+	atomic.AddInt32(&c.opened, 1)
+	return &recordLogStream{client: c}, nil
+}
+
+type recordLogStream struct {
+	grpc.ClientStream
+	client *recordLogClient
+}
+
+func (s *recordLogStream) Send(l *logv3.LogData) error {
+	if atomic.AddInt32(&s.client.failSend, -1) >= 0 {
+		return errors.New("broken")
+	}
+	s.client.logs <- l
+	return nil
+}
+
+func (s *recordLogStream) CloseAndRecv() (*commonv3.Commands, error) {
+	s.client.closed <- struct{}{}
+	return nil, nil
+}
+
+func newRecordLogClient() *recordLogClient {
+	return &recordLogClient{logs: make(chan *logv3.LogData, 10), closed: make(chan struct{}, 10)}
+}
+
+func receiveLogs(t *testing.T, client *recordLogClient, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		select {
+		case <-client.logs:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the expected %d logs sent, current is %d", count, i)
+		}
+	}
+	select {
+	case <-client.logs:
+		t.Fatalf("the expected %d logs sent, current is more", count)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLogRateLimiter(t *testing.T) {
+	limiter := newLogRateLimiter(map[go2sky.LogLevel]int{go2sky.LogLevelDebug: 2, go2sky.LogLevelInfo: 0})
+	now := time.Unix(100, 0)
+	tests := []struct {
+		level go2sky.LogLevel
+		now   time.Time
+		allow bool
+	}{
+		{go2sky.LogLevelDebug, now, true},
+		{"DEBUG", now, true},
+		{go2sky.LogLevelDebug, now.Add(500 * time.Millisecond), false},
+		{go2sky.LogLevelInfo, now, false},
+		{go2sky.LogLevelError, now, true},
+		{go2sky.LogLevelDebug, now.Add(time.Second), true},
+	}
+	for i, tt := range tests {
+		if allow := limiter.allow(tt.level, tt.now); allow != tt.allow {
+			t.Errorf("case %d: allow %s = %v, want %v", i, tt.level, allow, tt.allow)
+		}
+	}
+	if newLogRateLimiter(nil) != nil || !(*logRateLimiter)(nil).allow(go2sky.LogLevelDebug, now) {
+		t.Error("the logs should not be limited without limits")
+	}
+}
+
+func TestSendLog_FilterAndDropped(t *testing.T) {
+	r := createGRPCReporter()
+	WithLogMinLevel(go2sky.LogLevelInfo)(r)
+	WithLogRateLimit(go2sky.LogLevelWarn, 1)(r)
+	r.logLimiter = newLogRateLimiter(r.logRateLimits)
+	r.dynamicLogMinLevel = go2sky.NewDynamicConfig(logMinLevelKey, r.logMinLevel, parseLogMinLevel)
+	r.logClient = &recordLogClient{}
+	r.logCh = make(chan *logv3.LogData, 2)
+	r.meterCh = make(chan []*agentv3.MeterData, 1)
+
+	for _, level := range []go2sky.LogLevel{go2sky.LogLevelDebug, go2sky.LogLevelWarn, go2sky.LogLevelWarn, go2sky.LogLevelInfo, go2sky.LogLevelError} {
+		r.SendLog(&go2sky.DefaultLogData{LogErrLevel: level})
+	}
+	if len(r.logCh) != 2 {
+		t.Fatalf("the expected 2 queued logs, current is %d", len(r.logCh))
+	}
+	if warn := <-r.logCh; warn.GetTags().GetData()[0].GetValue() != string(go2sky.LogLevelWarn) {
+		t.Errorf("unexpected first log %v", warn)
+	}
+
+	// the minimum level is changed by CDS
+	r.dynamicLogMinLevel.Notify(go2sky.MODIFY, "error")
+	r.SendLog(&go2sky.DefaultLogData{LogErrLevel: go2sky.LogLevelInfo})
+	if len(r.logCh) != 1 {
+		t.Errorf("the info log should be filtered, current queued is %d", len(r.logCh))
+	}
+	if err := r.dynamicLogMinLevel.Validate("fatal"); err == nil {
+		t.Error("the unknown level should be rejected")
+	}
+
+	r.SendMetrics(go2sky.RunTimeMetric{Time: 1})
+	dropped := make(map[string]float64)
+	for _, m := range <-r.meterCh {
+		if single := m.GetSingleValue(); single.GetName() == logDroppedMeterName {
+			dropped[single.GetLabels()[0].GetValue()] = single.GetValue()
+		}
+	}
+	// the warn log is rate limited, the error log is dropped by the full queue
+	if dropped["rate_limited"] != 1 || dropped["queue_full"] != 1 || dropped["send_failed"] != 0 {
+		t.Errorf("unexpected dropped logs %v", dropped)
+	}
+}
+
+func TestLogDropCounter(t *testing.T) {
+	c := &logDropCounter{queueFull: 2}
+	for i, expected := range []float64{2, 0} {
+		for _, v := range c.collect() {
+			if v.Type != go2sky.MeterTypeGauge || !v.Delta {
+				t.Errorf("the dropped logs should be reported as the delta gauge, current is %+v", v)
+			}
+			if v.Labels[0].Value == "queue_full" && v.Value != expected {
+				t.Errorf("collection %d: the expected dropped logs are %v, current are %v", i, expected, v.Value)
+			}
+		}
+	}
+}
+
+func TestSendLogPipeline_Batch(t *testing.T) {
+	r := createGRPCReporter()
+	client := newRecordLogClient()
+	r.logClient = client
+	r.logCh = make(chan *logv3.LogData, 10)
+	r.logBatchSize, r.logFlushInterval = 2, time.Hour
+
+	r.initSendLogPipeline()
+	for i := 0; i < 3; i++ {
+		r.logCh <- &logv3.LogData{}
+	}
+	// the full batch is sent, the rest waits for the next one
+	receiveLogs(t, client, 2)
+	if len(client.closed) != 0 {
+		t.Error("the stream should be kept after a batch")
+	}
+	close(r.logCh)
+	receiveLogs(t, client, 1)
+	<-client.closed
+	if opened := atomic.LoadInt32(&client.opened); opened != 1 {
+		t.Errorf("the expected 1 stream opened, current is %d", opened)
+	}
+}
+
+func TestSendLogPipeline_Reopen(t *testing.T) {
+	r := createGRPCReporter()
+	client := newRecordLogClient()
+	client.failOpen, client.failSend = 1, 1
+	r.logClient = client
+	r.logCh = make(chan *logv3.LogData, 10)
+	r.logBatchSize, r.logFlushInterval, r.logRetryInterval = 1, time.Hour, 10*time.Millisecond
+
+	r.initSendLogPipeline()
+	// the log waits until the stream is opened, and is dropped by the broken stream
+	r.logCh <- &logv3.LogData{}
+	<-client.closed
+	r.logCh <- &logv3.LogData{}
+	receiveLogs(t, client, 1)
+	if opened := atomic.LoadInt32(&client.opened); opened != 2 {
+		t.Errorf("the expected the stream reopened after the error, current opened is %d", opened)
+	}
+	if failed := atomic.LoadInt64(&r.logDropped.sendFailed); failed != 1 {
+		t.Errorf("the expected 1 log dropped by the broken stream, current is %d", failed)
+	}
+	close(r.logCh)
+	<-client.closed
+}
@@ -21,6 +21,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/SkyAPM/go2sky"
 	"github.com/SkyAPM/go2sky/logger"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
@@ -92,6 +93,14 @@ func WithCDS(interval time.Duration) GRPCReporterOption {
 	}
 }
 
+// WithConfigSource setup the source of the dynamic configurations instead of the backend CDS,
+// it is polled at the interval set by WithCDS
+func WithConfigSource(source go2sky.ConfigSource) GRPCReporterOption {
+	return func(r *gRPCReporter) {
+		r.configSource = source
+	}
+}
+
 // WithLayer setup layer
 func WithLayer(layer string) GRPCReporterOption {
 	return func(r *gRPCReporter) {
@@ -137,3 +146,53 @@ func WithMeterCollectPeriod(interval time.Duration) GRPCReporterOption {
 		r.meterInterval = &interval
 	}
 }
+
+// WithHostMetrics setup is enabled the host CPU and memory metrics, they are enabled by default
+func WithHostMetrics(enable bool) GRPCReporterOption {
+	return func(r *gRPCReporter) {
+		r.hostMetricsOff = !enable
+	}
+}
+
+// WithMetricsReporters setup is set the extra sinks of the collected metrics, such as go2sky.PrometheusHandler
+func WithMetricsReporters(reporters ...go2sky.MetricsReporter) GRPCReporterOption {
+	return func(r *gRPCReporter) {
+		r.metricsReporters = append(r.metricsReporters, reporters...)
+	}
+}
+
+// WithLogBatch setup the max logs sent in a batch and the interval sending the not full batch,
+// the non-positive values keep the defaults, 100 logs and 1 second
+func WithLogBatch(size int, flushInterval time.Duration) GRPCReporterOption {
+	return func(r *gRPCReporter) {
+		if size > 0 {
+			r.logBatchSize = size
+		}
+		if flushInterval > 0 {
+			r.logFlushInterval = flushInterval
+		}
+	}
+}
+
+// WithLogMinLevel setup the minimum level of the reported logs, it could be changed by CDS
+func WithLogMinLevel(level go2sky.LogLevel) GRPCReporterOption {
+	return func(r *gRPCReporter) {
+		r.logMinLevel = level
+	}
+}
+
+// WithLogRateLimit setup the max logs of the level reported per second, the exceeded logs are dropped
+// and counted. A negative limit removes the limit of the level.
+func WithLogRateLimit(level go2sky.LogLevel, perSecond int) GRPCReporterOption {
+	return func(r *gRPCReporter) {
+		level = go2sky.LogLevel(strings.ToLower(string(level)))
+		if perSecond < 0 {
+			delete(r.logRateLimits, level)
+			return
+		}
+		if r.logRateLimits == nil {
+			r.logRateLimits = make(map[go2sky.LogLevel]int)
+		}
+		r.logRateLimits[level] = perSecond
+	}
+}
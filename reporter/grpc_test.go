@@ -30,10 +30,15 @@ import (
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
 	"log"
+	"math"
 	"os"
 	"reflect"
+	"strings"
+	"sync/atomic"
+	configuration "skywalking.apache.org/repo/goapi/collect/agent/configuration/v3"
 	commonv3 "skywalking.apache.org/repo/goapi/collect/common/v3"
 	agentv3 "skywalking.apache.org/repo/goapi/collect/language/agent/v3"
+	logv3 "skywalking.apache.org/repo/goapi/collect/logging/v3"
 	managementv3 "skywalking.apache.org/repo/goapi/collect/management/v3"
 	"testing"
 	"time"
@@ -164,6 +169,15 @@ func TestGRPCReporterOption(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "with metrics reporters",
+			option: WithMetricsReporters(go2sky.NewPrometheusHandler()),
+			verifyFunc: func(t *testing.T, reporter *gRPCReporter) {
+				if len(reporter.metricsReporters) != 1 {
+					t.Error("error are not set metricsReporters")
+				}
+			},
+		},
 		{
 			name:   "with max send queue size",
 			option: WithMaxSendQueueSize(50000),
@@ -234,6 +248,15 @@ func TestGRPCReporterOption(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "with config source",
+			option: WithConfigSource(go2sky.NewFileConfigSource("agent.yaml")),
+			verifyFunc: func(t *testing.T, reporter *gRPCReporter) {
+				if reporter.configSource == nil {
+					t.Error("error are not set config source")
+				}
+			},
+		},
 		{
 			name:   "with layer",
 			option: WithLayer("test"),
@@ -284,11 +307,9 @@ func TestGRPCReporter_reportInstanceProperties(t *testing.T) {
 	mockManagementServiceClient.EXPECT().ReportInstanceProperties(gomock.Any(), instancePropertiesMatcher{instanceProperties}).Return(nil, nil)
 
 	reporter := createGRPCReporter()
-	reporter.service = mockService
-	reporter.serviceInstance = mockServiceInstance
 	reporter.instanceProps = customProps
 	reporter.managementClient = mockManagementServiceClient
-	err := reporter.reportInstanceProperties()
+	err := reporter.reportInstanceProperties(serviceIdentity{service: mockService, serviceInstance: mockServiceInstance})
 	if err != nil {
 		t.Error()
 	}
@@ -467,3 +488,437 @@ func TestSendMetrics(t *testing.T) {
 	mockGRPCReporter.initMetricsCollector()
 	time.Sleep(1 * time.Second)
 }
+
+func TestSendMetrics_ApplicationMeters(t *testing.T) {
+	r := createGRPCReporter()
+	r.boots = []*identityBoot{{id: serviceIdentity{service: "service", serviceInstance: "instance"}}}
+	r.meterCh = make(chan []*agentv3.MeterData, 1)
+
+	r.SendMetrics(go2sky.RunTimeMetric{
+		Time: 1,
+		Meters: []go2sky.MeterValue{
+			{Type: go2sky.MeterTypeCounter, Name: "requests", Labels: []go2sky.MeterLabel{{Name: "method", Value: "GET"}}, Value: 3},
+			{Type: go2sky.MeterTypeHistogram, Name: "latency", Buckets: []go2sky.MeterBucket{
+				{Bucket: math.Inf(-1), Count: 1},
+				{Bucket: 10, Count: 2},
+			}},
+		},
+	})
+	meters := <-r.meterCh
+	single := meters[len(meters)-2].GetSingleValue()
+	if single.GetName() != "requests" || single.GetValue() != 3 || len(single.GetLabels()) != 1 ||
+		single.GetLabels()[0].GetName() != "method" || single.GetLabels()[0].GetValue() != "GET" {
+		t.Errorf("unexpected counter %v", single)
+	}
+	histogram := meters[len(meters)-1].GetHistogram()
+	if histogram.GetName() != "latency" || len(histogram.GetValues()) != 2 {
+		t.Fatalf("unexpected histogram %v", histogram)
+	}
+	if !histogram.GetValues()[0].GetIsNegativeInfinity() || histogram.GetValues()[0].GetCount() != 1 {
+		t.Errorf("unexpected negative infinity bucket %v", histogram.GetValues()[0])
+	}
+	if histogram.GetValues()[1].GetIsNegativeInfinity() || histogram.GetValues()[1].GetBucket() != 10 {
+		t.Errorf("unexpected bucket %v", histogram.GetValues()[1])
+	}
+}
+
+func TestSendLog_Structured(t *testing.T) {
+	r := createGRPCReporter()
+	r.logClient = logv3.NewLogReportServiceClient(nil)
+	r.logCh = make(chan *logv3.LogData, 3)
+	ts := time.Unix(1, 0)
+
+	r.SendLog(&go2sky.DefaultLogData{LogErrLevel: go2sky.LogLevelInfo, LogContent: "plain"})
+	r.SendLog(&go2sky.DefaultLogData{
+		LogErrLevel: go2sky.LogLevelError,
+		LogContent:  `{"order":1}`,
+		LogTags:     []go2sky.LogTag{{Key: "user", Value: "alice"}},
+		LogBodyType: go2sky.LogBodyTypeJSON,
+		LogEndpoint: "/orders",
+		LogTime:     ts,
+	})
+	r.SendLog(&go2sky.DefaultLogData{LogContent: "order: 1\n", LogBodyType: go2sky.LogBodyTypeYAML})
+
+	text := <-r.logCh
+	if text.GetBody().GetType() != "text" || text.GetBody().GetText().GetText() != "plain" || text.GetTimestamp() == 0 {
+		t.Errorf("unexpected text log %v", text)
+	}
+	jsonLog := <-r.logCh
+	if jsonLog.GetBody().GetType() != "json" || jsonLog.GetBody().GetJson().GetJson() != `{"order":1}` {
+		t.Errorf("unexpected json body %v", jsonLog.GetBody())
+	}
+	if jsonLog.GetEndpoint() != "/orders" || jsonLog.GetTimestamp() != 1000 {
+		t.Errorf("unexpected endpoint %s or timestamp %d", jsonLog.GetEndpoint(), jsonLog.GetTimestamp())
+	}
+	if tags := jsonLog.GetTags().GetData(); len(tags) != 2 || tags[0].GetKey() != "LEVEL" || tags[1].GetKey() != "user" || tags[1].GetValue() != "alice" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	yamlLog := <-r.logCh
+	if yamlLog.GetBody().GetType() != "yaml" || yamlLog.GetBody().GetYaml().GetYaml() != "order: 1\n" {
+		t.Errorf("unexpected yaml body %v", yamlLog.GetBody())
+	}
+}
+
+func TestGRPCReporter_DynamicConfigs(t *testing.T) {
+	reporter := createGRPCReporter()
+	reporter.checkInterval = 20 * time.Second
+	reporter.cdsInterval = 20 * time.Second
+	if len(reporter.DynamicConfigs()) != 0 {
+		t.Error("reporter without dynamic configurations should not bind watchers")
+	}
+	if reporter.heartbeatPeriod() != reporter.checkInterval {
+		t.Errorf("the expected value of heartbeat period is %v", reporter.checkInterval)
+	}
+
+	reporter.dynamicCheckInterval = go2sky.NewDynamicConfig(heartbeatPeriodKey, reporter.checkInterval, parseIntervalSeconds)
+	reporter.dynamicCDSInterval = go2sky.NewDynamicConfig(getAgentDynamicConfigIntervalKey, reporter.cdsInterval, parseIntervalSeconds)
+	cds := go2sky.NewConfigDiscoveryService(go2sky.WithConfigDiscoveryLogger(&testLog{}))
+	cds.BindWatchers(reporter.DynamicConfigs())
+	cds.HandleCommand(&commonv3.Command{Args: []*commonv3.KeyStringValuePair{
+		{Key: "UUID", Value: "uuid1"},
+		{Key: heartbeatPeriodKey, Value: "5"},
+		{Key: getAgentDynamicConfigIntervalKey, Value: "0"},
+	}})
+	if reporter.heartbeatPeriod() != 5*time.Second {
+		t.Errorf("the expected value of heartbeat period is 5s, current is %v", reporter.heartbeatPeriod())
+	}
+	if reporter.fetchConfigInterval() != 20*time.Second {
+		t.Errorf("the invalid cds interval should be ignored, current is %v", reporter.fetchConfigInterval())
+	}
+}
+
+func TestGRPCReporter_ConfigSource(t *testing.T) {
+	conn, err := grpc.Dial("127.0.0.1:0", grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &fakeConfigSource{configs: map[string]string{"test.key": "a"}}
+	reporter := createGRPCReporter()
+	reporter.conn = conn
+	reporter.cdsInterval = 10 * time.Millisecond
+	reporter.configSource = source
+	reporter.dynamicCDSInterval = go2sky.NewDynamicConfig(getAgentDynamicConfigIntervalKey, reporter.cdsInterval, parseIntervalSeconds)
+
+	watcher := go2sky.NewDynamicConfig("test.key", "", func(value string) (string, error) {
+		return value, nil
+	})
+	b := &identityBoot{id: serviceIdentity{service: mockService, serviceInstance: mockServiceInstance},
+		watchers: []go2sky.AgentConfigChangeWatcher{watcher}}
+	reporter.boots = []*identityBoot{b}
+	reporter.initCDS(b)
+	time.Sleep(100 * time.Millisecond)
+	if watcher.Get() != "a" {
+		t.Errorf("the expected value of test.key is a, current is %s", watcher.Get())
+	}
+	if b.cds.Version() != "v1" {
+		t.Errorf("the expected version is v1, current is %s", b.cds.Version())
+	}
+	_ = conn.Close()
+}
+
+// fakeConfigSource returns the same configurations in version v1, test only
+type fakeConfigSource struct {
+	configs map[string]string
+}
+
+func (f *fakeConfigSource) Fetch(ctx context.Context, currentVersion string) (string, map[string]string, error) {
+	return "v1", f.configs, nil
+}
+
+func TestGRPCReporter_EnvExtended(t *testing.T) {
+	envs := map[string]string{
+		swAgentMeterCollectPeriod:     "-1",
+		swAgentHostMetricsEnable:      "false",
+		swAgentInstancePropertiesJSON: `{"org":"SkyAPM"}`,
+		swAgentProcessLabels:          "a,b",
+		swAgentDynamicConfigFile:      "agent.yaml",
+		swAgentSSLTrustedCAPath:       "../test/test-data/certs/cert.crt",
+		swAgentLogMinLevel:            "WARN",
+		swAgentLogBatchSize:           "10",
+		swAgentLogRateLimitJSON:       `{"warn":5}`,
+	}
+	for k, v := range envs {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	r := createGRPCReporter()
+	err := applyGRPCReporterOption(r, WithInstanceProps(map[string]string{"code": "true"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.meterInterval == nil || *r.meterInterval != -1*time.Second {
+		t.Errorf("the expected value of meterInterval is -1s")
+	}
+	if !r.hostMetricsOff {
+		t.Errorf("the expected host metrics is disabled")
+	}
+	if r.instanceProps["org"] != "SkyAPM" || r.instanceProps["code"] != "true" || r.instanceProps[ProcessLabelKey] != "a,b" {
+		t.Errorf("error validate instance props, current is %v", r.instanceProps)
+	}
+	if r.configSource == nil {
+		t.Errorf("error are not set config source")
+	}
+	if r.creds == nil {
+		t.Errorf("error are not set TransportCredentials")
+	}
+	if r.logMinLevel != go2sky.LogLevelWarn || r.logBatchSize != 10 || r.logRateLimits[go2sky.LogLevelWarn] != 5 {
+		t.Errorf("error validate log options, current is %s %d %v", r.logMinLevel, r.logBatchSize, r.logRateLimits)
+	}
+}
+
+func TestGRPCReporter_EnvInvalid(t *testing.T) {
+	for _, env := range []string{swAgentCollectorMaxSendQueueSize, swAgentProcessStatusHookEnable, swAgentMeterCollectPeriod,
+		swAgentHostMetricsEnable, 		swAgentInstancePropertiesJSON, swAgentForceTLS,
+		swAgentLogMinLevel, swAgentLogBatchSize, swAgentLogRateLimitJSON} {
+		t.Run(env, func(t *testing.T) {
+			os.Setenv(env, "invalid")
+			defer os.Unsetenv(env)
+			err := applyGRPCReporterOption(createGRPCReporter())
+			if err == nil || !strings.Contains(err.Error(), env) {
+				t.Errorf("the error should name %s, current is %v", env, err)
+			}
+		})
+	}
+}
+
+func TestNewTracerFromConfig(t *testing.T) {
+	config := go2sky.DefaultAgentConfig()
+	config.Service = mockService
+	config.Instance = mockServiceInstance
+	config.Reporter.Type = go2sky.ReporterTypeLog
+	tracer, err := go2sky.NewTracerFromConfig(config)
+	if err != nil || tracer == nil {
+		t.Fatalf("create tracer with log reporter error %v", err)
+	}
+
+	config.Reporter.Type = go2sky.ReporterTypeGRPC
+	config.Reporter.BackendServices = "127.0.0.1:11800"
+	config.Reporter.DynamicConfigInterval = -1
+	config.Reporter.MeterCollectPeriod = -1
+	config.Reporter.TLS.CAPath = "../test/test-data/certs/missing.crt"
+	if _, err = go2sky.NewTracerFromConfig(config); err == nil || !strings.Contains(err.Error(), "reporter.tls.ca_path") {
+		t.Errorf("the error should name reporter.tls.ca_path, current is %v", err)
+	}
+
+	config.Reporter.TLS.CAPath = "../test/test-data/certs/cert.crt"
+	config.Reporter.InstanceProperties = map[string]string{"org": "SkyAPM"}
+	reporterConfig := config.Reporter
+	r, err := newGRPCReporterFromConfig(&reporterConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	gr := r.(*gRPCReporter)
+	if gr.creds == nil || gr.instanceProps["org"] != "SkyAPM" || gr.dynamicCDSInterval != nil || gr.checkInterval != 20*time.Second {
+		t.Errorf("error validate the reporter created from config")
+	}
+}
+
+// slowTraceClient opens the collect stream sending every segment after the delay
+type slowTraceClient struct {
+	agentv3.TraceSegmentReportServiceClient
+	delay  time.Duration
+	sent   int32
+	opened int32
+	closed int32
+}
+
+func (c *slowTraceClient) Collect(context.Context, ...grpc.CallOption) (agentv3.TraceSegmentReportService_CollectClient, error) {
+	atomic.AddInt32(&c.opened, 1)
+	return &slowCollectClient{client: c}, nil
+}
+
+type slowCollectClient struct {
+	grpc.ClientStream
+	client *slowTraceClient
+}
+
+func (s *slowCollectClient) Send(*agentv3.SegmentObject) error {
+	time.Sleep(s.client.delay)
+	atomic.AddInt32(&s.client.sent, 1)
+	return nil
+}
+
+func (s *slowCollectClient) CloseAndRecv() (*commonv3.Commands, error) {
+	atomic.AddInt32(&s.client.closed, 1)
+	return nil, nil
+}
+
+func TestGRPCReporter_Flush(t *testing.T) {
+	reporter := createGRPCReporter()
+	reporter.sendCh = make(chan *agentv3.SegmentObject, 10)
+	client := &slowTraceClient{delay: 100 * time.Millisecond}
+	reporter.traceClient = client
+	tracer, err := go2sky.NewTracer(mockService, go2sky.WithReporter(reporter), go2sky.WithInstance(mockServiceInstance))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reporter.Close()
+
+	for i := 0; i < 3; i++ {
+		span, _, err := tracer.CreateLocalSpan(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		span.End()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err = tracer.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("the flush should be timeout, current is %v", err)
+	}
+	if err = tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sent := atomic.LoadInt32(&client.sent); sent != 3 {
+		t.Errorf("the expected 3 sent segments after the flush, current is %d", sent)
+	}
+	// the stream is closed to confirm the delivery, and a new one is opened for the next segments
+	if closed := atomic.LoadInt32(&client.closed); closed != 1 {
+		t.Errorf("the expected the stream closed by the flush, current closed is %d", closed)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&client.opened) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("the expected a new stream opened after the flush, current opened is %d", atomic.LoadInt32(&client.opened))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGRPCReporter_MultiService(t *testing.T) {
+	reporter := createGRPCReporter()
+	reporter.sendCh = make(chan *agentv3.SegmentObject, 10)
+	services := []string{"gateway-a", "gateway-b"}
+	tracers := make([]*go2sky.Tracer, 0, len(services))
+	for _, service := range services {
+		tracer, err := go2sky.NewTracer(service, go2sky.WithReporter(reporter), go2sky.WithInstance(mockServiceInstance))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracers = append(tracers, tracer)
+	}
+	if len(reporter.identities) != 2 || reporter.primary().service != services[0] {
+		t.Fatalf("unexpected service instances %v", reporter.identities)
+	}
+
+	for i, tracer := range tracers {
+		span, _, err := tracer.CreateLocalSpan(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		span.End()
+		select {
+		case s := <-reporter.sendCh:
+			if s.Service != services[i] || s.ServiceInstance != mockServiceInstance {
+				t.Errorf("the expected service %s, current is %s", services[i], s.Service)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the segment is not sent")
+		}
+	}
+
+	if err := tracers[0].Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracers[0].Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case reporter.sendCh <- &agentv3.SegmentObject{}:
+	default:
+		t.Fatal("the shared reporter should not be closed before the last tracer")
+	}
+	if err := tracers[1].Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-reporter.sendCh; !ok {
+		t.Fatal("the buffered segment should be kept")
+	}
+	if _, ok := <-reporter.sendCh; ok {
+		t.Error("the shared reporter should be closed with the last tracer")
+	}
+}
+
+func TestGRPCReporter_MultiServiceCDS(t *testing.T) {
+	conn, err := grpc.Dial("127.0.0.1:0", grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	meterInterval := -1 * time.Second
+	reporter := createGRPCReporter()
+	reporter.conn = conn
+	reporter.meterInterval = &meterInterval
+	reporter.meterCh = make(chan []*agentv3.MeterData, 1)
+	reporter.cdsInterval = 10 * time.Millisecond
+	reporter.dynamicCDSInterval = go2sky.NewDynamicConfig(getAgentDynamicConfigIntervalKey, reporter.cdsInterval, parseIntervalSeconds)
+	// the correlation of gateway-b is turned off by its own configurations
+	reporter.cdsClient = &serviceCDSClient{configs: map[string]map[string]string{
+		"gateway-b": {"correlation.element_max_number": "0"},
+	}}
+	services := []string{"gateway-a", "gateway-b"}
+	tracers := make([]*go2sky.Tracer, 0, len(services))
+	for _, service := range services {
+		tracer, err := go2sky.NewTracer(service, go2sky.WithReporter(reporter), go2sky.WithInstance(mockServiceInstance))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracers = append(tracers, tracer)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	for i, expected := range []bool{true, false} {
+		span, ctx, err := tracers[i].CreateLocalSpan(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if put := go2sky.PutCorrelation(ctx, "key", "value"); put != expected {
+			t.Errorf("the expected correlation put of %s is %t, current is %t", services[i], expected, put)
+		}
+		span.End()
+	}
+
+	// the meters are reported by the remaining service instance
+	if err := tracers[0].Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	reporter.SendMetrics(go2sky.RunTimeMetric{Time: 1})
+	if meters := <-reporter.meterCh; len(meters) == 0 || meters[0].Service != services[1] {
+		t.Errorf("the expected meters of %s, current is %v", services[1], meters)
+	}
+	if err := tracers[1].Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serviceCDSClient returns the configurations of the requested service in version v1, test only
+type serviceCDSClient struct {
+	configs map[string]map[string]string
+}
+
+func (c *serviceCDSClient) FetchConfigurations(ctx context.Context, in *configuration.ConfigurationSyncRequest,
+	opts ...grpc.CallOption) (*commonv3.Commands, error) {
+	args := []*commonv3.KeyStringValuePair{{Key: "UUID", Value: "v1"}}
+	for k, v := range c.configs[in.Service] {
+		args = append(args, &commonv3.KeyStringValuePair{Key: k, Value: v})
+	}
+	return &commonv3.Commands{Commands: []*commonv3.Command{{Command: "ConfigurationDiscoveryCommand", Args: args}}}, nil
+}
+
+func TestGRPCReporter_Boot(t *testing.T) {
+	reporter := createGRPCReporter()
+	reporter.sendCh = make(chan *agentv3.SegmentObject, 10)
+	client := &slowTraceClient{}
+	reporter.traceClient = client
+	for i := 0; i < 3; i++ {
+		reporter.Boot(mockService, mockServiceInstance, nil)
+	}
+	reporter.Close()
+	reporter.Close()
+	time.Sleep(100 * time.Millisecond)
+	if opened := atomic.LoadInt32(&client.opened); opened != 1 {
+		t.Errorf("the repeated boots should start the pipeline once, current opened streams %d", opened)
+	}
+}
@@ -17,20 +17,72 @@
 package reporter
 
 import (
+	"context"
 	"encoding/json"
 	"log"
+	"math"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/SkyAPM/go2sky"
 )
 
-func NewLogReporter() (go2sky.Reporter, error) {
-	return &logReporter{logger: log.New(os.Stderr, "go2sky-log", log.LstdFlags)}, nil
+// LogReporterOption allows for functional options to adjust behaviour
+// of a log reporter to be created by NewLogReporter
+type LogReporterOption func(r *logReporter)
+
+// WithLogMetrics setup is set the meter collect interval of the log reporter, the metrics are
+// logged as JSON. The meter collection is off by default.
+func WithLogMetrics(interval time.Duration) LogReporterOption {
+	return func(r *logReporter) {
+		r.meterInterval = interval
+	}
+}
+
+func NewLogReporter(opts ...LogReporterOption) (go2sky.Reporter, error) {
+	r := &logReporter{logger: log.New(os.Stderr, "go2sky-log", log.LstdFlags)}
+	for _, o := range opts {
+		o(r)
+	}
+	return r, nil
 }
 
 type logReporter struct {
-	logger *log.Logger
+	logger        *log.Logger
+	meterInterval time.Duration
+	cancelFunc    context.CancelFunc
+}
+
+// logMeter is the JSON format of a meter, the bucket of histogram is the lower boundary
+type logMeter struct {
+	Name    string            `json:"name"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Value   logFloat          `json:"value"`
+	Buckets []logMeterBucket  `json:"buckets,omitempty"`
+}
+
+type logMeterBucket struct {
+	Bucket             logFloat `json:"bucket"`
+	Count              int64    `json:"count"`
+	IsNegativeInfinity bool     `json:"isNegativeInfinity,omitempty"`
+}
+
+// logFloat is a float encoded as a JSON number, or as the "NaN", "+Inf" and "-Inf" strings
+// which JSON has no number for
+type logFloat float64
+
+func (f logFloat) MarshalJSON() ([]byte, error) {
+	switch v := float64(f); {
+	case math.IsNaN(v):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(v, 1):
+		return []byte(`"+Inf"`), nil
+	case math.IsInf(v, -1):
+		return []byte(`"-Inf"`), nil
+	default:
+		return json.Marshal(v)
+	}
 }
 
 func (lr *logReporter) SendLog(logData go2sky.ReportedLogData) {
@@ -57,7 +109,42 @@ func (lr *logReporter) SendLog(logData go2sky.ReportedLogData) {
 }
 
 func (lr *logReporter) Boot(service string, serviceInstance string, cdsWatchers []go2sky.AgentConfigChangeWatcher) {
+	if lr.meterInterval <= 0 || lr.cancelFunc != nil {
+		return
+	}
+	var ctx context.Context
+	ctx, lr.cancelFunc = context.WithCancel(context.Background())
+	go2sky.InitMetricCollector(lr, &lr.meterInterval, ctx)
+}
 
+// SendMetrics logs the metrics as a JSON array
+func (lr *logReporter) SendMetrics(m go2sky.RunTimeMetric) {
+	values := m.MeterValues()
+	meters := make([]logMeter, 0, len(values))
+	for _, v := range values {
+		meter := logMeter{Name: v.Name, Value: logFloat(v.Value)}
+		if len(v.Labels) > 0 {
+			meter.Labels = make(map[string]string, len(v.Labels))
+			for _, l := range v.Labels {
+				meter.Labels[l.Name] = l.Value
+			}
+		}
+		for _, b := range v.Buckets {
+			bucket := logMeterBucket{Bucket: logFloat(b.Bucket), Count: b.Count}
+			// keep the lower boundary of the first bucket as before
+			if math.IsInf(b.Bucket, -1) {
+				bucket.Bucket, bucket.IsNegativeInfinity = 0, true
+			}
+			meter.Buckets = append(meter.Buckets, bucket)
+		}
+		meters = append(meters, meter)
+	}
+	b, err := json.Marshal(meters)
+	if err != nil {
+		lr.logger.Printf("Error: %s", err)
+		return
+	}
+	lr.logger.Printf("Metrics-%d: %s \n", m.Time, b)
 }
 
 func (lr *logReporter) Send(spans []go2sky.ReportedSpan) {
@@ -74,5 +161,8 @@ func (lr *logReporter) Send(spans []go2sky.ReportedSpan) {
 }
 
 func (lr *logReporter) Close() {
+	if lr.cancelFunc != nil {
+		lr.cancelFunc()
+	}
 	lr.logger.Println("Close log reporter")
 }
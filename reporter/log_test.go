@@ -0,0 +1,99 @@
+//
+// Copyright 2022 SkyAPM org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporter
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SkyAPM/go2sky"
+)
+
+// syncBuffer is a bytes.Buffer safe to read while the reporter is writing
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLogReporter_SendMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewLogReporter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lr := r.(*logReporter)
+	lr.logger = log.New(&buf, "", 0)
+
+	lr.SendMetrics(go2sky.RunTimeMetric{
+		Time: 1,
+		Meters: []go2sky.MeterValue{
+			{Type: go2sky.MeterTypeCounter, Name: "requests", Labels: []go2sky.MeterLabel{{Name: "method", Value: "GET"}}, Value: 3},
+			{Type: go2sky.MeterTypeHistogram, Name: "latency", Buckets: []go2sky.MeterBucket{{Bucket: math.Inf(-1), Count: 1}}},
+			{Type: go2sky.MeterTypeGauge, Name: "ratio", Value: math.NaN()},
+			{Type: go2sky.MeterTypeGauge, Name: "max", Value: math.Inf(1)},
+		},
+	})
+	out := buf.String()
+	for _, want := range []string{
+		"Metrics-1: [",
+		`{"name":"requests","labels":{"method":"GET"},"value":3}`,
+		`{"name":"latency","value":0,"buckets":[{"bucket":0,"count":1,"isNegativeInfinity":true}]}`,
+		`{"name":"ratio","value":"NaN"}`,
+		`{"name":"max","value":"+Inf"}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in the log %s", want, out)
+		}
+	}
+}
+
+func TestLogReporter_Metrics(t *testing.T) {
+	var buf syncBuffer
+	r, err := NewLogReporter(WithLogMetrics(10 * time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lr := r.(*logReporter)
+	lr.logger = log.New(&buf, "", 0)
+	r.Boot("service", "instance", nil)
+	defer r.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(buf.String(), go2sky.InstanceGolangHeap) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the metrics logged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
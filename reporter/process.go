@@ -46,6 +46,7 @@ type processStat struct {
 	basePath     string
 	metadataFile string
 	status       ProcessReportStatus
+	id           serviceIdentity // the service instance of the metadata file
 	shutdownOnce sync.Once
 }
 
@@ -60,19 +61,23 @@ func initProcessStat(r *gRPCReporter) *processStat {
 	}
 }
 
-// Report the current process metadata to local file
-// using to work with eBPF agent
-func reportProcess(r *gRPCReporter) {
+// Report the current process metadata of the service instance to local file
+// using to work with eBPF agent, the file is created again when the service instance changes
+func reportProcess(r *gRPCReporter, id serviceIdentity) {
 	if process == nil {
 		process = initProcessStat(r)
 	}
+	if process.status == Reported && process.id != id {
+		process.status = NotInit
+	}
 
 	if process.status == NotInit {
 		// create metadata file
-		if p, err := process.initMetadataFile(r); err != nil {
+		if p, err := process.initMetadataFile(r, id); err != nil {
 			r.logger.Warnf("process status file init failure: %s, %v", p, err)
 		} else {
 			process.status = Reported
+			process.id = id
 		}
 	} else if process.status == Reported {
 		// keep the metadata file alive(update modify time)
@@ -83,7 +88,7 @@ func reportProcess(r *gRPCReporter) {
 	}
 }
 
-func (p *processStat) initMetadataFile(r *gRPCReporter) (string, error) {
+func (p *processStat) initMetadataFile(r *gRPCReporter, id serviceIdentity) (string, error) {
 	// create base directory
 	basePath := process.basePath
 	if err := os.RemoveAll(basePath); err != nil && !errors.Is(err, os.ErrNotExist) {
@@ -98,7 +103,7 @@ func (p *processStat) initMetadataFile(r *gRPCReporter) (string, error) {
 	if metaFile, err := os.Create(metadataFile); err != nil {
 		return metadataFile, err
 	} else {
-		if content, err := p.buildMetadataContent(r); err != nil {
+		if content, err := p.buildMetadataContent(r, id); err != nil {
 			return metadataFile, err
 		} else if _, err = metaFile.WriteString(content); err != nil {
 			return metadataFile, err
@@ -107,7 +112,7 @@ func (p *processStat) initMetadataFile(r *gRPCReporter) (string, error) {
 	return "", nil
 }
 
-func (p *processStat) buildMetadataContent(g *gRPCReporter) (string, error) {
+func (p *processStat) buildMetadataContent(g *gRPCReporter, id serviceIdentity) (string, error) {
 	layer := g.layer
 	if layer == "" {
 		layer = "GENERAL"
@@ -120,9 +125,9 @@ func (p *processStat) buildMetadataContent(g *gRPCReporter) (string, error) {
 
 	metadata := map[string]string{
 		"layer":         layer,
-		"service_name":  g.service,
-		"instance_name": g.serviceInstance,
-		"process_name":  g.serviceInstance, // process name is same with instance name
+		"service_name":  id.service,
+		"instance_name": id.serviceInstance,
+		"process_name":  id.serviceInstance, // process name is same with instance name
 		"properties":    propertiesJson,
 		"labels":        strings.Join(g.processLabels, ","),
 		"language":      "golang",
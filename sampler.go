@@ -21,6 +21,7 @@ import (
 	"math/rand"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -105,15 +106,23 @@ func NewRandomSampler(samplingRate float64) *RandomSampler {
 	return s
 }
 
+// DynamicSampler switches the sampling rate on configuration changes.
+// The rate and its sampler are published together as an immutable snapshot,
+// so IsSampled never observes a half-applied update.
 type DynamicSampler struct {
-	currentRate float64
 	defaultRate float64
-	sampler     Sampler
+	current     atomic.Value
+}
+
+// dynamicSamplerState is never modified after it has been stored
+type dynamicSamplerState struct {
+	rate    float64
+	sampler Sampler
 }
 
 // IsSampled implements IsSampled() of Sampler.
 func (s *DynamicSampler) IsSampled(operation string) bool {
-	return s.sampler.IsSampled(operation)
+	return s.load().sampler.IsSampled(operation)
 }
 
 func (s *DynamicSampler) Key() string {
@@ -138,17 +147,25 @@ func (s *DynamicSampler) Notify(eventType AgentConfigEventType, newValue string)
 	} else {
 		sampler = NewRandomSampler(samplingRate)
 	}
-	s.sampler = sampler
-	s.currentRate = samplingRate
+	s.current.Store(&dynamicSamplerState{rate: samplingRate, sampler: sampler})
+}
+
+// Validate implements AgentConfigChangeValidator
+func (s *DynamicSampler) Validate(newValue string) error {
+	_, err := strconv.ParseFloat(newValue, 64)
+	return err
 }
 
 func (s *DynamicSampler) Value() string {
-	return fmt.Sprintf("%f", s.currentRate)
+	return fmt.Sprintf("%f", s.load().rate)
+}
+
+func (s *DynamicSampler) load() *dynamicSamplerState {
+	return s.current.Load().(*dynamicSamplerState)
 }
 
 func NewDynamicSampler(samplingRate float64, tracer *Tracer) *DynamicSampler {
 	s := &DynamicSampler{
-		currentRate: samplingRate,
 		defaultRate: samplingRate,
 	}
 	s.Notify(MODIFY, fmt.Sprintf("%f", samplingRate))
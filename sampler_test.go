@@ -17,6 +17,7 @@
 package go2sky
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 )
@@ -95,6 +96,72 @@ func TestRandomSampler_getRandomizer(t *testing.T) {
 	})
 }
 
+func TestDynamicSampler_Notify(t *testing.T) {
+	sampler := newTestDynamicSampler(0.3)
+	tests := []struct {
+		name      string
+		eventType AgentConfigEventType
+		value     string
+		rate      float64
+	}{
+		{name: "modify rate", eventType: MODIFY, value: "0.8", rate: 0.8},
+		{name: "invalid rate", eventType: MODIFY, value: "abc", rate: 0.8},
+		{name: "sample nothing", eventType: MODIFY, value: "0", rate: 0},
+		{name: "delete rate", eventType: DELETED, value: "", rate: 0.3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler.Notify(tt.eventType, tt.value)
+			if sampler.Value() != fmt.Sprintf("%f", tt.rate) {
+				t.Errorf("error validate current rate, current is: %s, excepted is: %f", sampler.Value(), tt.rate)
+			}
+		})
+	}
+}
+
+func TestDynamicSampler_ConcurrentNotify(t *testing.T) {
+	sampler := newTestDynamicSampler(1)
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					sampler.IsSampled("op")
+					_ = sampler.Value()
+				}
+			}
+		}()
+	}
+	for i := 0; i < 1000; i++ {
+		if i%2 == 0 {
+			sampler.Notify(MODIFY, "0.25")
+		} else {
+			sampler.Notify(DELETED, "")
+		}
+	}
+	close(done)
+	wg.Wait()
+	if sampler.Value() != fmt.Sprintf("%f", 1.0) {
+		t.Errorf("error validate current rate, current is: %s, excepted is: %f", sampler.Value(), 1.0)
+	}
+}
+
+func BenchmarkDynamicSampler_IsSampled(b *testing.B) {
+	sampler := newTestDynamicSampler(0.5)
+	operationName := "op"
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			sampler.IsSampled(operationName)
+		}
+	})
+}
+
 func BenchmarkRandomPoolSampler_IsSampled(b *testing.B) {
 	sampler := NewRandomSampler(0.5)
 	operationName := "op"
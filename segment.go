@@ -55,6 +55,7 @@ type SegmentContext struct {
 	ParentSpanID       int32
 	ParentSegmentID    string
 	collect            chan<- ReportedSpan
+	sent               chan struct{} // closed when the segment is sent to the reporter
 	refNum             *int32
 	spanIDGenerator    *int32
 	FirstSpan          Span `json:"-"`
@@ -95,6 +96,7 @@ func (s *segmentSpanImpl) End() {
 		return
 	}
 	s.defaultSpan.End()
+	s.defaultSpan.recordEndpointMetrics()
 	go func() {
 		s.Context().collect <- s
 	}()
@@ -211,6 +213,8 @@ func (rs *rootSegmentSpan) End() {
 		return
 	}
 	rs.defaultSpan.End()
+	rs.defaultSpan.recordEndpointMetrics()
+	rs.tracer().segments.Add()
 	go func() {
 		rs.doneCh <- atomic.SwapInt32(rs.Context().refNum, -1)
 	}()
@@ -248,6 +252,7 @@ func newSegmentRoot(segmentSpan *segmentSpanImpl) *rootSegmentSpan {
 	ch := make(chan ReportedSpan)
 	s.collect = ch
 	s.notify = ch
+	s.sent = make(chan struct{})
 	s.segment = make([]ReportedSpan, 0, 10)
 	s.doneCh = make(chan int32)
 	go func() {
@@ -266,6 +271,8 @@ func newSegmentRoot(segmentSpan *segmentSpanImpl) *rootSegmentSpan {
 			}
 		}
 		s.tracer().reporter.Send(append(s.segment, s))
+		close(s.sent)
+		s.tracer().segments.Done()
 	}()
 	return s
 }
@@ -150,16 +150,27 @@ type SpanOption func(s *defaultSpan)
 type Tag string
 
 const (
-	TagURL             Tag = "url"
-	TagStatusCode      Tag = "status_code"
-	TagHTTPMethod      Tag = "http.method"
-	TagDBType          Tag = "db.type"
-	TagDBInstance      Tag = "db.instance"
-	TagDBStatement     Tag = "db.statement"
-	TagDBSqlParameters Tag = "db.sql.parameters"
-	TagMQQueue         Tag = "mq.queue"
-	TagMQBroker        Tag = "mq.broker"
-	TagMQTopic         Tag = "mq.topic"
+	TagURL                  Tag = "url"
+	TagStatusCode           Tag = "status_code"
+	TagHTTPMethod           Tag = "http.method"
+	TagHTTPRequestBodySize  Tag = "http.request.body.size"
+	TagHTTPResponseBodySize Tag = "http.response.body.size"
+	TagHTTPHeaders          Tag = "http.headers"
+	TagDBType               Tag = "db.type"
+	TagDBInstance           Tag = "db.instance"
+	TagDBStatement          Tag = "db.statement"
+	TagDBSqlParameters      Tag = "db.sql.parameters"
+	TagMQQueue              Tag = "mq.queue"
+	TagMQBroker             Tag = "mq.broker"
+	TagMQTopic              Tag = "mq.topic"
+	TagRPCMethod            Tag = "rpc.method"
+	TagRPCStatusCode        Tag = "rpc.status_code"
+	TagCacheType            Tag = "cache.type"
+	TagCacheOp              Tag = "cache.op"
+	TagCacheCmd             Tag = "cache.cmd"
+	TagCacheKey             Tag = "cache.key"
+	TagFaaSColdStart        Tag = "faas.coldstart"
+	TagFaaSInvocationID     Tag = "faas.invocation_id"
 )
 
 const (
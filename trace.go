@@ -18,6 +18,7 @@ package go2sky
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/SkyAPM/go2sky/internal/idgen"
 	"github.com/pkg/errors"
@@ -35,11 +36,18 @@ type Tracer struct {
 	service  string
 	instance string
 	reporter Reporter
-	// 0 not init 1 init
+	// 0 not init or closed 1 init, accessed atomically
 	initFlag    int32
 	sampler     Sampler
 	correlation *CorrelationConfig
 	cdsWatchers []AgentConfigChangeWatcher
+
+	correlationKeyCount  *DynamicConfig[int]
+	correlationValueSize *DynamicConfig[int]
+	// nil if the endpoint metrics are disabled
+	endpointMetrics *endpointMetrics
+	// the ended segments not sent to the reporter yet
+	segments tool.Pending
 }
 
 // TracerOption allows for functional options to adjust behaviour
@@ -74,8 +82,16 @@ func NewTracer(service string, opts ...TracerOption) (tracer *Tracer, err error)
 	if t.sampler == nil {
 		t.sampler = NewDynamicSampler(1, t)
 	}
+	newCorrelationConfigs(t)
+	if t.endpointMetrics != nil {
+		t.endpointMetrics.service = t.service
+		meters.registerSource(t.endpointMetrics)
+	}
 
 	if t.reporter != nil {
+		if dr, ok := t.reporter.(DynamicConfigReporter); ok {
+			t.cdsWatchers = append(t.cdsWatchers, dr.DynamicConfigs()...)
+		}
 		if t.instance == "" {
 			id, err := idgen.UUID()
 			if err != nil {
@@ -83,8 +99,12 @@ func NewTracer(service string, opts ...TracerOption) (tracer *Tracer, err error)
 			}
 			t.instance = id + "@" + tool.IPV4()
 		}
+		if mr, ok := t.reporter.(MultiServiceReporter); ok {
+			// the reporter shared by the tracers reports as the service instance of each tracer
+			t.reporter = mr.ForService(t.service, t.instance)
+		}
 		t.reporter.Boot(t.service, t.instance, t.cdsWatchers)
-		t.initFlag = 1
+		atomic.StoreInt32(&t.initFlag, 1)
 	}
 
 	return t, nil
@@ -95,7 +115,7 @@ func (t *Tracer) CreateEntrySpan(ctx context.Context, operationName string, extr
 	if ctx == nil || operationName == "" || extractor == nil {
 		return nil, nil, errParameter
 	}
-	if s, nCtx = t.createNoop(ctx); s != nil {
+	if s, nCtx = t.createNoop(ctx, SpanTypeEntry, operationName); s != nil {
 		return
 	}
 	var refSc = &propagation.SpanContext{}
@@ -115,7 +135,7 @@ func (t *Tracer) CreateLocalSpan(ctx context.Context, opts ...SpanOption) (s Spa
 	if ctx == nil {
 		return nil, nil, errParameter
 	}
-	if s, c = t.createNoop(ctx); s != nil {
+	if s, c = t.createNoop(ctx, SpanTypeLocal, ""); s != nil {
 		return
 	}
 	ds := newLocalSpan(t)
@@ -133,7 +153,7 @@ func (t *Tracer) CreateLocalSpan(ctx context.Context, opts ...SpanOption) (s Spa
 		sampled := t.sampler.IsSampled(ds.OperationName)
 		if !sampled {
 			// Filter by sample just return noop span
-			s = &NoopSpan{}
+			s = t.newMeasuredNoopSpan(ds.SpanType, ds.OperationName)
 			return s, context.WithValue(ctx, ctxKeyInstance, s), nil
 		}
 	}
@@ -156,7 +176,8 @@ func (t *Tracer) CreateExitSpanWithContext(ctx context.Context, operationName st
 	if ctx == nil || operationName == "" || peer == "" || injector == nil {
 		return nil, nil, errParameter
 	}
-	if s, nCtx = t.createNoop(ctx); s != nil {
+	if s, nCtx = t.createNoop(ctx, SpanTypeExit, operationName); s != nil {
+		s.SetPeer(peer)
 		return
 	}
 	s, nCtx, err = t.CreateLocalSpan(ctx, WithSpanType(SpanTypeExit), WithOperationName(operationName))
@@ -166,6 +187,7 @@ func (t *Tracer) CreateExitSpanWithContext(ctx context.Context, operationName st
 	noopSpan, ok := interface{}(s).(*NoopSpan)
 	if ok {
 		// Ignored, there is no need to inject SW8 in the request header
+		noopSpan.SetPeer(peer)
 		return noopSpan, nCtx, nil
 	}
 	s.SetPeer(peer)
@@ -193,13 +215,78 @@ func (t *Tracer) CreateExitSpanWithContext(ctx context.Context, operationName st
 	return
 }
 
-func (t *Tracer) createNoop(ctx context.Context) (s Span, nCtx context.Context) {
+// Flush blocks until the ended segments are sent to the reporter, then flushes the reporter
+// if it is a FlushableReporter. It returns the error of the context if the context is done before.
+func (t *Tracer) Flush(ctx context.Context) error {
+	if err := t.segments.Wait(ctx); err != nil {
+		return err
+	}
+	if r, ok := t.reporter.(FlushableReporter); ok {
+		return r.Flush(ctx)
+	}
+	return nil
+}
+
+// FlushSegment blocks until the segment of the span is sent to the reporter, then flushes the reporter
+// if it is a FlushableReporter. Unlike Flush, it does not wait for the other segments, so the concurrent
+// requests do not delay each other. The segment is sent after all its spans end, and nothing is waited
+// for the noop span. It returns the error of the context if the context is done before.
+func (t *Tracer) FlushSegment(ctx context.Context, span Span) error {
+	s, ok := span.(segmentSpan)
+	if !ok {
+		return nil
+	}
+	select {
+	case <-s.context().sent:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if r, ok := t.reporter.(FlushableReporter); ok {
+		return r.Flush(ctx)
+	}
+	return nil
+}
+
+// Close stops tracing, the spans created afterwards are noop. It flushes the ended segments
+// like Flush, then closes the reporter and unregisters the endpoint metrics of the tracer.
+// The reporter shared by the tracers by MultiServiceReporter is closed with the last tracer.
+// It returns the error of flushing, and the repeated closes are ignored.
+func (t *Tracer) Close(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&t.initFlag, 1, 0) {
+		return nil
+	}
+	err := t.Flush(ctx)
+	t.reporter.Close()
+	if t.endpointMetrics != nil {
+		meters.unregisterSource(t.endpointMetrics)
+	}
+	return err
+}
+
+// Config returns the current effective values of the dynamic configurations
+// bound to the tracer and registered globally, keyed by the configuration key.
+func (t *Tracer) Config() map[string]string {
+	config := make(map[string]string)
+	for key, watcher := range dynamicConfigs.all() {
+		config[key] = watcher.Value()
+	}
+	for _, watcher := range t.cdsWatchers {
+		config[watcher.Key()] = watcher.Value()
+	}
+	return config
+}
+
+func (t *Tracer) createNoop(ctx context.Context, spanType SpanType, operationName string) (s Span, nCtx context.Context) {
 	if ns, ok := ctx.Value(ctxKeyInstance).(*NoopSpan); ok {
 		nCtx = ctx
 		s = ns
+		if ns.measured != nil || (t.endpointMetrics != nil && spanType != SpanTypeLocal) {
+			// the measured span must not be ended by the spans in its context
+			s = t.newMeasuredNoopSpan(spanType, operationName)
+		}
 		return
 	}
-	if t.initFlag == 0 {
+	if atomic.LoadInt32(&t.initFlag) == 0 {
 		s = &NoopSpan{}
 		nCtx = context.WithValue(ctx, ctxKeyInstance, s)
 		return
@@ -214,3 +301,22 @@ type Reporter interface {
 	SendLog(logData ReportedLogData)
 	Close()
 }
+
+// FlushableReporter is implemented by the reporters sending asynchronously,
+// Flush blocks until the queued segments are sent or the context is done.
+type FlushableReporter interface {
+	Flush(ctx context.Context) error
+}
+
+// MultiServiceReporter is implemented by the reporters shared by the tracers of several services,
+// such as a gateway reporting as several services. Each tracer reports by the Reporter returned by
+// ForService, which sends the data as the service instance, and closing it releases the service instance.
+type MultiServiceReporter interface {
+	ForService(service string, serviceInstance string) Reporter
+}
+
+// DynamicConfigReporter is implemented by the reporters owning dynamic configurations,
+// they are bound to the Configuration Discovery Service together with the tracer ones.
+type DynamicConfigReporter interface {
+	DynamicConfigs() []AgentConfigChangeWatcher
+}
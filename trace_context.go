@@ -24,6 +24,7 @@ const (
 	EmptyTraceID             = "N/A"
 	EmptyTraceSegmentID      = "N/A"
 	EmptySpanID              = -1
+	EmptyEndpoint            = ""
 )
 
 type ctxKey struct{}
@@ -70,6 +71,18 @@ func SpanID(ctx context.Context) int32 {
 	return (*span).context().SpanID
 }
 
+// Endpoint returns the operation name of the first span in the segment of the active span
+func Endpoint(ctx context.Context) string {
+	span, failed, ok := extractSpanString(ctx, EmptyEndpoint)
+	if !ok {
+		return failed
+	}
+	if first := (*span).context().FirstSpan; first != nil {
+		return first.GetOperationName()
+	}
+	return EmptyEndpoint
+}
+
 func ActiveSpan(ctx context.Context) Span {
 	activeSpan := ctx.Value(ctxKeyInstance)
 	if activeSpan != nil {
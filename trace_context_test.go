@@ -88,3 +88,20 @@ func verifySpan(ctx context.Context, t *testing.T, expectSpan Span) {
 	actualSpan := ActiveSpan(ctx)
 	verifyEqual(t, "ActiveSpan", expectSpan, actualSpan)
 }
+
+func TestEndpoint(t *testing.T) {
+	verifyEqual(t, "Endpoint", EmptyEndpoint, Endpoint(context.Background()))
+
+	tracer, _ := NewTracer("service", WithInstance("instance"), WithReporter(&mockRegisterReporter{success: true}))
+	_, ctx, err := tracer.CreateEntrySpan(context.Background(), "/orders", func(key string) (string, error) {
+		return "", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ctx, err = tracer.CreateLocalSpan(ctx, WithOperationName("query"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyEqual(t, "Endpoint", "/orders", Endpoint(ctx))
+}
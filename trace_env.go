@@ -28,6 +28,10 @@ const (
 	swAgentName         = "SW_AGENT_NAME"
 	swAgentInstanceName = "SW_AGENT_INSTANCE_NAME"
 	swAgentSample       = "SW_AGENT_SAMPLE"
+
+	swAgentCorrelationElementMaxNumber = "SW_AGENT_CORRELATION_ELEMENT_MAX_NUMBER"
+	swAgentCorrelationValueMaxLength   = "SW_AGENT_CORRELATION_VALUE_MAX_LENGTH"
+	swAgentEndpointMetricsEnable       = "SW_AGENT_ENDPOINT_METRICS_ENABLE"
 )
 
 // serviceFormEnv read the service in the environment variable
@@ -53,5 +57,42 @@ func traceOptionsFormEnv() (opts []TracerOption, err error) {
 		}
 		opts = append(opts, WithSampler(samplingRate))
 	}
+
+	// SW_AGENT_CORRELATION_ELEMENT_MAX_NUMBER
+	if value := os.Getenv(swAgentCorrelationElementMaxNumber); value != "" {
+		keyCount, err1 := parseNonNegativeInt(value)
+		if err1 != nil {
+			return nil, errors.Wrap(err1, fmt.Sprintf("%s=%s", swAgentCorrelationElementMaxNumber, value))
+		}
+		opts = append(opts, func(t *Tracer) {
+			t.correlation = &CorrelationConfig{MaxKeyCount: keyCount, MaxValueSize: t.correlation.MaxValueSize}
+		})
+	}
+
+	// SW_AGENT_CORRELATION_VALUE_MAX_LENGTH
+	if value := os.Getenv(swAgentCorrelationValueMaxLength); value != "" {
+		valueSize, err1 := parseNonNegativeInt(value)
+		if err1 != nil {
+			return nil, errors.Wrap(err1, fmt.Sprintf("%s=%s", swAgentCorrelationValueMaxLength, value))
+		}
+		opts = append(opts, func(t *Tracer) {
+			t.correlation = &CorrelationConfig{MaxKeyCount: t.correlation.MaxKeyCount, MaxValueSize: valueSize}
+		})
+	}
+
+	// SW_AGENT_ENDPOINT_METRICS_ENABLE
+	if value := os.Getenv(swAgentEndpointMetricsEnable); value != "" {
+		enable, err1 := strconv.ParseBool(value)
+		if err1 != nil {
+			return nil, errors.Wrap(err1, fmt.Sprintf("%s=%s", swAgentEndpointMetricsEnable, value))
+		}
+		opts = append(opts, func(t *Tracer) {
+			if !enable {
+				t.endpointMetrics = nil
+			} else if t.endpointMetrics == nil {
+				WithEndpointMetrics()(t)
+			}
+		})
+	}
 	return
 }
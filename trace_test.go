@@ -22,7 +22,9 @@ import (
 	"os"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/SkyAPM/go2sky/propagation"
 )
@@ -113,7 +115,7 @@ func TestTracer_EnvIfNotSet(t *testing.T) {
 		t.Errorf("the expected value of sampler is DynamicSampler")
 	}
 
-	if sampler.currentRate != 0.5 {
+	if sampler.load().rate != 0.5 {
 		t.Errorf("the expected value of currentRate is 0.5")
 	}
 
@@ -147,7 +149,7 @@ func TestTracer_EnvOverride(t *testing.T) {
 		t.Errorf("the expected value of sampler is DynamicSampler")
 	}
 
-	if sampler.currentRate != 0.5 {
+	if sampler.load().rate != 0.5 {
 		t.Errorf("the expected value of currentRate is 0.5")
 	}
 
@@ -156,6 +158,25 @@ func TestTracer_EnvOverride(t *testing.T) {
 	}
 }
 
+func TestTracer_CorrelationEnv(t *testing.T) {
+	os.Setenv(swAgentCorrelationElementMaxNumber, "5")
+	defer os.Unsetenv(swAgentCorrelationElementMaxNumber)
+
+	tracer, err := NewTracer("service", WithCorrelation(1, 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tracer.correlation.MaxKeyCount != 5 || tracer.correlation.MaxValueSize != 64 {
+		t.Errorf("the expected value of correlation is {5 64}, current is %v", *tracer.correlation)
+	}
+
+	os.Setenv(swAgentCorrelationValueMaxLength, "-1")
+	defer os.Unsetenv(swAgentCorrelationValueMaxLength)
+	if _, err = NewTracer("service"); err == nil {
+		t.Error("negative correlation value length should be failed")
+	}
+}
+
 func verifySpans(t *testing.T, span ReportedSpan, subSpan ReportedSpan) {
 	if !reflect.DeepEqual(subSpan.Context().TraceID, span.Context().TraceID) {
 		t.Errorf("trace id is different %v %v", subSpan.Context().TraceID, span.Context().TraceID)
@@ -225,15 +246,15 @@ func TestNewTracer(t *testing.T) {
 				opts    []TracerOption
 			}{service: "test", opts: nil},
 			&Tracer{service: "test",
-				sampler: &DynamicSampler{
-					sampler:     &ConstSampler{true},
-					currentRate: 1,
-					defaultRate: 1,
-				},
+				sampler: newTestDynamicSampler(1),
 				correlation: &CorrelationConfig{
 					MaxKeyCount:  3,
 					MaxValueSize: 128,
-				}, cdsWatchers: []AgentConfigChangeWatcher{&DynamicSampler{sampler: &ConstSampler{true}, currentRate: 1, defaultRate: 1}}},
+				}, cdsWatchers: []AgentConfigChangeWatcher{
+					newTestDynamicSampler(1),
+					NewDynamicConfig(correlationElementMaxNumberKey, 3, parseNonNegativeInt),
+					NewDynamicConfig(correlationValueMaxLengthKey, 128, parseNonNegativeInt),
+				}},
 			false,
 		},
 	}
@@ -244,13 +265,30 @@ func TestNewTracer(t *testing.T) {
 				t.Errorf("NewTracer() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(gotTracer, tt.wantTracer) {
+			if !equalTracer(gotTracer, tt.wantTracer) {
 				t.Errorf("NewTracer() = %v, want %v", gotTracer, tt.wantTracer)
 			}
 		})
 	}
 }
 
+// equalTracer compares the tracers by their effective configurations,
+// the dynamic configurations hold parse functions which are never deeply equal
+func equalTracer(got, want *Tracer) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return got.service == want.service && got.instance == want.instance && got.reporter == want.reporter &&
+		got.initFlag == want.initFlag &&
+		reflect.DeepEqual(got.sampler, want.sampler) &&
+		reflect.DeepEqual(got.correlation, want.correlation) &&
+		reflect.DeepEqual(got.Config(), want.Config())
+}
+
+func newTestDynamicSampler(samplingRate float64) *DynamicSampler {
+	return NewDynamicSampler(samplingRate, &Tracer{})
+}
+
 func TestTracer_CreateEntrySpan_Parameter(t *testing.T) {
 	type args struct {
 		ctx           context.Context
@@ -516,3 +554,142 @@ func TestTracer_CreateExitSpanWithContext_Parameter(t *testing.T) {
 		})
 	}
 }
+
+// flushReporter receives the segments after the delay, and records the flushes
+type flushReporter struct {
+	delay   time.Duration
+	sent    int32
+	flushed int32
+	closed  int32
+}
+
+func (r *flushReporter) Boot(string, string, []AgentConfigChangeWatcher) {}
+func (r *flushReporter) SendLog(ReportedLogData)                         {}
+
+func (r *flushReporter) Close() {
+	atomic.AddInt32(&r.closed, 1)
+}
+
+func (r *flushReporter) Send([]ReportedSpan) {
+	time.Sleep(r.delay)
+	atomic.AddInt32(&r.sent, 1)
+}
+
+func (r *flushReporter) Flush(context.Context) error {
+	atomic.AddInt32(&r.flushed, 1)
+	return nil
+}
+
+func TestTracer_Flush(t *testing.T) {
+	reporter := &flushReporter{delay: 100 * time.Millisecond}
+	tracer, err := NewTracer("service", WithReporter(reporter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tracer.Flush(context.Background()); err != nil || atomic.LoadInt32(&reporter.flushed) != 1 {
+		t.Fatalf("the reporter should be flushed without the segments, error %v", err)
+	}
+	span, _, err := tracer.CreateLocalSpan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = tracer.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("the flush should be timeout, current is %v", err)
+	}
+	if err = tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&reporter.sent) != 1 || atomic.LoadInt32(&reporter.flushed) != 2 {
+		t.Errorf("the segment should be sent and the reporter flushed, sent %d, flushed %d", reporter.sent, reporter.flushed)
+	}
+}
+
+// gateReporter blocks sending the segments of the slow operation until the gate is open
+type gateReporter struct {
+	flushReporter
+	gate chan struct{}
+}
+
+func (r *gateReporter) Send(spans []ReportedSpan) {
+	if spans[0].OperationName() == "slow" {
+		<-r.gate
+	}
+}
+
+func TestTracer_FlushSegment(t *testing.T) {
+	reporter := &gateReporter{gate: make(chan struct{})}
+	tracer, err := NewTracer("service", WithReporter(reporter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer close(reporter.gate)
+	slow, _, err := tracer.CreateLocalSpan(context.Background(), WithOperationName("slow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow.End()
+	span, _, err := tracer.CreateLocalSpan(context.Background(), WithOperationName("fast"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = tracer.FlushSegment(ctx, span); err != context.DeadlineExceeded {
+		t.Errorf("the flush should wait for the span to end, current is %v", err)
+	}
+	span.End()
+	// the segment of the span is flushed while the other one is still being sent
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = tracer.FlushSegment(ctx, span); err != nil || atomic.LoadInt32(&reporter.flushed) != 1 {
+		t.Errorf("the segment should be sent and the reporter flushed, error %v, flushed %d", err, reporter.flushed)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = tracer.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("the flush of all the segments should wait for the slow one, current is %v", err)
+	}
+	if err = tracer.FlushSegment(context.Background(), &NoopSpan{}); err != nil {
+		t.Errorf("nothing should be waited for the noop span, current is %v", err)
+	}
+}
+
+func TestTracer_Close(t *testing.T) {
+	reporter := &flushReporter{delay: 100 * time.Millisecond}
+	tracer, err := NewTracer("service", WithReporter(reporter), WithEndpointMetrics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	span, _, err := tracer.CreateLocalSpan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	span.End()
+
+	if err = tracer.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&reporter.sent) != 1 || atomic.LoadInt32(&reporter.closed) != 1 {
+		t.Errorf("the segment should be sent before the reporter closed, sent %d, closed %d", reporter.sent, reporter.closed)
+	}
+	for _, source := range meters.sources {
+		if source == meterSource(tracer.endpointMetrics) {
+			t.Error("the endpoint metrics should be unregistered")
+		}
+	}
+	span, _, err = tracer.CreateLocalSpan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := span.(*NoopSpan); !ok {
+		t.Errorf("the span of the closed tracer should be noop, current is %T", span)
+	}
+	if err = tracer.Close(context.Background()); err != nil || atomic.LoadInt32(&reporter.closed) != 1 {
+		t.Errorf("the repeated close should be ignored, error %v, closed %d", err, reporter.closed)
+	}
+}